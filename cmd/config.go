@@ -131,18 +131,24 @@ func init() {
 	configCmd.Flags().StringVar(&conf.Config.DataDir, "dataDir", "", "Data directory (default cwd/apla-data)")
 	configCmd.Flags().StringVar(&conf.Config.TempDir, "tempDir", "", "Temporary directory (default temporary directory of OS)")
 	configCmd.Flags().StringVar(&conf.Config.FirstBlockPath, "firstBlock", "", "First block path (default dataDir/1block)")
+	configCmd.Flags().StringVar(&conf.Config.ContractsCacheDir, "contractsCache", "",
+		fmt.Sprintf("Directory of compiled contracts (default dataDir/%s)", consts.DefaultContractsCacheDirName),
+	)
 	configCmd.Flags().BoolVar(&conf.Config.TLS, "tls", false, "Enable https")
 	configCmd.Flags().StringVar(&conf.Config.TLSCert, "tls-cert", "", "Filepath to the fullchain of certificates")
 	configCmd.Flags().StringVar(&conf.Config.TLSKey, "tls-key", "", "Filepath to the private key")
 	configCmd.Flags().Int64Var(&conf.Config.MaxPageGenerationTime, "mpgt", 1000, "Max page generation time in ms")
 	configCmd.Flags().StringSliceVar(&conf.Config.NodesAddr, "nodesAddr", []string{}, "List of addresses for downloading blockchain")
 	configCmd.Flags().StringVar(&conf.Config.RunningMode, "runMode", "PublicBlockchain", "Node running mode")
+	configCmd.Flags().StringVar(&conf.Config.TCPSecurity, "tcpSecurity", string(conf.TCPSecurityPrefer), "Secure channel between nodes (off | prefer | require)")
+	configCmd.Flags().BoolVar(&conf.Config.Webhooks.Dispatch, "webhooks", false, "Send the webhooks which are assigned to the node")
 
 	viper.BindPFlag("PidFilePath", configCmd.Flags().Lookup("pid"))
 	viper.BindPFlag("LockFilePath", configCmd.Flags().Lookup("lock"))
 	viper.BindPFlag("KeysDir", configCmd.Flags().Lookup("keysDir"))
 	viper.BindPFlag("DataDir", configCmd.Flags().Lookup("dataDir"))
 	viper.BindPFlag("FirstBlockPath", configCmd.Flags().Lookup("firstBlock"))
+	viper.BindPFlag("ContractsCacheDir", configCmd.Flags().Lookup("contractsCache"))
 	viper.BindPFlag("TLS", configCmd.Flags().Lookup("tls"))
 	viper.BindPFlag("TLSCert", configCmd.Flags().Lookup("tls-cert"))
 	viper.BindPFlag("TLSKey", configCmd.Flags().Lookup("tls-key"))
@@ -150,4 +156,6 @@ func init() {
 	viper.BindPFlag("TempDir", configCmd.Flags().Lookup("tempDir"))
 	viper.BindPFlag("NodesAddr", configCmd.Flags().Lookup("nodesAddr"))
 	viper.BindPFlag("RunningMode", configCmd.Flags().Lookup("runMode"))
+	viper.BindPFlag("TCPSecurity", configCmd.Flags().Lookup("tcpSecurity"))
+	viper.BindPFlag("Webhooks.Dispatch", configCmd.Flags().Lookup("webhooks"))
 }
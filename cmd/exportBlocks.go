@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/AplaProject/go-apla/packages/blockarchive"
+	"github.com/AplaProject/go-apla/packages/conf"
+	"github.com/AplaProject/go-apla/packages/model"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// exportBatchSize is the number of blocks which are read from the database by one query
+const exportBatchSize = 1000
+
+var (
+	blocksFile        string
+	blocksFrom        int64
+	blocksTo          int64
+	blocksCompression string
+)
+
+// exportBlocksCmd writes the blocks to the archive
+var exportBlocksCmd = &cobra.Command{
+	Use:    "export-blocks",
+	Short:  "Export blocks to archive file",
+	PreRun: loadConfig,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := model.GormInit(
+			conf.Config.DB.Host,
+			conf.Config.DB.Port,
+			conf.Config.DB.User,
+			conf.Config.DB.Password,
+			conf.Config.DB.Name,
+		); err != nil {
+			log.WithError(err).Fatal("init db")
+			return
+		}
+		if blocksTo == 0 {
+			last := &model.Block{}
+			if _, err := last.GetMaxBlock(); err != nil {
+				log.WithError(err).Fatal("getting last block")
+				return
+			}
+			blocksTo = last.ID
+		}
+		if blocksFrom < 1 || blocksFrom > blocksTo {
+			log.WithFields(log.Fields{"from": blocksFrom, "to": blocksTo}).Fatal("wrong range of blocks")
+			return
+		}
+
+		// the archive is written to the temporary file, so the previous archive isn't broken by the failure
+		tmpFile := blocksFile + ".tmp"
+		if err := exportBlocks(tmpFile); err != nil {
+			os.Remove(tmpFile)
+			log.WithError(err).Fatal("exporting blocks")
+			return
+		}
+		if err := os.Rename(tmpFile, blocksFile); err != nil {
+			log.WithError(err).Fatal("renaming archive file")
+			return
+		}
+		log.WithFields(log.Fields{"from": blocksFrom, "to": blocksTo, "file": blocksFile}).Info("blocks have been exported")
+	},
+}
+
+func exportBlocks(fileName string) error {
+	file, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	w, err := blockarchive.NewWriter(file, blocksCompression)
+	if err != nil {
+		return err
+	}
+	for blockID := blocksFrom; blockID <= blocksTo; blockID += exportBatchSize {
+		endBlockID := blockID + exportBatchSize - 1
+		if endBlockID > blocksTo {
+			endBlockID = blocksTo
+		}
+		blocks, err := model.GetBlockchain(blockID-1, endBlockID)
+		if err != nil {
+			return err
+		}
+		if len(blocks) == 0 || blocks[0].ID != blockID {
+			return fmt.Errorf("block %d has not been found", blockID)
+		}
+		for _, b := range blocks {
+			if err = w.Write(b.ID, b.Data); err != nil {
+				return err
+			}
+		}
+		if w.Last() != endBlockID {
+			return fmt.Errorf("block %d has not been found", w.Last()+1)
+		}
+		log.WithFields(log.Fields{"block_id": endBlockID}).Debug("blocks have been exported")
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	return file.Sync()
+}
+
+func init() {
+	exportBlocksCmd.Flags().StringVar(&blocksFile, "file", "blocks.bin", "Archive file")
+	exportBlocksCmd.Flags().Int64Var(&blocksFrom, "from", 1, "First block to export")
+	exportBlocksCmd.Flags().Int64Var(&blocksTo, "to", 0, "Last block to export (default the last block)")
+	exportBlocksCmd.Flags().StringVar(&blocksCompression, "compression", blockarchive.CompressionNone,
+		fmt.Sprintf("Compression of archive (%s)", strings.Join(blockarchive.Compressions(), ", ")))
+}
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/AplaProject/go-apla/packages/block"
+	"github.com/AplaProject/go-apla/packages/blockarchive"
+	"github.com/AplaProject/go-apla/packages/conf"
+	"github.com/AplaProject/go-apla/packages/conf/syspar"
+	"github.com/AplaProject/go-apla/packages/model"
+	"github.com/AplaProject/go-apla/packages/smart"
+	"github.com/AplaProject/go-apla/packages/utils"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// importBlocksCmd plays the blocks from the archive. The blocks which are already in the blockchain
+// are skipped, so the interrupted import continues from the last played block.
+var importBlocksCmd = &cobra.Command{
+	Use:    "import-blocks",
+	Short:  "Import blocks from archive file",
+	PreRun: loadConfigWKey,
+	Run: func(cmd *cobra.Command, args []string) {
+		f := utils.LockOrDie(conf.Config.LockFilePath)
+		defer f.Unlock()
+
+		if err := model.GormInit(
+			conf.Config.DB.Host,
+			conf.Config.DB.Port,
+			conf.Config.DB.User,
+			conf.Config.DB.Password,
+			conf.Config.DB.Name,
+		); err != nil {
+			log.WithError(err).Fatal("init db")
+			return
+		}
+		count, err := importBlocks(blocksFile)
+		if err != nil {
+			log.WithFields(log.Fields{"count": count}).WithError(err).Fatal("importing blocks")
+			return
+		}
+		log.WithFields(log.Fields{"count": count, "file": blocksFile}).Info("blocks have been imported")
+	},
+}
+
+// loadState reads the system parameters and the contracts which are needed to play blocks
+func loadState() error {
+	if err := syspar.SysUpdate(nil); err != nil {
+		return err
+	}
+	if data, ok := block.GetDataFromFirstBlock(); ok {
+		syspar.SetFirstBlockData(data)
+	}
+	return smart.LoadContracts(nil)
+}
+
+func importBlocks(fileName string) (int, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	r, err := blockarchive.NewReader(file)
+	if err != nil {
+		return 0, err
+	}
+	infoBlock := &model.InfoBlock{}
+	if _, err = infoBlock.Get(); err != nil {
+		return 0, err
+	}
+	lastBlockID := infoBlock.BlockID
+	if lastBlockID > 0 {
+		if err = loadState(); err != nil {
+			return 0, err
+		}
+	}
+
+	count := 0
+	for blocksTo == 0 || lastBlockID < blocksTo {
+		b, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return count, err
+		}
+		if b.ID < lastBlockID {
+			continue
+		}
+		if b.ID == lastBlockID {
+			// the archive has to belong to the same blockchain
+			stored := &model.Block{}
+			if _, err = stored.Get(b.ID); err != nil {
+				return count, err
+			}
+			if !bytes.Equal(stored.Data, b.Data) {
+				return count, fmt.Errorf("block %d differs from block of blockchain", b.ID)
+			}
+			continue
+		}
+		if b.ID != lastBlockID+1 {
+			return count, fmt.Errorf("archive doesn't contain block %d", lastBlockID+1)
+		}
+		if err = block.InsertBlockWOForks(b.Data, false, b.ID == 1); err != nil {
+			return count, fmt.Errorf("inserting block %d: %s", b.ID, err)
+		}
+		if b.ID == 1 {
+			if err = loadState(); err != nil {
+				return count, err
+			}
+		}
+		lastBlockID = b.ID
+		count++
+		if count%exportBatchSize == 0 {
+			log.WithFields(log.Fields{"block_id": b.ID}).Info("blocks have been imported")
+		}
+	}
+	return count, nil
+}
+
+func init() {
+	importBlocksCmd.Flags().StringVar(&blocksFile, "file", "blocks.bin", "Archive file")
+	importBlocksCmd.Flags().Int64Var(&blocksTo, "to", 0, "Last block to import (default the last block of archive)")
+}
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/AplaProject/go-apla/packages/conf"
+	"github.com/AplaProject/go-apla/packages/conf/syspar"
+	"github.com/AplaProject/go-apla/packages/model"
+	"github.com/AplaProject/go-apla/packages/script"
+	"github.com/AplaProject/go-apla/packages/smart"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	lintFile      string
+	lintEcosystem int64
+)
+
+// lintCmd checks the source code of contracts and prints diagnostics as JSON
+var lintCmd = &cobra.Command{
+	Use:    "lint",
+	Short:  "Check the source code of contracts for possible errors",
+	PreRun: loadConfigWKey,
+	Run: func(cmd *cobra.Command, args []string) {
+		source, err := ioutil.ReadFile(lintFile)
+		if err != nil {
+			log.WithError(err).Fatal("reading source file")
+			return
+		}
+		if err = model.GormInit(
+			conf.Config.DB.Host,
+			conf.Config.DB.Port,
+			conf.Config.DB.User,
+			conf.Config.DB.Password,
+			conf.Config.DB.Name,
+		); err != nil {
+			log.WithError(err).Fatal("init db")
+			return
+		}
+		if err = syspar.SysUpdate(nil); err != nil {
+			log.WithError(err).Error("can't read system parameters")
+		}
+		if err = smart.LoadContracts(nil); err != nil {
+			log.WithError(err).Fatal("loading contracts")
+			return
+		}
+		diags, err := smart.GetVM().Analyze([]rune(string(source)),
+			&script.OwnerInfo{StateID: uint32(lintEcosystem)})
+		if err != nil {
+			log.WithError(err).Fatal("analyzing source")
+			return
+		}
+		out, err := json.MarshalIndent(diags, ``, `  `)
+		if err != nil {
+			log.WithError(err).Fatal("marshalling diagnostics")
+			return
+		}
+		fmt.Println(string(out))
+		if len(diags) > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	lintCmd.Flags().StringVar(&lintFile, "file", "", "file with the source code of contracts")
+	lintCmd.Flags().Int64Var(&lintEcosystem, "ecosystem", 1, "ecosystem of the contracts")
+	lintCmd.MarkFlagRequired("file")
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/AplaProject/go-apla/packages/api"
+	"github.com/AplaProject/go-apla/packages/conf"
+	"github.com/AplaProject/go-apla/packages/conf/syspar"
+	"github.com/AplaProject/go-apla/packages/model"
+	"github.com/AplaProject/go-apla/packages/smart"
+	"github.com/AplaProject/go-apla/packages/utils/tx"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	profileContract  string
+	profileParams    string
+	profileOutput    string
+	profileEcosystem int64
+	profileKeyID     int64
+)
+
+// profileCmd runs the contract with the profiler and rolls back its changes
+var profileCmd = &cobra.Command{
+	Use:    "profile",
+	Short:  "Profile the fuel and the time of the contract execution",
+	PreRun: loadConfigWKey,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := model.GormInit(
+			conf.Config.DB.Host,
+			conf.Config.DB.Port,
+			conf.Config.DB.User,
+			conf.Config.DB.Password,
+			conf.Config.DB.Name,
+		); err != nil {
+			log.WithError(err).Fatal("init db")
+			return
+		}
+		if err := syspar.SysUpdate(nil); err != nil {
+			log.WithError(err).Error("can't read system parameters")
+		}
+		if err := smart.LoadContracts(nil); err != nil {
+			log.WithError(err).Fatal("loading contracts")
+			return
+		}
+		contract := smart.VMGetContract(smart.GetVM(), profileContract, uint32(profileEcosystem))
+		if contract == nil {
+			log.WithFields(log.Fields{"contract": profileContract}).Fatal("unknown contract")
+			return
+		}
+		params := make(map[string]string)
+		if len(profileParams) > 0 {
+			if err := json.Unmarshal([]byte(profileParams), &params); err != nil {
+				log.WithError(err).Fatal("unmarshalling contract parameters")
+				return
+			}
+		}
+		sc, err := api.NewDryRunContract(contract, tx.Header{
+			Time:        time.Now().Unix(),
+			EcosystemID: profileEcosystem,
+			KeyID:       profileKeyID,
+		}, params, conf.Config.IsSupportingVDE())
+		if err != nil {
+			log.WithError(err).Fatal("preparing contract")
+			return
+		}
+		result, err := api.ProfileContract(sc)
+		if err != nil {
+			log.WithError(err).Fatal("profiling contract")
+			return
+		}
+		if len(profileOutput) > 0 {
+			if err = ioutil.WriteFile(profileOutput, result.Pprof, 0644); err != nil {
+				log.WithError(err).Fatal("writing pprof profile")
+				return
+			}
+		}
+		result.Pprof = nil
+		out, err := json.MarshalIndent(result, ``, `  `)
+		if err != nil {
+			log.WithError(err).Fatal("marshalling profile summary")
+			return
+		}
+		fmt.Println(string(out))
+	},
+}
+
+func init() {
+	profileCmd.Flags().StringVar(&profileContract, "contract", "", "name of the contract")
+	profileCmd.Flags().StringVar(&profileParams, "params", "", "parameters of the contract as JSON object")
+	profileCmd.Flags().StringVar(&profileOutput, "output", "", "filepath for the profile in pprof format")
+	profileCmd.Flags().Int64Var(&profileEcosystem, "ecosystem", 1, "ecosystem of the contract")
+	profileCmd.Flags().Int64Var(&profileKeyID, "keyId", 0, "key_id of the caller")
+	profileCmd.MarkFlagRequired("contract")
+}
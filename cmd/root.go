@@ -25,6 +25,10 @@ func init() {
 		startCmd,
 		configCmd,
 		stopNetworkCmd,
+		profileCmd,
+		lintCmd,
+		exportBlocksCmd,
+		importBlocksCmd,
 	)
 
 	// This flags are visible for all child commands
@@ -18,4 +18,5 @@ var startCmd = &cobra.Command{
 
 func init() {
 	startCmd.Flags().BoolVar(&conf.Config.TestRollBack, "testRollBack", false, "Starts special set of daemons")
+	startCmd.Flags().BoolVar(&conf.Config.FastSync, "fastsync", false, "Imports the latest state snapshot from other nodes instead of playing all blocks")
 }
@@ -59,6 +59,11 @@ type apiData struct {
 	vde           bool
 	vm            *script.VM
 	token         *jwt.Token
+	streamed      bool // the handler has written the response itself
+	route         string
+	apiKey        *model.APIKey
+	apiKeyRoutes  []string
+	tx            *model.DbTransaction // the read only transaction of the batch, it is nil usually
 }
 
 // ParamString reaturs string value of the api params
@@ -142,6 +147,13 @@ func getSignHeader(txName string, data *apiData) tx.Header {
 		EcosystemID: data.ecosystemId, KeyID: data.keyId, NetworkID: consts.NETWORK_ID}
 }
 
+// getSmartContract returns the contract of the user which is used for checking access rights
+func getSmartContract(data *apiData) *smart.SmartContract {
+	return &smart.SmartContract{VDE: data.vde, VM: data.vm, TxSmart: tx.SmartContract{
+		Header: tx.Header{EcosystemID: data.ecosystemId, KeyID: data.keyId, RoleID: data.roleId,
+			NetworkID: consts.NETWORK_ID}}}
+}
+
 func getHeader(txName string, data *apiData) (tx.Header, error) {
 	publicKey := []byte("null")
 	if _, ok := data.params[`pubkey`]; ok && len(data.params[`pubkey`].([]byte)) > 0 {
@@ -170,7 +182,7 @@ func DefaultHandler(method, pattern string, params map[string]int, handlers ...a
 		startTime := time.Now()
 		var (
 			err  error
-			data = &apiData{ecosystemId: 1}
+			data = &apiData{ecosystemId: 1, route: method + ` ` + pattern}
 		)
 		requestLogger := log.WithFields(log.Fields{"headers": r.Header, "path": r.URL.Path, "protocol": r.Proto, "remote": r.RemoteAddr})
 		requestLogger.Info("received http request")
@@ -192,9 +204,12 @@ func DefaultHandler(method, pattern string, params map[string]int, handlers ...a
 		for _, par := range ps {
 			data.params[par.Key] = par.Value
 		}
+		data.tx, _ = r.Context().Value(batchTxKey{}).(*model.DbTransaction)
 
 		ihandlers := append([]apiHandle{
+			clientRateLimit(counterName),
 			fillToken,
+			keyRateLimit(counterName),
 			fillParams(params),
 		}, handlers...)
 
@@ -204,6 +219,9 @@ func DefaultHandler(method, pattern string, params map[string]int, handlers ...a
 			}
 		}
 
+		if data.streamed {
+			return
+		}
 		jsonResult, err := json.Marshal(data.result)
 		if err != nil {
 			requestLogger.WithFields(log.Fields{"type": consts.JSONMarshallError, "error": err}).Error("marhsalling http response to json")
@@ -216,6 +234,9 @@ func DefaultHandler(method, pattern string, params map[string]int, handlers ...a
 }
 
 func fillToken(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) error {
+	if strings.HasPrefix(r.Header.Get(`Authorization`), apiKeyPrefix) {
+		return fillAPIKey(w, r, data, logger)
+	}
 	token, err := jwtToken(r)
 	if err != nil {
 		logger.WithFields(log.Fields{"type": consts.JWTError, "error": err}).Error("starting session")
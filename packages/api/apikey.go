@@ -0,0 +1,155 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/AplaProject/go-apla/packages/conf"
+	"github.com/AplaProject/go-apla/packages/consts"
+	"github.com/AplaProject/go-apla/packages/converter"
+	"github.com/AplaProject/go-apla/packages/crypto"
+	"github.com/AplaProject/go-apla/packages/model"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// apiKeyPrefix is the prefix of Authorization header which contains API key.
+// API key has the format <ecosystem>:<secret>, the ecosystem keeps the hash of the secret.
+const apiKeyPrefix = "ApiKey "
+
+// The special values of the allowed routes of API key
+const (
+	apiKeyRouteAll      = `*`
+	apiKeyRouteRead     = `read`
+	apiKeyRouteContract = `contract:`
+)
+
+// apiKeyContractRoutes are the routes which send the contracts. They are allowed if API key
+// allows any contract, the names of the contracts are checked by checkAPIKeyContract.
+var apiKeyContractRoutes = map[string]bool{
+	`POST prepare/:name`:                true,
+	`POST prepareMultiple`:              true,
+	`POST contract/:request_id`:         true,
+	`POST contractMultiple/:request_id`: true,
+	`POST txstatusMultiple`:             true,
+}
+
+// apiKeyAllowed returns true if the route is in the list of allowed routes
+func apiKeyAllowed(routes []string, route, contract string) bool {
+	for _, item := range routes {
+		switch {
+		case item == apiKeyRouteAll || item == route:
+			return true
+		case item == apiKeyRouteRead:
+			if strings.HasPrefix(route, `GET `) {
+				return true
+			}
+		case strings.HasPrefix(item, apiKeyRouteContract):
+			if !apiKeyContractRoutes[route] {
+				continue
+			}
+			if route != `POST prepare/:name` ||
+				trimEcosystem(item[len(apiKeyRouteContract):]) == trimEcosystem(contract) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// trimEcosystem removes @ecosystem prefix of the contract name
+func trimEcosystem(name string) string {
+	if strings.HasPrefix(name, `@`) {
+		name = strings.TrimLeft(name[1:], `0123456789`)
+	}
+	return name
+}
+
+func fillAPIKey(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) error {
+	if conf.Config.IsSupportingVDE() {
+		logger.WithFields(log.Fields{"type": consts.VDEManagerError}).Error("API keys are not supported by VDE")
+		return errorAPI(w, `E_UNAUTHORIZED`, http.StatusUnauthorized)
+	}
+	auth := r.Header.Get(`Authorization`)[len(apiKeyPrefix):]
+	sep := strings.IndexByte(auth, ':')
+	if sep <= 0 {
+		logger.WithFields(log.Fields{"type": consts.InvalidObject}).Error("wrong format of API key")
+		return errorAPI(w, `E_UNAUTHORIZED`, http.StatusUnauthorized)
+	}
+	ecosystemID := converter.StrToInt64(auth[:sep])
+	hash, err := crypto.HashHex([]byte(auth[sep+1:]))
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.CryptoError, "error": err}).Error("hashing API key")
+		return errorAPI(w, err, http.StatusInternalServerError)
+	}
+	apiKey := &model.APIKey{}
+	found, err := apiKey.SetTablePrefix(ecosystemID).GetByHash(hash)
+	if err != nil || !found {
+		logger.WithFields(log.Fields{"type": consts.NotFound, "ecosystem": ecosystemID, "error": err}).Error("API key not found")
+		return errorAPI(w, `E_UNAUTHORIZED`, http.StatusUnauthorized)
+	}
+	routes, err := apiKey.RouteList()
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.JSONUnmarshallError, "error": err}).Error("unmarshalling routes of API key")
+		return errorAPI(w, err, http.StatusInternalServerError)
+	}
+	allowed := apiKeyAllowed(routes, data.route, data.ParamString(`name`))
+	logAPIKey(r, ecosystemID, apiKey, data.route, allowed, logger)
+	if !allowed {
+		logger.WithFields(log.Fields{"type": consts.AccessDenied, "api_key": apiKey.ID, "route": data.route}).Error("route is not allowed for API key")
+		return errorAPI(w, `E_PERMISSION`, http.StatusForbidden)
+	}
+	data.apiKey = apiKey
+	data.apiKeyRoutes = routes
+	claims := &JWTClaims{
+		EcosystemID: converter.Int64ToStr(ecosystemID),
+		KeyID:       converter.Int64ToStr(apiKey.KeyID),
+		RoleID:      converter.Int64ToStr(apiKey.RoleID),
+	}
+	if err = fillTokenData(data, claims, logger); err != nil {
+		return errorAPI(w, "E_SERVER", http.StatusNotFound, err)
+	}
+	return nil
+}
+
+// logAPIKey writes the usage of API key to the audit log
+func logAPIKey(r *http.Request, ecosystemID int64, apiKey *model.APIKey, route string, allowed bool, logger *log.Entry) {
+	record := &model.APIKeyLog{
+		Ecosystem: ecosystemID,
+		APIKeyID:  apiKey.ID,
+		KeyID:     apiKey.KeyID,
+		Route:     route,
+		Remote:    r.RemoteAddr,
+		Allowed:   allowed,
+		Time:      time.Now().Unix(),
+	}
+	if err := record.Create(); err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("writing API key log")
+	}
+}
+
+// checkAPIKeyContract checks that the contract is allowed for API key of the request
+func checkAPIKeyContract(w http.ResponseWriter, data *apiData, contract string, logger *log.Entry) error {
+	if data.apiKey == nil || apiKeyAllowed(data.apiKeyRoutes, `POST prepare/:name`, contract) {
+		return nil
+	}
+	logger.WithFields(log.Fields{"type": consts.AccessDenied, "api_key": data.apiKey.ID, "contract": contract}).Error("contract is not allowed for API key")
+	return errorAPI(w, `E_PERMISSION`, http.StatusForbidden)
+}
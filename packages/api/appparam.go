@@ -34,7 +34,7 @@ func appParam(w http.ResponseWriter, r *http.Request, data *apiData, logger *log
 	ap := &model.AppParam{}
 	ap.SetTablePrefix(prefix)
 	name := data.params[`name`].(string)
-	found, err := ap.Get(nil, converter.StrToInt64(data.params[`appid`].(string)), name)
+	found, err := ap.Get(data.tx, converter.StrToInt64(data.params[`appid`].(string)), name)
 	if err != nil {
 		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("Getting app parameter by name")
 		return errorAPI(w, err, http.StatusInternalServerError)
@@ -43,7 +43,7 @@ func appParams(w http.ResponseWriter, r *http.Request, data *apiData, logger *lo
 	}
 	ap := &model.AppParam{}
 	ap.SetTablePrefix(prefix)
-	list, err := ap.GetAllAppParameters(converter.StrToInt64(data.params[`appid`].(string)))
+	list, err := ap.GetAllAppParameters(data.tx, converter.StrToInt64(data.params[`appid`].(string)))
 	if err != nil {
 		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("Getting all app parameters")
 	}
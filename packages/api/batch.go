@@ -0,0 +1,169 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/AplaProject/go-apla/packages/consts"
+	"github.com/AplaProject/go-apla/packages/model"
+
+	hr "github.com/julienschmidt/httprouter"
+	log "github.com/sirupsen/logrus"
+)
+
+// batchLimit is the maximum number of requests in the batch
+const batchLimit = 50
+
+var (
+	// batchRouter contains GET routes which can be called in the batch
+	batchRouter = hr.New()
+
+	// batchRoutes are the routes whose handlers read the database by the transaction of the batch
+	batchRoutes = map[string]bool{
+		`GET appparam/:appid/:name`: true,
+		`GET appparams/:appid`:      true,
+		`GET contracts`:             true,
+		`GET ecosystemparam/:name`:  true,
+		`GET ecosystemparams`:       true,
+		`GET list/:name`:            true,
+		`GET row/:name/:id`:         true,
+		`GET tables`:                true,
+	}
+)
+
+// batchTxKey is the key of the context of the request from the batch, its value is the transaction of the batch
+type batchTxKey struct{}
+
+type batchRequest struct {
+	Route  string            `json:"route"`
+	Params map[string]string `json:"params"`
+}
+
+type batchItem struct {
+	Status int          `json:"status"`
+	Result interface{}  `json:"result,omitempty"`
+	Error  *errorResult `json:"error,omitempty"`
+}
+
+type batchResult struct {
+	Results []batchItem `json:"results"`
+}
+
+// batchWriter keeps the response of the request from the batch
+type batchWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *batchWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *batchWriter) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.body.Write(data)
+}
+
+func (w *batchWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+// isBatchRoute returns true if the route can be called in the batch
+func isBatchRoute(method, pattern string) bool {
+	return batchRoutes[method+` `+pattern]
+}
+
+func batch(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) error {
+	var requests []batchRequest
+	if err := json.Unmarshal([]byte(data.params[`data`].(string)), &requests); err != nil {
+		logger.WithFields(log.Fields{"type": consts.JSONUnmarshallError, "error": err}).Error("unmarshalling batch requests")
+		return errorAPI(w, err, http.StatusBadRequest)
+	}
+	if len(requests) > batchLimit {
+		logger.WithFields(log.Fields{"type": consts.ParameterExceeded, "count": len(requests)}).Error("too many requests in the batch")
+		return errorAPI(w, `E_LIMITBATCH`, http.StatusBadRequest, batchLimit)
+	}
+	// all requests see the same state of the database
+	tx, err := model.StartReadOnlyTransaction()
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("starting read only transaction")
+		return errorAPI(w, err, http.StatusInternalServerError)
+	}
+	defer tx.Rollback()
+
+	result := &batchResult{Results: make([]batchItem, len(requests))}
+	for i, req := range requests {
+		result.Results[i] = batchCall(r, tx, req, logger)
+	}
+	data.result = result
+	return nil
+}
+
+// batchCall executes the request from the batch with the authorization of the batch request
+func batchCall(r *http.Request, tx *model.DbTransaction, req batchRequest, logger *log.Entry) batchItem {
+	notFound := batchItem{Status: http.StatusNotFound, Error: &errorResult{Error: `E_ROUTENOTFOUND`,
+		Msg: fmt.Sprintf(apiErrors[`E_ROUTENOTFOUND`], req.Route), Params: []string{req.Route}}}
+	u, err := url.Parse(consts.ApiPath + strings.TrimPrefix(req.Route, `/`))
+	if err != nil {
+		return notFound
+	}
+	handle, params, _ := batchRouter.Lookup(`GET`, u.Path)
+	if handle == nil {
+		return notFound
+	}
+	query := u.Query()
+	for key, value := range req.Params {
+		query.Set(key, value)
+	}
+	u.RawQuery = query.Encode()
+	sub, err := http.NewRequest(`GET`, u.String(), nil)
+	if err != nil {
+		return notFound
+	}
+	sub = sub.WithContext(context.WithValue(r.Context(), batchTxKey{}, tx))
+	sub.Header.Set(`Authorization`, r.Header.Get(`Authorization`))
+	sub.RemoteAddr = r.RemoteAddr
+
+	rw := &batchWriter{header: make(http.Header)}
+	handle(rw, sub, params)
+	item := batchItem{Status: rw.status}
+	if item.Status == 0 {
+		item.Status = http.StatusOK
+	}
+	if item.Status == http.StatusOK {
+		item.Result = json.RawMessage(rw.body.Bytes())
+		return item
+	}
+	item.Error = &errorResult{}
+	if err = json.Unmarshal(rw.body.Bytes(), item.Error); err != nil {
+		logger.WithFields(log.Fields{"type": consts.JSONUnmarshallError, "error": err, "route": req.Route}).Error("unmarshalling error of batch request")
+		item.Error = &errorResult{Error: `E_SERVER`, Msg: rw.body.String()}
+	}
+	return item
+}
@@ -109,6 +109,11 @@ func getDataMultiRequestParams(fields []*script.FieldInfo, params map[string]str
 				return idata, err
 			}
 			idata = append(append(idata, converter.EncodeLength(int64(len(bytes)))...), bytes...)
+		default:
+			// the records are sent as JSON objects
+			if len(script.RecordName(fitem.Type)) > 0 {
+				idata = append(append(idata, converter.EncodeLength(int64(len(val)))...), []byte(val)...)
+			}
 		}
 	}
 	return idata, nil
@@ -173,6 +178,11 @@ func getData(fields []*script.FieldInfo, req *tx.Request, w http.ResponseWriter,
 				return idata, err
 			}
 			idata = append(append(idata, converter.EncodeLength(int64(len(bytes)))...), bytes...)
+		default:
+			// the records are sent as JSON objects
+			if len(script.RecordName(fitem.Type)) > 0 {
+				idata = append(append(idata, converter.EncodeLength(int64(len(val)))...), []byte(val)...)
+			}
 		}
 	}
 	return idata, nil
@@ -239,6 +249,9 @@ func (c *contractHandlers) contractMulti(w http.ResponseWriter, r *http.Request,
 	payover := multiRequest.Payover
 	hashes := []string{}
 	for i, c := range req.Contracts {
+		if err = checkAPIKeyContract(w, data, c.Contract, logger); err != nil {
+			return err
+		}
 		contract := smart.VMGetContract(data.vm, c.Contract, uint32(data.ecosystemId))
 		if contract == nil {
 			return errorAPI(w, "E_CONTRACT", http.StatusBadRequest, c.Contract)
@@ -302,6 +315,9 @@ func (c *contractHandlers) contract(w http.ResponseWriter, r *http.Request, data
 	if !ok {
 		return errorAPI(w, "E_REQUESTNOTFOUND", http.StatusNotFound, requestID)
 	}
+	if err := checkAPIKeyContract(w, data, req.Contract, logger); err != nil {
+		return err
+	}
 	contract := smart.VMGetContract(data.vm, req.Contract, uint32(data.ecosystemId))
 	if contract == nil {
 		return errorAPI(w, "E_CONTRACT", http.StatusBadRequest, req.Contract)
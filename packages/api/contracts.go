@@ -39,7 +39,7 @@ func getContracts(w http.ResponseWriter, r *http.Request, data *apiData, logger
 
 	table := getPrefix(data) + `_contracts`
 
-	count, err := model.GetRecordsCountTx(nil, table)
+	count, err := model.GetRecordsCountTx(data.tx, table)
 	if err != nil {
 		logger.WithFields(log.Fields{"type": consts.DBError, "error": err, "table": table}).Error("Getting table records count")
 		return errorAPI(w, err.Error(), http.StatusInternalServerError)
@@ -50,7 +50,7 @@ func getContracts(w http.ResponseWriter, r *http.Request, data *apiData, logger
 	} else {
 		limit = 25
 	}
-	list, err := model.GetAll(`select * from "`+table+`" order by id desc`+
+	list, err := model.GetAllTx(data.tx, `select * from "`+table+`" order by id desc`+
 		fmt.Sprintf(` offset %d `, data.params[`offset`].(int64)), limit)
 	if err != nil {
 		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting all")
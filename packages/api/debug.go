@@ -0,0 +1,212 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/AplaProject/go-apla/packages/consts"
+	"github.com/AplaProject/go-apla/packages/model"
+	"github.com/AplaProject/go-apla/packages/script"
+	"github.com/AplaProject/go-apla/packages/smart"
+	"github.com/AplaProject/go-apla/packages/utils"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// debugExpire is the time of inactivity after which the debug session is stopped
+	debugExpire = 5 * time.Minute
+	// debugLifetime is the maximum lifetime of the debug session, it is not prolonged by commands
+	// because the session keeps the locks of the database transaction
+	debugLifetime = 10 * time.Minute
+	// debugMaxSessions is the maximum number of debug sessions of the node, every session keeps
+	// the database transaction
+	debugMaxSessions = 10
+	// debugMaxKeySessions is the maximum number of debug sessions of the key
+	debugMaxKeySessions = 2
+)
+
+type debugResult struct {
+	Session string             `json:"session,omitempty"`
+	State   *script.DebugState `json:"state"`
+}
+
+// debugSession is the contract which is being debugged. All changes of the contract
+// are made in the transaction which is always rolled back.
+type debugSession struct {
+	mutex       sync.Mutex
+	id          string
+	keyID       int64
+	ecosystemID int64
+	debugger    *script.Debugger
+	dbTx        *model.DbTransaction
+	timer       *time.Timer
+	lifetime    *time.Timer
+	closed      bool
+}
+
+var debugSessions = struct {
+	sync.Mutex
+	list  map[string]*debugSession
+	count int            // the number of sessions including the starting ones
+	keys  map[string]int // the number of sessions of keys
+}{list: make(map[string]*debugSession), keys: make(map[string]int)}
+
+func debugKey(ecosystemID, keyID int64) string {
+	return fmt.Sprintf(`%d:%d`, ecosystemID, keyID)
+}
+
+// reserveDebugSession returns false if the key or the node has too many debug sessions
+func reserveDebugSession(ecosystemID, keyID int64) bool {
+	key := debugKey(ecosystemID, keyID)
+	debugSessions.Lock()
+	defer debugSessions.Unlock()
+	if debugSessions.count >= debugMaxSessions || debugSessions.keys[key] >= debugMaxKeySessions {
+		return false
+	}
+	debugSessions.count++
+	debugSessions.keys[key]++
+	return true
+}
+
+// releaseDebugSession must be called when the session which has been reserved is closed
+func releaseDebugSession(ecosystemID, keyID int64) {
+	key := debugKey(ecosystemID, keyID)
+	debugSessions.Lock()
+	defer debugSessions.Unlock()
+	debugSessions.count--
+	if debugSessions.keys[key]--; debugSessions.keys[key] <= 0 {
+		delete(debugSessions.keys, key)
+	}
+}
+
+func getDebugSession(id string) *debugSession {
+	debugSessions.Lock()
+	defer debugSessions.Unlock()
+	return debugSessions.list[id]
+}
+
+// close rolls back the changes of the contract, it must be called when the session is locked
+func (s *debugSession) close() {
+	if s.closed {
+		return
+	}
+	s.closed = true
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+	if s.lifetime != nil {
+		s.lifetime.Stop()
+	}
+	s.dbTx.Rollback()
+	debugSessions.Lock()
+	delete(debugSessions.list, s.id)
+	debugSessions.Unlock()
+	releaseDebugSession(s.ecosystemID, s.keyID)
+}
+
+// expire stops the execution of the contract if the session has not been used for a long time
+// or its lifetime is over
+func (s *debugSession) expire() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.closed {
+		return
+	}
+	if !s.debugger.Finished() {
+		s.debugger.Command(script.DebugStop)
+	}
+	s.close()
+}
+
+func startDebug(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) error {
+	var breakpoints []script.Breakpoint
+	if list := data.ParamString(`breakpoints`); len(list) > 0 {
+		if err := json.Unmarshal([]byte(list), &breakpoints); err != nil {
+			logger.WithFields(log.Fields{"type": consts.JSONUnmarshallError, "error": err}).Error("unmarshalling breakpoints")
+			return errorAPI(w, err, http.StatusBadRequest)
+		}
+	}
+	if !reserveDebugSession(data.ecosystemId, data.keyId) {
+		logger.WithFields(log.Fields{"type": consts.ParameterExceeded, "key_id": data.keyId}).Warning("too many debug sessions")
+		return errorAPI(w, `E_LIMITDEBUG`, http.StatusTooManyRequests, debugMaxKeySessions)
+	}
+	sc, err := dryRunContract(w, r, data, data.ParamString(`name`), logger)
+	if err != nil {
+		releaseDebugSession(data.ecosystemId, data.keyId)
+		return err
+	}
+	session := &debugSession{
+		id:          utils.UUID(),
+		keyID:       data.keyId,
+		ecosystemID: data.ecosystemId,
+		debugger:    script.NewDebugger(breakpoints),
+		dbTx:        sc.DbTransaction,
+	}
+	sc.Debugger = session.debugger
+
+	session.mutex.Lock()
+	defer session.mutex.Unlock()
+	state := session.debugger.Start(func() (interface{}, error) {
+		return sc.CallContract(smart.CallInit | smart.CallCondition | smart.CallAction)
+	})
+	result := &debugResult{State: state}
+	if state.Finished {
+		session.close()
+	} else {
+		debugSessions.Lock()
+		debugSessions.list[session.id] = session
+		debugSessions.Unlock()
+		session.timer = time.AfterFunc(debugExpire, session.expire)
+		session.lifetime = time.AfterFunc(debugLifetime, session.expire)
+		result.Session = session.id
+	}
+	data.result = result
+	return nil
+}
+
+func debugCommand(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) error {
+	id := data.ParamString(`session`)
+	session := getDebugSession(id)
+	if session == nil || session.keyID != data.keyId || session.ecosystemID != data.ecosystemId {
+		return errorAPI(w, `E_DEBUGSESSION`, http.StatusNotFound, id)
+	}
+	session.mutex.Lock()
+	defer session.mutex.Unlock()
+	if session.closed {
+		return errorAPI(w, `E_DEBUGSESSION`, http.StatusNotFound, id)
+	}
+	state, err := session.debugger.Command(data.ParamString(`cmd`))
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.InvalidObject, "error": err}).Error("debugging contract")
+		return errorAPI(w, err.Error(), http.StatusBadRequest)
+	}
+	result := &debugResult{State: state}
+	if state.Finished {
+		session.close()
+	} else {
+		session.timer.Reset(debugExpire)
+		result.Session = id
+	}
+	data.result = result
+	return nil
+}
@@ -0,0 +1,114 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/AplaProject/go-apla/packages/consts"
+	"github.com/AplaProject/go-apla/packages/crypto"
+	"github.com/AplaProject/go-apla/packages/model"
+	"github.com/AplaProject/go-apla/packages/script"
+	"github.com/AplaProject/go-apla/packages/smart"
+	"github.com/AplaProject/go-apla/packages/utils"
+	"github.com/AplaProject/go-apla/packages/utils/tx"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/vmihailenco/msgpack.v2"
+)
+
+const (
+	// dryRunLockTimeout is the maximum time of waiting for a lock by the dry run contract
+	dryRunLockTimeout = 5 * time.Second
+	// dryRunStatementTimeout is the maximum time of the execution of a query by the dry run contract
+	dryRunStatementTimeout = 10 * time.Second
+)
+
+// NewDryRunContract prepares the contract for the execution without the signature and the payment.
+// The contract is run in the new database transaction which must be rolled back by the caller.
+func NewDryRunContract(contract *smart.Contract, header tx.Header, params map[string]string, vde bool) (*smart.SmartContract, error) {
+	logger := log.WithFields(log.Fields{"contract_name": contract.Name})
+	info := contract.Block.Info.(*script.ContractInfo)
+
+	var err error
+	idata := make([]byte, 0)
+	if info.Tx != nil {
+		if idata, err = getDataMultiRequestParams(*info.Tx, params, nil, logger); err != nil {
+			return nil, err
+		}
+	}
+	header.Type = int(info.ID)
+	header.PublicKey = []byte("null")
+	header.NetworkID = consts.NETWORK_ID
+	serializedData, err := msgpack.Marshal(tx.SmartContract{Header: header, Data: idata})
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.MarshallingError, "error": err}).Error("marshalling smart contract to msgpack")
+		return nil, err
+	}
+	hash, err := crypto.Hash(serializedData)
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.CryptoError, "error": err}).Error("getting hash of contract data")
+		return nil, err
+	}
+
+	sc := &smart.SmartContract{VDE: vde, DryRun: true, TxHash: hash}
+	if err = InitSmartContract(sc, serializedData); err != nil {
+		return nil, err
+	}
+	if !vde {
+		infoBlock := &model.InfoBlock{}
+		if _, err = infoBlock.Get(); err != nil {
+			logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting info block")
+			return nil, err
+		}
+		sc.BlockData = &utils.BlockData{BlockID: infoBlock.BlockID + 1, Time: header.Time,
+			EcosystemID: infoBlock.EcosystemID, KeyID: infoBlock.KeyID, Version: consts.BLOCK_VERSION}
+	}
+	if sc.DbTransaction, err = model.StartTransaction(); err != nil {
+		return nil, err
+	}
+	if err = sc.DbTransaction.SetTimeouts(dryRunLockTimeout, dryRunStatementTimeout); err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("setting timeouts of dry run transaction")
+		sc.DbTransaction.Rollback()
+		return nil, err
+	}
+	return sc, nil
+}
+
+// dryRunContract prepares the contract of the request for the execution without the signature.
+// The parameters of the contract are taken from the form values.
+func dryRunContract(w http.ResponseWriter, r *http.Request, data *apiData, name string, logger *log.Entry) (*smart.SmartContract, error) {
+	contract := smart.VMGetContract(data.vm, name, uint32(data.ecosystemId))
+	if contract == nil {
+		return nil, errorAPI(w, `E_CONTRACT`, http.StatusBadRequest, name)
+	}
+	params := make(map[string]string)
+	for key := range r.Form {
+		params[key] = r.FormValue(key)
+	}
+	sc, err := NewDryRunContract(contract, tx.Header{
+		Time:        time.Now().Unix(),
+		EcosystemID: data.ecosystemId,
+		KeyID:       data.keyId,
+		RoleID:      data.roleId,
+	}, params, data.vde)
+	if err != nil {
+		return nil, errorAPI(w, err, http.StatusBadRequest)
+	}
+	return sc, nil
+}
@@ -26,6 +26,10 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+type ecosystemNameResult struct {
+	EcosystemName string `json:"ecosystem_name"`
+}
+
 func ecosystemParam(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) (err error) {
 	_, prefix, err := checkEcosystem(w, data, logger)
 	if err != nil {
@@ -34,7 +38,7 @@ func ecosystemParam(w http.ResponseWriter, r *http.Request, data *apiData, logge
 	sp := &model.StateParameter{}
 	sp.SetTablePrefix(prefix)
 	name := data.params[`name`].(string)
-	found, err := sp.Get(nil, name)
+	found, err := sp.Get(data.tx, name)
 	if err != nil {
 		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("Getting state parameter by name")
 		return errorAPI(w, err, http.StatusInternalServerError)
@@ -62,10 +66,6 @@ func getEcosystemName(w http.ResponseWriter, r *http.Request, data *apiData, log
 		return errorAPI(w, `E_PARAMNOTFOUND`, http.StatusNotFound, "name")
 	}
 
-	data.result = &struct {
-		EcosystemName string `json:"ecosystem_name"`
-	}{
-		EcosystemName: ecosystems.Name,
-	}
+	data.result = &ecosystemNameResult{EcosystemName: ecosystems.Name}
 	return nil
 }
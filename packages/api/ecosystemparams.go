@@ -49,7 +49,7 @@ func ecosystemParams(w http.ResponseWriter, r *http.Request, data *apiData, logg
 	}
 	sp := &model.StateParameter{}
 	sp.SetTablePrefix(prefix)
-	list, err := sp.GetAllStateParameters()
+	list, err := sp.GetAllStateParameters(data.tx)
 	if err != nil {
 		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("Getting all state parameters")
 	}
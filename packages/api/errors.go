@@ -20,6 +20,7 @@ var (
 	apiErrors = map[string]string{
 		`E_CONTRACT`:        `There is not %s contract`,
 		`E_DBNIL`:           `DB is nil`,
+		`E_DEBUGSESSION`:    `Debug session %s doesn't exist`,
 		`E_DELETEDKEY`:      `The key is deleted`,
 		`E_ECOSYSTEM`:       `Ecosystem %d doesn't exist`,
 		`E_EMPTYPUBLIC`:     `Public key is undefined`,
@@ -29,7 +30,11 @@ var (
 		`E_HEAVYPAGE`:       `This page is heavy`,
 		`E_INSTALLED`:       `Apla is already installed`,
 		`E_INVALIDWALLET`:   `Wallet %s is not valid`,
+		`E_LIMITBATCH`:      `The number of requests is too big (%d)`,
+		`E_LIMITDEBUG`:      `Too many debug sessions, the limit of the key is %d`,
 		`E_LIMITFORSIGN`:    `Length of forsign is too big (%d)`,
+		`E_LIMITHASHES`:     `The number of hashes is too big (%d)`,
+		`E_LIMITREQUESTS`:   `Too many requests, retry after %d seconds`,
 		`E_LIMITTXSIZE`:     `The size of tx is too big (%d)`,
 		`E_NOTFOUND`:        `Page not found`,
 		`E_NOTINSTALLED`:    `Apla is not installed`,
@@ -48,9 +53,11 @@ var (
 		`E_UNAUTHORIZED`:    `Unauthorized`,
 		`E_UNDEFINEVAL`:     `Value %s is undefined`,
 		`E_UNKNOWNUID`:      `Unknown uid`,
+		`E_WHERE`:           `Where condition %s is not valid`,
 		`E_VDE`:             `Virtual Dedicated Ecosystem %d doesn't exist`,
 		`E_VDECREATED`:      `Virtual Dedicated Ecosystem is already created`,
 		`E_REQUESTNOTFOUND`: `Request %s doesn't exist`,
+		`E_ROUTENOTFOUND`:   `Route %s has not been found`,
 		`E_UPDATING`:        `Node is updating blockchain`,
 		`E_STOPPING`:        `Network is stopping`,
 	}
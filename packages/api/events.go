@@ -0,0 +1,73 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/AplaProject/go-apla/packages/consts"
+	"github.com/AplaProject/go-apla/packages/model"
+	"github.com/AplaProject/go-apla/packages/publisher"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const maxEventsLimit = 1000
+
+type eventsResult struct {
+	Channel string        `json:"channel"`
+	List    []model.Event `json:"list"`
+}
+
+// getEvents returns the events of the ecosystem. The events can be filtered by the contract,
+// the name, the range of blocks and the values of data fields which are passed in where
+// parameter as JSON object, for example, where={"recipient":"1234"}
+func getEvents(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) error {
+	filter := model.EventFilter{
+		Contract:  data.params[`contract`].(string),
+		Name:      data.params[`name`].(string),
+		Data:      data.params[`where`].(string),
+		FromBlock: data.params[`from_block`].(int64),
+		ToBlock:   data.params[`to_block`].(int64),
+		Offset:    data.params[`offset`].(int64),
+		Limit:     data.params[`limit`].(int64),
+	}
+	if len(filter.Contract) > 0 && filter.Contract[0] != '@' {
+		filter.Contract = fmt.Sprintf(`@%d%s`, data.ecosystemId, filter.Contract)
+	}
+	if len(filter.Data) > 0 {
+		var where map[string]interface{}
+		if err := json.Unmarshal([]byte(filter.Data), &where); err != nil {
+			logger.WithFields(log.Fields{"type": consts.JSONUnmarshallError, "error": err}).Error("unmarshalling where of events")
+			return errorAPI(w, `E_WHERE`, http.StatusBadRequest, filter.Data)
+		}
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = 25
+	} else if filter.Limit > maxEventsLimit {
+		filter.Limit = maxEventsLimit
+	}
+	list, err := model.GetEvents(data.ecosystemId, &filter)
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting events")
+		return errorAPI(w, err, http.StatusInternalServerError)
+	}
+	data.result = &eventsResult{Channel: publisher.EventsChannel(data.ecosystemId), List: list}
+	return nil
+}
@@ -0,0 +1,397 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/AplaProject/go-apla/packages/block"
+	"github.com/AplaProject/go-apla/packages/consts"
+	"github.com/AplaProject/go-apla/packages/converter"
+	"github.com/AplaProject/go-apla/packages/model"
+	"github.com/AplaProject/go-apla/packages/smart"
+	"github.com/AplaProject/go-apla/packages/transaction"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	explorerMaxBlocks = 20
+	explorerMaxTxs    = 100
+)
+
+type explorerTxHeader struct {
+	Type          int    `json:"type"`
+	Time          int64  `json:"time"`
+	EcosystemID   int64  `json:"ecosystem_id"`
+	KeyID         int64  `json:"key_id"`
+	RoleID        int64  `json:"role_id"`
+	NetworkID     int64  `json:"network_id"`
+	NodePosition  int64  `json:"node_position"`
+	PublicKey     string `json:"public_key"`
+	BinSignatures string `json:"signatures"`
+}
+
+type explorerRollback struct {
+	Table   string          `json:"table"`
+	TableID string          `json:"table_id"`
+	Data    json.RawMessage `json:"data"`
+}
+
+type explorerTx struct {
+	Hash           string                 `json:"hash"`
+	BlockID        int64                  `json:"block_id"`
+	Type           int64                  `json:"type"`
+	Time           int64                  `json:"time"`
+	KeyID          int64                  `json:"key_id"`
+	Contract       string                 `json:"contract"`
+	Header         *explorerTxHeader      `json:"header,omitempty"`
+	Params         map[string]interface{} `json:"params,omitempty"`
+	TokenEcosystem int64                  `json:"token_ecosystem,omitempty"`
+	Fuel           int64                  `json:"fuel"`
+	Commission     string                 `json:"commission"`
+	Rollback       []explorerRollback     `json:"rollback"`
+}
+
+type explorerBlock struct {
+	ID            int64        `json:"id"`
+	Hash          string       `json:"hash"`
+	RollbacksHash string       `json:"rollbacks_hash"`
+	EcosystemID   int64        `json:"ecosystem_id"`
+	KeyID         int64        `json:"key_id"`
+	NodePosition  int64        `json:"node_position"`
+	Time          int64        `json:"time"`
+	Version       int          `json:"version"`
+	TxCount       int32        `json:"tx_count"`
+	Transactions  []explorerTx `json:"transactions"`
+}
+
+type explorerBlocksResult struct {
+	List []explorerBlock `json:"list"`
+}
+
+type explorerTxsResult struct {
+	List []explorerTx `json:"list"`
+}
+
+// explorerAccess checks the read access of the caller to the data of transactions. The access
+// to the tables is cached during the request.
+type explorerAccess struct {
+	sc     *smart.SmartContract
+	tables map[string]bool
+}
+
+func newExplorerAccess(data *apiData) *explorerAccess {
+	return &explorerAccess{sc: getSmartContract(data), tables: make(map[string]bool)}
+}
+
+// rollback returns the previous values of the record which can be read by the caller, ok is false
+// if the caller can't read the table or any of the columns
+func (a *explorerAccess) rollback(table string, data string) (value json.RawMessage, ok bool) {
+	readable, cached := a.tables[table]
+	if !cached {
+		_, err := a.sc.AccessTablePerm(table, `read`)
+		readable = err == nil
+		a.tables[table] = readable
+	}
+	if !readable {
+		return nil, false
+	}
+	if len(data) == 0 {
+		return json.RawMessage(`null`), true
+	}
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(data), &values); err != nil {
+		log.WithFields(log.Fields{"type": consts.JSONUnmarshallError, "error": err, "table": table}).Error("unmarshalling rollback data")
+		return nil, false
+	}
+	columns := make([]string, 0, len(values))
+	for column := range values {
+		columns = append(columns, column)
+	}
+	if len(columns) > 0 {
+		if err := a.sc.AccessColumns(table, &columns, false); err != nil {
+			return nil, false
+		}
+	}
+	readValues := make(map[string]json.RawMessage, len(columns))
+	for _, column := range columns {
+		readValues[column] = values[column]
+	}
+	out, err := json.Marshal(readValues)
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.JSONMarshallError, "error": err, "table": table}).Error("marshalling rollback data")
+		return nil, false
+	}
+	return out, true
+}
+
+// explorerParams returns the decoded fields of the data of the transaction
+func explorerParams(t *transaction.Transaction) map[string]interface{} {
+	params := make(map[string]interface{})
+	for key, value := range t.TxData {
+		if key == `forsign` {
+			continue
+		}
+		if bin, ok := value.([]byte); ok {
+			value = hex.EncodeToString(bin)
+		}
+		params[key] = value
+	}
+	return params
+}
+
+// explorerTransaction returns the decoded transaction with its rollback records, the spent fuel
+// and the paid commission. The rollback records are returned if the caller can read them.
+func explorerTransaction(blockID int64, t *transaction.Transaction, rollbacks []model.RollbackTx,
+	fuel int64, access *explorerAccess) (*explorerTx, error) {
+	item := &explorerTx{
+		Hash:       hex.EncodeToString(t.TxHash),
+		BlockID:    blockID,
+		Type:       t.TxType,
+		Time:       t.TxTime,
+		KeyID:      t.TxKeyID,
+		Params:     explorerParams(t),
+		Fuel:       fuel,
+		Commission: `0`,
+		Rollback:   make([]explorerRollback, 0),
+	}
+	if t.TxContract != nil {
+		item.Contract = t.TxContract.Name
+		item.Type = int64(t.TxHeader.Type)
+	} else if name, ok := consts.TxTypes[int(t.TxType)]; ok {
+		item.Contract = name
+	}
+	if t.TxHeader != nil {
+		item.Header = &explorerTxHeader{
+			Type:          t.TxHeader.Type,
+			Time:          t.TxHeader.Time,
+			EcosystemID:   t.TxHeader.EcosystemID,
+			KeyID:         t.TxHeader.KeyID,
+			RoleID:        t.TxHeader.RoleID,
+			NetworkID:     t.TxHeader.NetworkID,
+			NodePosition:  t.TxHeader.NodePosition,
+			PublicKey:     hex.EncodeToString(t.TxHeader.PublicKey),
+			BinSignatures: hex.EncodeToString(t.TxHeader.BinSignatures),
+		}
+	}
+	for _, rb := range rollbacks {
+		if !bytes.Equal(rb.TxHash, t.TxHash) {
+			continue
+		}
+		if data, ok := access.rollback(rb.NameTable, rb.Data); ok {
+			item.Rollback = append(item.Rollback, explorerRollback{Table: rb.NameTable, TableID: rb.TableID, Data: data})
+		}
+		// the commission for the contract is written to the history of the token ecosystem
+		if t.TxContract == nil || item.TokenEcosystem != 0 || !strings.HasSuffix(rb.NameTable, `_history`) {
+			continue
+		}
+		commission, err := model.GetTxCommission(rb.NameTable, blockID, t.TxHash)
+		if err != nil {
+			log.WithFields(log.Fields{"type": consts.DBError, "error": err, "table": rb.NameTable}).Error("getting commission of transaction")
+			return nil, err
+		}
+		if commission.Sign() > 0 {
+			item.TokenEcosystem = converter.StrToInt64(strings.TrimSuffix(rb.NameTable, `_history`))
+			item.Commission = commission.String()
+		}
+	}
+	return item, nil
+}
+
+// explorerBlockInfo decodes the block. If hashes is not empty then only these transactions are returned.
+func explorerBlockInfo(b *model.Block, hashes map[string]bool, access *explorerAccess) (*explorerBlock, error) {
+	blk, err := block.UnmarshallBlock(bytes.NewBuffer(b.Data), true)
+	if err != nil {
+		return nil, err
+	}
+	rollbackTx := &model.RollbackTx{}
+	rollbacks, err := rollbackTx.GetBlockRollbackTransactions(nil, b.ID)
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err, "block_id": b.ID}).Error("getting rollback transactions of block")
+		return nil, err
+	}
+	fuel, err := model.GetLogTransactionsFuel(b.ID)
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err, "block_id": b.ID}).Error("getting fuel of block transactions")
+		return nil, err
+	}
+	result := &explorerBlock{
+		ID:            b.ID,
+		Hash:          hex.EncodeToString(b.Hash),
+		RollbacksHash: hex.EncodeToString(b.RollbacksHash),
+		EcosystemID:   b.EcosystemID,
+		KeyID:         b.KeyID,
+		NodePosition:  b.NodePosition,
+		Time:          b.Time,
+		Version:       blk.Header.Version,
+		TxCount:       b.Tx,
+		Transactions:  make([]explorerTx, 0, len(blk.Transactions)),
+	}
+	for _, t := range blk.Transactions {
+		if len(hashes) > 0 && !hashes[string(t.TxHash)] {
+			continue
+		}
+		item, err := explorerTransaction(b.ID, t, rollbacks, fuel[string(t.TxHash)], access)
+		if err != nil {
+			return nil, err
+		}
+		result.Transactions = append(result.Transactions, *item)
+	}
+	return result, nil
+}
+
+func getExplorerBlock(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) error {
+	blockID := converter.StrToInt64(data.ParamString(`id`))
+	b := &model.Block{}
+	found, err := b.Get(blockID)
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting block")
+		return errorAPI(w, err, http.StatusInternalServerError)
+	}
+	if !found {
+		logger.WithFields(log.Fields{"type": consts.NotFound, "id": blockID}).Error("block with id not found")
+		return errorAPI(w, `E_NOTFOUND`, http.StatusNotFound)
+	}
+	info, err := explorerBlockInfo(b, nil, newExplorerAccess(data))
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.UnmarshallingError, "error": err, "id": blockID}).Error("decoding block")
+		return errorAPI(w, err, http.StatusInternalServerError)
+	}
+	data.result = info
+	return nil
+}
+
+func getExplorerBlocks(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) error {
+	from := data.ParamInt64(`from_block`)
+	count := data.ParamInt64(`count`)
+	if count <= 0 {
+		count = 1
+	} else if count > explorerMaxBlocks {
+		count = explorerMaxBlocks
+	}
+	if from < 1 {
+		from = 1
+	}
+	blocks, err := model.GetBlockchain(from-1, from+count-1)
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting blocks")
+		return errorAPI(w, err, http.StatusInternalServerError)
+	}
+	result := &explorerBlocksResult{List: make([]explorerBlock, 0, len(blocks))}
+	access := newExplorerAccess(data)
+	for i := range blocks {
+		info, err := explorerBlockInfo(&blocks[i], nil, access)
+		if err != nil {
+			logger.WithFields(log.Fields{"type": consts.UnmarshallingError, "error": err, "id": blocks[i].ID}).Error("decoding block")
+			return errorAPI(w, err, http.StatusInternalServerError)
+		}
+		result.List = append(result.List, *info)
+	}
+	data.result = result
+	return nil
+}
+
+// explorerLogTxs returns the decoded transactions, the blocks are decoded once
+func explorerLogTxs(ltxs []model.LogTransaction, access *explorerAccess) ([]explorerTx, error) {
+	hashes := make(map[int64]map[string]bool)
+	for _, ltx := range ltxs {
+		if hashes[ltx.BlockID] == nil {
+			hashes[ltx.BlockID] = make(map[string]bool)
+		}
+		hashes[ltx.BlockID][string(ltx.Hash)] = true
+	}
+	txs := make(map[string]explorerTx)
+	for blockID, list := range hashes {
+		b := &model.Block{}
+		found, err := b.Get(blockID)
+		if err != nil {
+			log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting block")
+			return nil, err
+		}
+		if !found {
+			continue
+		}
+		info, err := explorerBlockInfo(b, list, access)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range info.Transactions {
+			txs[item.Hash] = item
+		}
+	}
+	result := make([]explorerTx, 0, len(ltxs))
+	for _, ltx := range ltxs {
+		if item, ok := txs[hex.EncodeToString(ltx.Hash)]; ok {
+			result = append(result, item)
+		}
+	}
+	return result, nil
+}
+
+func getExplorerTx(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) error {
+	hash, err := hex.DecodeString(data.ParamString(`hash`))
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.ConversionError, "error": err}).Error("decoding tx hash from hex")
+		return errorAPI(w, `E_HASHWRONG`, http.StatusBadRequest)
+	}
+	ltx := &model.LogTransaction{}
+	found, err := ltx.GetByHash(hash)
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting log transaction by hash")
+		return errorAPI(w, err, http.StatusInternalServerError)
+	}
+	if !found || ltx.BlockID == 0 {
+		return errorAPI(w, `E_HASHNOTFOUND`, http.StatusNotFound)
+	}
+	txs, err := explorerLogTxs([]model.LogTransaction{*ltx}, newExplorerAccess(data))
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.UnmarshallingError, "error": err}).Error("decoding transaction")
+		return errorAPI(w, err, http.StatusInternalServerError)
+	}
+	if len(txs) == 0 {
+		return errorAPI(w, `E_HASHNOTFOUND`, http.StatusNotFound)
+	}
+	data.result = &txs[0]
+	return nil
+}
+
+func getExplorerKeyTxs(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) error {
+	keyID := converter.StrToInt64(data.ParamString(`key_id`))
+	limit := int(data.ParamInt64(`limit`))
+	if limit <= 0 {
+		limit = 25
+	} else if limit > explorerMaxTxs {
+		limit = explorerMaxTxs
+	}
+	ltxs, err := model.GetLogTransactionsByKey(keyID, int(data.ParamInt64(`offset`)), limit)
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting log transactions by key")
+		return errorAPI(w, err, http.StatusInternalServerError)
+	}
+	txs, err := explorerLogTxs(ltxs, newExplorerAccess(data))
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.UnmarshallingError, "error": err}).Error("decoding transactions")
+		return errorAPI(w, err, http.StatusInternalServerError)
+	}
+	data.result = &explorerTxsResult{List: txs}
+	return nil
+}
@@ -0,0 +1,36 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"testing"
+
+	"github.com/AplaProject/go-apla/packages/transaction"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExplorerTransactionParams(t *testing.T) {
+	tx := &transaction.Transaction{TxHash: []byte{1, 2}, TxKeyID: 5, TxType: 1,
+		TxData: map[string]interface{}{`Name`: `test`, `Data`: []byte{0xab}, `forsign`: `5,test`}}
+	item, err := explorerTransaction(10, tx, nil, 120, &explorerAccess{})
+	require.NoError(t, err)
+	require.Equal(t, `0102`, item.Hash)
+	require.Equal(t, int64(120), item.Fuel)
+	require.Equal(t, `0`, item.Commission)
+	require.Equal(t, map[string]interface{}{`Name`: `test`, `Data`: `ab`}, item.Params)
+}
@@ -66,6 +66,9 @@ func getContract(w http.ResponseWriter, r *http.Request, data *apiData, logger *
 	if info.Tx != nil {
 		for _, fitem := range *info.Tx {
 			field := contractField{Name: fitem.Name, Type: fitem.Type.String(), Tags: fitem.Tags}
+			if name := script.RecordName(fitem.Type); len(name) > 0 {
+				field.Type = name
+			}
 
 			if strings.Contains(fitem.Tags, `hidden`) || strings.Contains(fitem.Tags, `signature`) {
 				field.HTML = `hidden`
@@ -0,0 +1,586 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/AplaProject/go-apla/packages/consts"
+	"github.com/AplaProject/go-apla/packages/graphql"
+	"github.com/AplaProject/go-apla/packages/model"
+	"github.com/AplaProject/go-apla/packages/smart"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// The schema of GraphQL contains the field of Query type for every table of the ecosystem.
+// The field returns the list of rows and has the arguments id, where, order, limit, offset
+// and after_id which are the same as the parameters of list/ request.
+//
+//  {
+//    keys(where: {amount: {$gt: "0"}}, limit: 10) {
+//      id amount
+//      member { member_name }
+//      sent(limit: 5) { recipient_id amount }
+//    }
+//  }
+
+const (
+	graphqlDefaultLimit = 25
+	graphqlMaxLimit     = 250
+	graphqlMaxDepth     = 4    // the maximum nesting of the tables in the query
+	graphqlMaxQueries   = 50   // the maximum number of selects for one request
+	graphqlMaxRows      = 1000 // the maximum number of selected rows for one request
+)
+
+var (
+	errGraphqlQueries = fmt.Errorf(`Query is too complex, the limit of selects is %d`, graphqlMaxQueries)
+	errGraphqlRows    = fmt.Errorf(`Query is too big, the limit of rows is %d`, graphqlMaxRows)
+)
+
+type graphqlError struct {
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path,omitempty"`
+}
+
+type graphqlResult struct {
+	Data   *graphql.Object `json:"data,omitempty"`
+	Errors []graphqlError  `json:"errors,omitempty"`
+}
+
+type graphqlSchemaResult struct {
+	Schema string `json:"schema"`
+}
+
+// gqlTable is the table of the ecosystem which can be queried
+type gqlTable struct {
+	name    string // the name without the prefix of the ecosystem
+	columns []string
+	types   map[string]string // GraphQL types of the columns
+}
+
+// gqlRelation links the row with the rows of other table
+type gqlRelation struct {
+	table  string
+	column string // the column of the row
+	ref    string // the column of the linked table
+	single bool
+}
+
+var (
+	regGraphqlName = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+	gqlRelations = map[string]map[string]gqlRelation{
+		`keys`: {
+			`member`:   {table: `members`, column: `id`, ref: `id`, single: true},
+			`sent`:     {table: `history`, column: `id`, ref: `sender_id`},
+			`received`: {table: `history`, column: `id`, ref: `recipient_id`},
+			`binaries`: {table: `binaries`, column: `id`, ref: `member_id`},
+		},
+		`members`: {
+			`key`:      {table: `keys`, column: `id`, ref: `id`, single: true},
+			`sent`:     {table: `history`, column: `id`, ref: `sender_id`},
+			`received`: {table: `history`, column: `id`, ref: `recipient_id`},
+			`binaries`: {table: `binaries`, column: `id`, ref: `member_id`},
+		},
+		`history`: {
+			`sender`:    {table: `keys`, column: `sender_id`, ref: `id`, single: true},
+			`recipient`: {table: `keys`, column: `recipient_id`, ref: `id`, single: true},
+		},
+		`binaries`: {
+			`member`: {table: `members`, column: `member_id`, ref: `id`, single: true},
+		},
+	}
+
+	gqlListArgs = []string{`id: String`, `where: JSON`, `order: String`, `limit: Int`, `offset: Int`,
+		`after_id: String`}
+)
+
+// gqlType returns GraphQL type of the column. The numbers are returned as strings like
+// in the other requests because bigint and decimal values don't fit in Int.
+func gqlType(dataType string) string {
+	switch {
+	case dataType == `integer` || dataType == `smallint`:
+		return `Int`
+	case strings.HasPrefix(dataType, `double`) || dataType == `real`:
+		return `Float`
+	case dataType == `boolean`:
+		return `Boolean`
+	case strings.HasPrefix(dataType, `json`):
+		return `JSON`
+	case dataType == `bytea`:
+		return `Bytes`
+	}
+	return `String`
+}
+
+// gqlTypeName returns the name of the object type for the table
+func gqlTypeName(table string) string {
+	var name string
+	for _, part := range strings.Split(table, `_`) {
+		if len(part) > 0 {
+			name += strings.ToUpper(part[:1]) + part[1:]
+		}
+	}
+	switch name {
+	case ``, `Query`, `JSON`, `Bytes`:
+		name += `Table`
+	}
+	return name
+}
+
+type gqlResolver struct {
+	sc     *smart.SmartContract
+	prefix string
+	tables map[string]*gqlTable
+	errors []graphqlError
+	logger *log.Entry
+	// fetch checks the access to the table and the columns and selects the rows
+	fetch    func(table string, columns []string, query string, limit int) ([]map[string]string, error)
+	queries  int  // the number of executed selects
+	rows     int  // the number of selected rows
+	exceeded bool // the limit of selects or rows is exceeded, the rest fields aren't resolved
+}
+
+func (r *gqlResolver) addError(path []interface{}, err error) {
+	r.errors = append(r.errors, graphqlError{Message: err.Error(),
+		Path: append([]interface{}{}, path...)})
+}
+
+// table returns the description of the table or nil if the table doesn't exist
+func (r *gqlResolver) table(name string) (*gqlTable, error) {
+	if tbl, ok := r.tables[name]; ok {
+		return tbl, nil
+	}
+	if !regGraphqlName.MatchString(name) {
+		return nil, nil
+	}
+	tables := &model.Table{}
+	tables.SetTablePrefix(r.prefix)
+	found, err := tables.Get(nil, name)
+	if err != nil {
+		r.logger.WithFields(log.Fields{"type": consts.DBError, "error": err, "table": name}).Error("getting table")
+		return nil, err
+	}
+	if !found {
+		return nil, nil
+	}
+	rows, err := model.GetAllColumnTypes(r.prefix + `_` + name)
+	if err != nil {
+		r.logger.WithFields(log.Fields{"type": consts.DBError, "error": err, "table": name}).Error("getting column types")
+		return nil, err
+	}
+	tbl := &gqlTable{name: name, types: make(map[string]string)}
+	for _, row := range rows {
+		tbl.columns = append(tbl.columns, row[`column_name`])
+		tbl.types[row[`column_name`]] = gqlType(row[`data_type`])
+	}
+	r.tables[name] = tbl
+	return tbl, nil
+}
+
+// gqlDepth returns the nesting of the tables in the selection
+func gqlDepth(fields []*graphql.Field) int {
+	var depth int
+	for _, field := range fields {
+		if len(field.Fields) == 0 {
+			continue
+		}
+		if sub := gqlDepth(field.Fields) + 1; sub > depth {
+			depth = sub
+		}
+	}
+	return depth
+}
+
+func (r *gqlResolver) resolveQuery(fields []*graphql.Field) *graphql.Object {
+	result := &graphql.Object{}
+	for _, field := range fields {
+		path := []interface{}{field.Key()}
+		if field.Name == `__typename` {
+			result.Set(field.Key(), `Query`)
+			continue
+		}
+		tbl, err := r.table(field.Name)
+		if err == nil && tbl == nil {
+			err = fmt.Errorf(`Cannot query field %s on type Query`, field.Name)
+		}
+		if depth := gqlDepth([]*graphql.Field{field}); err == nil && depth > graphqlMaxDepth {
+			err = fmt.Errorf(`Query depth %d exceeds the limit %d`, depth, graphqlMaxDepth)
+		}
+		if err != nil {
+			r.addError(path, err)
+			result.Set(field.Key(), nil)
+			continue
+		}
+		result.Set(field.Key(), r.resolveRows(path, field, tbl, ``, false))
+	}
+	return result
+}
+
+// resolveRows returns the list of rows of the table or one row if single is true. The cond
+// parameter is the additional condition of the nested field.
+func (r *gqlResolver) resolveRows(path []interface{}, field *graphql.Field, tbl *gqlTable, cond string,
+	single bool) interface{} {
+	if r.exceeded {
+		return nil
+	}
+	rows, err := r.selectRows(field, tbl, cond, single)
+	if err != nil {
+		r.addError(path, err)
+		return nil
+	}
+	list := make([]*graphql.Object, len(rows))
+	for i, row := range rows {
+		item := &graphql.Object{}
+		for _, sub := range field.Fields {
+			if sub.Name == `__typename` {
+				item.Set(sub.Key(), gqlTypeName(tbl.name))
+				continue
+			}
+			if ftype, ok := tbl.types[sub.Name]; ok {
+				item.Set(sub.Key(), gqlValue(ftype, row[sub.Name]))
+				continue
+			}
+			rel := gqlRelations[tbl.name][sub.Name]
+			subPath := append(append([]interface{}{}, path...), sub.Key())
+			if !single {
+				subPath = append(append([]interface{}{}, path...), i, sub.Key())
+			}
+			relTable, err := r.table(rel.table)
+			if err == nil && relTable == nil {
+				err = fmt.Errorf(apiErrors[`E_TABLENOTFOUND`], rel.table)
+			}
+			if err != nil {
+				r.addError(subPath, err)
+				item.Set(sub.Key(), nil)
+				continue
+			}
+			item.Set(sub.Key(), r.resolveRows(subPath, sub, relTable,
+				fmt.Sprintf(`"%s" = '%s'`, rel.ref, strings.Replace(row[rel.column], `'`, `''`, -1)), rel.single))
+		}
+		list[i] = item
+	}
+	if single {
+		if len(list) == 0 {
+			return nil
+		}
+		return list[0]
+	}
+	return list
+}
+
+func (r *gqlResolver) selectRows(field *graphql.Field, tbl *gqlTable, cond string,
+	single bool) ([]map[string]string, error) {
+	typeName := gqlTypeName(tbl.name)
+	if len(field.Fields) == 0 {
+		return nil, fmt.Errorf(`Field %s of type %s must have a selection of subfields`, field.Name, typeName)
+	}
+	columns := make([]string, 0, len(field.Fields))
+	addColumn := func(name string) {
+		for _, col := range columns {
+			if col == name {
+				return
+			}
+		}
+		columns = append(columns, name)
+	}
+	for _, sub := range field.Fields {
+		if sub.Name == `__typename` {
+			continue
+		}
+		if _, ok := tbl.types[sub.Name]; ok {
+			if len(sub.Fields) > 0 {
+				return nil, fmt.Errorf(`Field %s must not have a selection`, sub.Name)
+			}
+			addColumn(sub.Name)
+		} else if rel, ok := gqlRelations[tbl.name][sub.Name]; ok {
+			addColumn(rel.column)
+		} else {
+			return nil, fmt.Errorf(`Cannot query field %s on type %s`, sub.Name, typeName)
+		}
+	}
+	if len(columns) == 0 {
+		addColumn(`id`)
+	}
+	var (
+		conds  []string
+		order  []orderColumn
+		limit  = int64(graphqlDefaultLimit)
+		offset int64
+		err    error
+	)
+	if len(cond) > 0 {
+		conds = append(conds, cond)
+	}
+	checkColumns := append([]string{}, columns...)
+	for name, value := range field.Args {
+		if single && value != nil {
+			return nil, fmt.Errorf(`Unknown argument %s on field %s`, name, field.Name)
+		}
+		switch name {
+		case `id`:
+			if value != nil {
+				conds = append(conds, fmt.Sprintf(`id = '%d'`, gqlInt(value)))
+			}
+		case `where`:
+			var where string
+			switch v := value.(type) {
+			case nil:
+				continue
+			case string:
+				where = v
+			default:
+				out, err := json.Marshal(v)
+				if err != nil {
+					return nil, err
+				}
+				where = string(out)
+			}
+			where, whereColumns, err := smart.GetWhere(where)
+			if err != nil {
+				return nil, err
+			}
+			conds = append(conds, `(`+where+`)`)
+			checkColumns = append(checkColumns, whereColumns...)
+		case `order`:
+			if order, err = parseOrder(fmt.Sprint(value)); err != nil {
+				return nil, err
+			}
+		case `limit`:
+			if value != nil {
+				limit = gqlInt(value)
+			}
+		case `offset`:
+			offset = gqlInt(value)
+		case `after_id`:
+		default:
+			return nil, fmt.Errorf(`Unknown argument %s on field %s`, name, field.Name)
+		}
+	}
+	table := `"` + r.prefix + `_` + tbl.name + `"`
+	if order == nil {
+		order, _ = parseOrder(``)
+	}
+	for _, col := range order {
+		checkColumns = append(checkColumns, col.name)
+	}
+	if afterID := gqlInt(field.Args[`after_id`]); afterID > 0 {
+		conds = append(conds, afterSQL(table, order, afterID))
+	}
+	if limit <= 0 || limit > graphqlMaxLimit {
+		limit = graphqlMaxLimit
+	}
+	if single {
+		limit = 1
+	}
+	if r.queries >= graphqlMaxQueries {
+		r.exceeded = true
+		return nil, errGraphqlQueries
+	}
+	// one more row is selected to find out that the limit of rows is exceeded
+	if rest := int64(graphqlMaxRows - r.rows); limit > rest {
+		limit = rest + 1
+	}
+	query := `select "` + strings.Join(columns, `","`) + `" from ` + table
+	if len(conds) > 0 {
+		query += ` where ` + strings.Join(conds, ` and `)
+	}
+	query += orderSQL(order) + fmt.Sprintf(` offset %d`, offset)
+	rows, err := r.fetch(strings.Trim(table, `"`), checkColumns, query, int(limit))
+	if err != nil {
+		return nil, err
+	}
+	r.queries++
+	if r.rows += len(rows); r.rows > graphqlMaxRows {
+		r.exceeded = true
+		return nil, errGraphqlRows
+	}
+	return rows, nil
+}
+
+func (r *gqlResolver) fetchRows(table string, columns []string, query string, limit int) ([]map[string]string, error) {
+	if _, err := r.sc.AccessTablePerm(table, `read`); err != nil {
+		return nil, err
+	}
+	if err := r.sc.AccessWhereColumns(table, columns); err != nil {
+		return nil, err
+	}
+	rows, err := model.GetAll(query, limit)
+	if err != nil {
+		r.logger.WithFields(log.Fields{"type": consts.DBError, "error": err, "query": query}).Error("selecting rows")
+		return nil, errors.New(apiErrors[`E_QUERY`])
+	}
+	return rows, nil
+}
+
+// gqlValue converts the value of the column to the value of the result
+func gqlValue(ftype, value string) interface{} {
+	if value == `NULL` {
+		return nil
+	}
+	switch ftype {
+	case `Int`:
+		if val, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return val
+		}
+	case `Float`:
+		if val, err := strconv.ParseFloat(value, 64); err == nil {
+			return val
+		}
+	case `Boolean`:
+		if val, err := strconv.ParseBool(value); err == nil {
+			return val
+		}
+	case `JSON`:
+		if json.Valid([]byte(value)) {
+			return json.RawMessage(value)
+		}
+	case `Bytes`:
+		return hex.EncodeToString([]byte(value))
+	}
+	return value
+}
+
+func gqlInt(value interface{}) int64 {
+	switch v := value.(type) {
+	case int64:
+		return v
+	case float64:
+		return int64(v)
+	case json.Number:
+		val, _ := v.Int64()
+		return val
+	case string:
+		val, _ := strconv.ParseInt(v, 10, 64)
+		return val
+	}
+	return 0
+}
+
+// gqlSchema returns the schema of the ecosystem in GraphQL schema language
+func gqlSchema(r *gqlResolver) (string, error) {
+	tables := &model.Table{}
+	list, err := tables.GetAll(r.prefix)
+	if err != nil {
+		r.logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting tables")
+		return ``, err
+	}
+	names := make([]string, 0, len(list))
+	for _, item := range list {
+		if regGraphqlName.MatchString(item.Name) {
+			names = append(names, item.Name)
+		}
+	}
+	sort.Strings(names)
+	var (
+		query bytes.Buffer
+		types bytes.Buffer
+	)
+	listArgs := `(` + strings.Join(gqlListArgs, `, `) + `)`
+	query.WriteString("scalar JSON\n\nscalar Bytes\n\ntype Query {\n")
+	for _, name := range names {
+		tbl, err := r.table(name)
+		if err != nil {
+			return ``, err
+		}
+		if tbl == nil {
+			continue
+		}
+		typeName := gqlTypeName(name)
+		query.WriteString(fmt.Sprintf("  %s%s: [%s]\n", name, listArgs, typeName))
+		types.WriteString(fmt.Sprintf("\ntype %s {\n", typeName))
+		for _, col := range tbl.columns {
+			types.WriteString(fmt.Sprintf("  %s: %s\n", col, tbl.types[col]))
+		}
+		rels := make([]string, 0, len(gqlRelations[name]))
+		for rel := range gqlRelations[name] {
+			rels = append(rels, rel)
+		}
+		sort.Strings(rels)
+		for _, relName := range rels {
+			rel := gqlRelations[name][relName]
+			if rel.single {
+				types.WriteString(fmt.Sprintf("  %s: %s\n", relName, gqlTypeName(rel.table)))
+			} else {
+				types.WriteString(fmt.Sprintf("  %s%s: [%s]\n", relName, listArgs, gqlTypeName(rel.table)))
+			}
+		}
+		types.WriteString("}\n")
+	}
+	query.WriteString("}\n")
+	return query.String() + types.String(), nil
+}
+
+func newGqlResolver(data *apiData, logger *log.Entry) *gqlResolver {
+	r := &gqlResolver{sc: getSmartContract(data), prefix: getPrefix(data),
+		tables: make(map[string]*gqlTable), logger: logger}
+	r.fetch = r.fetchRows
+	return r
+}
+
+func graphQL(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) error {
+	var body struct {
+		Query         string                 `json:"query"`
+		Variables     map[string]interface{} `json:"variables"`
+		OperationName string                 `json:"operationName"`
+	}
+	body.Query = data.ParamString(`query`)
+	body.OperationName = data.ParamString(`operationName`)
+	if vars := data.ParamString(`variables`); len(vars) > 0 {
+		if err := json.Unmarshal([]byte(vars), &body.Variables); err != nil {
+			logger.WithFields(log.Fields{"type": consts.JSONUnmarshallError, "error": err}).Error("unmarshalling variables")
+			return errorAPI(w, err, http.StatusBadRequest)
+		}
+	}
+	if len(body.Query) == 0 && strings.HasPrefix(r.Header.Get(`Content-Type`), `application/json`) {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			logger.WithFields(log.Fields{"type": consts.JSONUnmarshallError, "error": err}).Error("unmarshalling graphql request")
+			return errorAPI(w, err, http.StatusBadRequest)
+		}
+	}
+	if len(body.Query) == 0 {
+		return errorAPI(w, `E_UNDEFINEVAL`, http.StatusBadRequest, `query`)
+	}
+	query, err := graphql.Parse(body.Query, body.Variables, body.OperationName)
+	if err != nil {
+		data.result = &graphqlResult{Errors: []graphqlError{{Message: err.Error()}}}
+		return nil
+	}
+	resolver := newGqlResolver(data, logger)
+	result := resolver.resolveQuery(query.Fields)
+	data.result = &graphqlResult{Data: result, Errors: resolver.errors}
+	return nil
+}
+
+func graphQLSchema(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) error {
+	schema, err := gqlSchema(newGqlResolver(data, logger))
+	if err != nil {
+		return errorAPI(w, `E_SERVER`, http.StatusInternalServerError)
+	}
+	data.result = &graphqlSchemaResult{Schema: schema}
+	return nil
+}
@@ -0,0 +1,117 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/AplaProject/go-apla/packages/graphql"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestGqlResolver returns the resolver which selects the rows with the sequential ids
+func newTestGqlResolver(queries *[]string) *gqlResolver {
+	r := &gqlResolver{prefix: `1`, logger: log.WithFields(log.Fields{}), tables: map[string]*gqlTable{
+		`keys`: {name: `keys`, columns: []string{`id`, `amount`},
+			types: map[string]string{`id`: `String`, `amount`: `String`}},
+		`members`: {name: `members`, columns: []string{`id`, `member_name`},
+			types: map[string]string{`id`: `String`, `member_name`: `String`}},
+		`history`: {name: `history`, columns: []string{`id`, `sender_id`, `amount`},
+			types: map[string]string{`id`: `String`, `sender_id`: `String`, `amount`: `String`}},
+	}}
+	r.fetch = func(table string, columns []string, query string, limit int) ([]map[string]string, error) {
+		*queries = append(*queries, query)
+		if limit > 3 {
+			limit = 3
+		}
+		rows := make([]map[string]string, limit)
+		for i := range rows {
+			id := fmt.Sprint(i + 1)
+			rows[i] = map[string]string{`id`: id, `amount`: id + `00`, `member_name`: `member` + id,
+				`sender_id`: id}
+		}
+		return rows, nil
+	}
+	return r
+}
+
+func resolveTestQuery(t *testing.T, r *gqlResolver, input string) string {
+	query, err := graphql.Parse(input, nil, ``)
+	require.NoError(t, err)
+	out, err := json.Marshal(r.resolveQuery(query.Fields))
+	require.NoError(t, err)
+	return string(out)
+}
+
+func TestGraphqlNested(t *testing.T) {
+	var queries []string
+	r := newTestGqlResolver(&queries)
+	out := resolveTestQuery(t, r, `{ keys(limit: 2) { id member { member_name } sent(limit: 1) { amount } } }`)
+	require.Empty(t, r.errors)
+	require.Equal(t, `{"keys":[{"id":"1","member":{"member_name":"member1"},"sent":[{"amount":"100"}]},`+
+		`{"id":"2","member":{"member_name":"member1"},"sent":[{"amount":"100"}]}]}`, out)
+	require.Len(t, queries, 5)
+	require.True(t, strings.HasPrefix(queries[1], `select "member_name" from "1_members" where "id" = '1'`), queries[1])
+	require.True(t, strings.HasPrefix(queries[2], `select "amount" from "1_history" where "sender_id" = '1'`), queries[2])
+	require.Equal(t, 5, r.queries)
+	require.Equal(t, 6, r.rows)
+}
+
+func TestGraphqlDepth(t *testing.T) {
+	var queries []string
+	r := newTestGqlResolver(&queries)
+	resolveTestQuery(t, r, `{ keys { member { key { member { key { id } } } } } }`)
+	require.Len(t, r.errors, 1)
+	require.Equal(t, fmt.Sprintf(`Query depth 5 exceeds the limit %d`, graphqlMaxDepth), r.errors[0].Message)
+	require.Empty(t, queries)
+
+	r = newTestGqlResolver(&queries)
+	resolveTestQuery(t, r, `{ keys { member { key { member { id } } } } }`)
+	require.Empty(t, r.errors)
+}
+
+func TestGraphqlLimits(t *testing.T) {
+	var queries []string
+	r := newTestGqlResolver(&queries)
+	r.fetch = func(table string, columns []string, query string, limit int) ([]map[string]string, error) {
+		queries = append(queries, query)
+		rows := make([]map[string]string, limit)
+		for i := range rows {
+			rows[i] = map[string]string{`id`: fmt.Sprint(i + 1)}
+		}
+		return rows, nil
+	}
+	resolveTestQuery(t, r, `{ keys(limit: 100) { id sent(limit: 1) { id } } }`)
+	require.Len(t, r.errors, 1)
+	require.Equal(t, errGraphqlQueries.Error(), r.errors[0].Message)
+	require.Len(t, queries, graphqlMaxQueries)
+
+	queries = queries[:0]
+	r.errors, r.queries, r.rows, r.exceeded = nil, 0, 0, false
+	out := resolveTestQuery(t, r, `{ a: keys(limit: 250) { id } b: keys(limit: 250) { id }
+		c: keys(limit: 250) { id } d: keys(limit: 250) { id } e: keys(limit: 250) { id } }`)
+	require.Len(t, r.errors, 1)
+	require.Equal(t, errGraphqlRows.Error(), r.errors[0].Message)
+	require.Equal(t, []interface{}{`e`}, r.errors[0].Path)
+	require.Len(t, queries, 5)
+	require.True(t, strings.HasSuffix(out, `"e":null}`))
+}
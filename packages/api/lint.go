@@ -0,0 +1,41 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/AplaProject/go-apla/packages/consts"
+	"github.com/AplaProject/go-apla/packages/script"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type lintResult struct {
+	Diagnostics []script.Diagnostic `json:"diagnostics"`
+}
+
+func lintContract(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) error {
+	diags, err := data.vm.Analyze([]rune(data.ParamString(`source`)),
+		&script.OwnerInfo{StateID: uint32(data.ecosystemId)})
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.ParseError, "error": err}).Error("analyzing contract source")
+		return errorAPI(w, err, http.StatusBadRequest)
+	}
+	data.result = &lintResult{Diagnostics: diags}
+	return nil
+}
@@ -19,11 +19,13 @@ package api
 import (
 	"fmt"
 	"net/http"
+	"regexp"
 	"strings"
 
 	"github.com/AplaProject/go-apla/packages/consts"
 	"github.com/AplaProject/go-apla/packages/converter"
 	"github.com/AplaProject/go-apla/packages/model"
+	"github.com/AplaProject/go-apla/packages/smart"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -33,19 +35,138 @@ type listResult struct {
 	List  []map[string]string `json:"list"`
 }
 
+type orderColumn struct {
+	name string
+	desc bool
+}
+
+var regOrderColumn = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// parseOrder parses the list of columns like `name, amount desc`. The rows are ordered by id
+// descending if the list is empty. Otherwise id is added at the end of the list so the order
+// of rows is always defined.
+func parseOrder(input string) ([]orderColumn, error) {
+	if len(strings.TrimSpace(input)) == 0 {
+		return []orderColumn{{name: `id`, desc: true}}, nil
+	}
+	var isID bool
+	order := make([]orderColumn, 0)
+	for _, item := range strings.Split(input, `,`) {
+		fields := strings.Fields(strings.ToLower(item))
+		if len(fields) == 0 || len(fields) > 2 || !regOrderColumn.MatchString(fields[0]) {
+			return nil, fmt.Errorf(`order %s is not valid`, item)
+		}
+		col := orderColumn{name: fields[0]}
+		if len(fields) == 2 {
+			switch fields[1] {
+			case `asc`:
+			case `desc`:
+				col.desc = true
+			default:
+				return nil, fmt.Errorf(`order %s is not valid`, item)
+			}
+		}
+		isID = isID || col.name == `id`
+		order = append(order, col)
+	}
+	if !isID {
+		order = append(order, orderColumn{name: `id`, desc: order[len(order)-1].desc})
+	}
+	return order, nil
+}
+
+// orderSQL returns the order by clause. NULL values are the last in any direction, afterSQL relies on it.
+func orderSQL(order []orderColumn) string {
+	list := make([]string, len(order))
+	for i, col := range order {
+		list[i] = `"` + col.name + `"`
+		if col.desc {
+			list[i] += ` desc`
+		}
+		list[i] += ` nulls last`
+	}
+	return ` order by ` + strings.Join(list, `,`)
+}
+
+// afterSQL returns the condition selecting the rows which follow the row with afterID in the specified
+// order. The values of the row are compared column by column so the index of the columns can be used.
+// NULL values follow all other values of the column and are equal to each other.
+func afterSQL(table string, order []orderColumn, afterID int64) string {
+	conds := make([]string, len(order))
+	for i, col := range order {
+		parts := make([]string, 0, i+1)
+		for _, prev := range order[:i] {
+			value := fmt.Sprintf(`(select "%s" from %s where id = %d)`, prev.name, table, afterID)
+			parts = append(parts, fmt.Sprintf(`("%s" = %s or "%[1]s" is null and %[2]s is null)`,
+				prev.name, value))
+		}
+		oper := `>`
+		if col.desc {
+			oper = `<`
+		}
+		value := fmt.Sprintf(`(select "%s" from %s where id = %d)`, col.name, table, afterID)
+		parts = append(parts, fmt.Sprintf(`("%s" %s %s or "%[1]s" is null and %[3]s is not null)`,
+			col.name, oper, value))
+		conds[i] = `(` + strings.Join(parts, ` and `) + `)`
+	}
+	return `(` + strings.Join(conds, ` or `) + `)`
+}
+
 func list(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) (err error) {
-	var limit int
+	var (
+		limit        int
+		where        string
+		whereColumns []string
+	)
 
 	table := converter.EscapeName(getPrefix(data) + `_` + data.params[`name`].(string))
 	cols := `*`
 	if len(data.params[`columns`].(string)) > 0 {
 		cols = `id,` + converter.EscapeName(data.params[`columns`].(string))
 	}
+	if !model.IsTable(strings.Trim(table, `"`)) {
+		logger.WithFields(log.Fields{"type": consts.NotFound, "table": table}).Error("Getting table records count")
+		return errorAPI(w, `E_TABLENOTFOUND`, http.StatusBadRequest, data.params[`name`].(string))
+	}
+
+	if len(data.params[`where`].(string)) > 0 {
+		if where, whereColumns, err = smart.GetWhere(data.params[`where`].(string)); err != nil {
+			return errorAPI(w, `E_WHERE`, http.StatusBadRequest, data.params[`where`].(string))
+		}
+	}
+	order, err := parseOrder(data.params[`order`].(string))
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.InvalidObject, "error": err}).Error("parsing order")
+		return errorAPI(w, err, http.StatusBadRequest)
+	}
+	for _, col := range order {
+		whereColumns = append(whereColumns, col.name)
+	}
+	if err = getSmartContract(data).AccessWhereColumns(strings.Trim(table, `"`), whereColumns); err != nil {
+		return errorAPI(w, `E_PERMISSION`, http.StatusUnauthorized)
+	}
 
-	count, err := model.GetRecordsCountTx(nil, strings.Trim(table, `"`))
+	from, err := asOfBlockTable(table, data)
+	if err == model.ErrAsOfBlockDepth {
+		return errorAPI(w, err, http.StatusBadRequest)
+	}
 	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err, "table": table}).Error("getting table as of block")
+		return errorAPI(w, `E_QUERY`, http.StatusInternalServerError)
+	}
+	countFrom := from
+	if from == table {
+		// gorm quotes the plain table name itself
+		countFrom = strings.Trim(table, `"`)
+	}
+	query := model.GetDB(data.tx).Table(countFrom)
+	if len(where) > 0 {
+		query = query.Where(where)
+	}
+	var count int64
+	if err = query.Count(&count).Error; err != nil {
 		logger.WithFields(log.Fields{"type": consts.DBError, "error": err, "table": table}).Error("Getting table records count")
-		return errorAPI(w, `E_TABLENOTFOUND`, http.StatusBadRequest, data.params[`name`].(string))
+		return errorAPI(w, `E_QUERY`, http.StatusBadRequest)
 	}
 
 	if data.params[`limit`].(int64) > 0 {
@@ -53,7 +174,27 @@ func list(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Ent
 	} else {
 		limit = 25
 	}
-	list, err := model.GetAll(`select `+cols+` from `+table+` order by id desc`+
+	var conds []string
+	if len(where) > 0 {
+		conds = append(conds, `(`+where+`)`)
+	}
+	if afterID := data.params[`after_id`].(int64); afterID > 0 {
+		row, err := model.GetOneRowTransaction(data.tx, `select id from `+from+` where id = ?`, afterID).String()
+		if err != nil {
+			logger.WithFields(log.Fields{"type": consts.DBError, "error": err, "table": table}).Error("Getting after_id row")
+			return errorAPI(w, `E_QUERY`, http.StatusInternalServerError)
+		}
+		if len(row) == 0 {
+			logger.WithFields(log.Fields{"type": consts.NotFound, "table": table, "id": afterID}).Error("after_id row not found")
+			return errorAPI(w, `E_NOTFOUND`, http.StatusNotFound)
+		}
+		conds = append(conds, afterSQL(from, order, afterID))
+	}
+	var sqlWhere string
+	if len(conds) > 0 {
+		sqlWhere = ` where ` + strings.Join(conds, ` and `)
+	}
+	list, err := model.GetAllTx(data.tx, `select `+cols+` from `+from+sqlWhere+orderSQL(order)+
 		fmt.Sprintf(` offset %d `, data.params[`offset`].(int64)), limit)
 	if err != nil {
 		logger.WithFields(log.Fields{"type": consts.DBError, "error": err, "table": table}).Error("Getting rows from table")
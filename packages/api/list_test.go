@@ -18,6 +18,7 @@ package api
 
 import (
 	"fmt"
+	"reflect"
 	"testing"
 
 	"github.com/AplaProject/go-apla/packages/converter"
@@ -43,6 +44,11 @@ func TestList(t *testing.T) {
 		t.Error(err)
 		return
 	}
+	err = sendGet(`list/contracts?after_id=999999999`, nil, &ret)
+	if err == nil || err.Error() != `404 {"error": "E_NOTFOUND", "msg": "Page not found" }` {
+		t.Error(err)
+		return
+	}
 	var retTable tableResult
 	for _, item := range []string{`app_params`, `parameters`} {
 		err = sendGet(`table/`+item, nil, &retTable)
@@ -56,3 +62,43 @@ func TestList(t *testing.T) {
 		}
 	}
 }
+
+func TestParseOrder(t *testing.T) {
+	for input, want := range map[string][]orderColumn{
+		``:                  {{name: `id`, desc: true}},
+		`name`:              {{name: `name`}, {name: `id`}},
+		`Name DESC, amount`: {{name: `name`, desc: true}, {name: `amount`}, {name: `id`}},
+		`amount desc`:       {{name: `amount`, desc: true}, {name: `id`, desc: true}},
+		`id asc, name desc`: {{name: `id`}, {name: `name`, desc: true}},
+	} {
+		order, err := parseOrder(input)
+		if err != nil {
+			t.Errorf(`%s: %v`, input, err)
+			continue
+		}
+		if !reflect.DeepEqual(order, want) {
+			t.Errorf(`%s: %v != %v`, input, order, want)
+		}
+	}
+	for _, input := range []string{`name;drop`, `name up`, `name desc asc`, `,`, `"name"`} {
+		if _, err := parseOrder(input); err == nil {
+			t.Errorf(`%s must be invalid`, input)
+		}
+	}
+}
+
+func TestAfterSQL(t *testing.T) {
+	order := []orderColumn{{name: `amount`, desc: true}, {name: `id`, desc: true}}
+	if sql := orderSQL(order); sql != ` order by "amount" desc nulls last,"id" desc nulls last` {
+		t.Errorf(`wrong order %s`, sql)
+	}
+	want := `((("amount" < (select "amount" from "1_keys" where id = 5) or "amount" is null and ` +
+		`(select "amount" from "1_keys" where id = 5) is not null)) or ` +
+		`(("amount" = (select "amount" from "1_keys" where id = 5) or "amount" is null and ` +
+		`(select "amount" from "1_keys" where id = 5) is null) and ` +
+		`("id" < (select "id" from "1_keys" where id = 5) or "id" is null and ` +
+		`(select "id" from "1_keys" where id = 5) is not null)))`
+	if sql := afterSQL(`"1_keys"`, order, 5); sql != want {
+		t.Errorf("wrong condition\n%s\n%s", sql, want)
+	}
+}
@@ -0,0 +1,386 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"path"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/AplaProject/go-apla/packages/consts"
+	"github.com/AplaProject/go-apla/packages/model"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// routeInfo is the description of the api route which is used for the generating of OpenAPI document
+type routeInfo struct {
+	method  string
+	pattern string
+	params  map[string]int
+	auth    bool
+}
+
+// imageResult is the marker of the routes which return the image instead of JSON object
+type imageResult []byte
+
+// streamResult is the marker of the routes which send Server-Sent Events
+type streamResult struct {
+	event interface{}
+}
+
+type errorResult struct {
+	Error  string   `json:"error"`
+	Msg    string   `json:"msg"`
+	Params []string `json:"params,omitempty"`
+}
+
+var (
+	apiRoutes      []*routeInfo
+	regOperationID = regexp.MustCompile(`[^a-zA-Z0-9]+`)
+
+	// routeResults contains the values of the results of routes, the schemas of responses are got from their types
+	routeResults = map[string]interface{}{
+		`GET appparam/:appid/:name`:         paramValue{},
+		`GET appparams/:appid`:              appParamsResult{},
+		`GET avatar/:ecosystem/:member`:     imageResult{},
+		`GET balance/:wallet`:               balanceResult{},
+		`GET block/:id`:                     getBlockInfoResult{},
+		`GET config/:option`:                ``,
+		`GET contract/:name`:                getContractResult{},
+		`GET contracts`:                     listResult{},
+		`GET ecosystemname`:                 ecosystemNameResult{},
+		`GET ecosystemparam/:name`:          paramValue{},
+		`GET ecosystemparams`:               ecosystemParamsResult{},
+		`GET ecosystems`:                    ecosystemsResult{},
+		`GET events`:                        eventsResult{},
+		`GET explorer/block/:id`:            explorerBlock{},
+		`GET explorer/blocks`:               explorerBlocksResult{},
+		`GET explorer/key/:key_id`:          explorerTxsResult{},
+		`GET explorer/tx/:hash`:             explorerTx{},
+		`GET getuid`:                        getUIDResult{},
+		`GET graphql`:                       graphqlResult{},
+		`GET graphql/schema`:                graphqlSchemaResult{},
+		`GET history/:table/:id`:            historyResult{},
+		`GET interface/block/:name`:         model.BlockInterface{},
+		`GET interface/menu/:name`:          model.Menu{},
+		`GET interface/page/:name`:          model.Page{},
+		`GET list/:name`:                    listResult{},
+		`GET maxblockid`:                    getMaxBlockIDResult{},
+		`GET openapi.json`:                  map[string]interface{}{},
+		`GET row/:name/:id`:                 rowResult{},
+		`GET systemparams`:                  ecosystemParamsResult{},
+		`GET table/:name`:                   tableResult{},
+		`GET tables`:                        tablesResult{},
+		`GET test/:name`:                    getTestResult{},
+		`GET txstatus/:hash`:                txstatusResult{},
+		`GET txstatusMultiple`:              multiTxStatusResult{},
+		`GET txstream`:                      streamResult{txStreamEvent{}},
+		`GET version`:                       ``,
+		`GET webhooks/deadletters`:          webhookDeadLettersResult{},
+		`GET webhooks/deliveries`:           webhookDeliveriesResult{},
+		`POST batch`:                        batchResult{},
+		`POST content`:                      contentResult{},
+		`POST content/hash/:name`:           hashResult{},
+		`POST content/menu/:name`:           contentResult{},
+		`POST content/page/:name`:           contentResult{},
+		`POST content/source/:name`:         contentResult{},
+		`POST contract/:request_id`:         contractResult{},
+		`POST contractMultiple/:request_id`: contractMultiResult{},
+		`POST debug/:name`:                  debugResult{},
+		`POST debugcmd/:session`:            debugResult{},
+		`POST graphql`:                      graphqlResult{},
+		`POST lint`:                         lintResult{},
+		`POST login`:                        loginResult{},
+		`POST node/:name`:                   contractResult{},
+		`POST prepare/:name`:                prepareResult{},
+		`POST prepareMultiple`:              multiPrepareResult{},
+		`POST profile/:name`:                ProfileResult{},
+		`POST refresh`:                      refreshResult{},
+		`POST simulate/:name`:               simulateResult{},
+		`POST test/:name`:                   getTestResult{},
+		`POST txstatusMultiple`:             multiTxStatusResult{},
+		`POST updnotificator`:               updateNotificatorResult{},
+	}
+
+	openAPI struct {
+		once sync.Once
+		spec map[string]interface{}
+	}
+)
+
+func newRouteInfo(method, pattern string, params map[string]int, handlers []apiHandle) *routeInfo {
+	info := &routeInfo{method: method, pattern: pattern, params: params}
+	auth := reflect.ValueOf(authWallet).Pointer()
+	for _, handler := range handlers {
+		if reflect.ValueOf(handler).Pointer() == auth {
+			info.auth = true
+		}
+	}
+	return info
+}
+
+// openAPIGenerator builds OpenAPI document, the schemas of the named structures are placed in components
+type openAPIGenerator struct {
+	schemas map[string]interface{}
+	names   map[reflect.Type]string
+}
+
+func (g *openAPIGenerator) typeName(t reflect.Type) string {
+	if name, ok := g.names[t]; ok {
+		return name
+	}
+	name := t.Name()
+	if _, ok := g.schemas[name]; ok {
+		name = path.Base(t.PkgPath()) + `.` + name
+	}
+	g.names[t] = name
+	return name
+}
+
+func (g *openAPIGenerator) schema(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	marshaler := reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	if t.Implements(marshaler) || reflect.PtrTo(t).Implements(marshaler) {
+		return map[string]interface{}{}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{`type`: `boolean`}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return map[string]interface{}{`type`: `integer`, `format`: `int64`}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]interface{}{`type`: `integer`, `format`: `int32`}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{`type`: `number`}
+	case reflect.String:
+		return map[string]interface{}{`type`: `string`}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{`type`: `string`, `format`: `byte`}
+		}
+		return map[string]interface{}{`type`: `array`, `items`: g.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{`type`: `object`, `additionalProperties`: g.schema(t.Elem())}
+	case reflect.Struct:
+		if len(t.Name()) == 0 {
+			return g.object(t)
+		}
+		name := g.typeName(t)
+		if _, ok := g.schemas[name]; !ok {
+			// the placeholder prevents the infinite recursion for the recursive types
+			g.schemas[name] = nil
+			g.schemas[name] = g.object(t)
+		}
+		return map[string]interface{}{`$ref`: `#/components/schemas/` + name}
+	}
+	return map[string]interface{}{}
+}
+
+func (g *openAPIGenerator) object(t reflect.Type) map[string]interface{} {
+	props := make(map[string]interface{})
+	g.fields(t, props)
+	return map[string]interface{}{`type`: `object`, `properties`: props}
+}
+
+func (g *openAPIGenerator) fields(t reflect.Type, props map[string]interface{}) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := strings.Split(field.Tag.Get(`json`), `,`)
+		if tag[0] == `-` {
+			continue
+		}
+		if field.Anonymous && len(tag[0]) == 0 && field.Type.Kind() == reflect.Struct {
+			g.fields(field.Type, props)
+			continue
+		}
+		if len(field.PkgPath) > 0 {
+			continue
+		}
+		name := field.Name
+		if len(tag[0]) > 0 {
+			name = tag[0]
+		}
+		schema := g.schema(field.Type)
+		for _, opt := range tag[1:] {
+			if opt == `string` {
+				schema = map[string]interface{}{`type`: `string`}
+			}
+		}
+		props[name] = schema
+	}
+}
+
+// operationID returns the identifier of the operation like getListName for GET list/:name
+func operationID(method, pattern string) string {
+	id := strings.ToLower(method)
+	for _, item := range regOperationID.Split(pattern, -1) {
+		if len(item) > 0 {
+			id += strings.ToUpper(item[:1]) + item[1:]
+		}
+	}
+	return id
+}
+
+func paramSchema(vtype int) map[string]interface{} {
+	switch vtype &^ pOptional {
+	case pInt64:
+		return map[string]interface{}{`type`: `integer`, `format`: `int64`}
+	case pHex:
+		return map[string]interface{}{`type`: `string`, `pattern`: `^[0-9a-fA-F]*$`}
+	}
+	return map[string]interface{}{`type`: `string`}
+}
+
+func (g *openAPIGenerator) operation(route *routeInfo) (string, map[string]interface{}) {
+	var (
+		pathNames []string
+		params    []interface{}
+	)
+	inPath := make(map[string]bool)
+	for _, item := range strings.Split(route.pattern, `/`) {
+		if strings.HasPrefix(item, `:`) {
+			item = item[1:]
+			inPath[item] = true
+			params = append(params, map[string]interface{}{`name`: item, `in`: `path`, `required`: true,
+				`schema`: map[string]interface{}{`type`: `string`}})
+			item = `{` + item + `}`
+		}
+		pathNames = append(pathNames, item)
+	}
+	names := make([]string, 0, len(route.params))
+	for name := range route.params {
+		if !inPath[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	op := map[string]interface{}{
+		`operationId`: operationID(route.method, route.pattern),
+		`tags`:        []string{pathNames[0]},
+	}
+	if route.method == `GET` {
+		for _, name := range names {
+			params = append(params, map[string]interface{}{`name`: name, `in`: `query`,
+				`required`: route.params[name]&pOptional == 0, `schema`: paramSchema(route.params[name])})
+		}
+	} else if len(names) > 0 {
+		props := make(map[string]interface{})
+		required := make([]string, 0)
+		for _, name := range names {
+			props[name] = paramSchema(route.params[name])
+			if route.params[name]&pOptional == 0 {
+				required = append(required, name)
+			}
+		}
+		body := map[string]interface{}{`type`: `object`, `properties`: props}
+		if len(required) > 0 {
+			body[`required`] = required
+		}
+		content := map[string]interface{}{`schema`: body}
+		op[`requestBody`] = map[string]interface{}{
+			`required`: len(required) > 0,
+			`content`: map[string]interface{}{
+				`application/x-www-form-urlencoded`: content,
+				`multipart/form-data`:               content,
+			},
+		}
+	}
+	if len(params) > 0 {
+		op[`parameters`] = params
+	}
+	if route.auth {
+		op[`security`] = []interface{}{map[string]interface{}{`bearerAuth`: []string{}},
+			map[string]interface{}{`apiKeyAuth`: []string{}}}
+	}
+
+	content := map[string]interface{}{}
+	key := route.method + ` ` + route.pattern
+	result, ok := routeResults[key]
+	switch {
+	case !ok:
+		log.WithFields(log.Fields{"type": consts.RouteError, "route": key}).Warning("result of api route is not described")
+		content[`application/json`] = map[string]interface{}{`schema`: map[string]interface{}{}}
+	case reflect.TypeOf(result) == reflect.TypeOf(imageResult{}):
+		content[`image/*`] = map[string]interface{}{`schema`: map[string]interface{}{`type`: `string`, `format`: `binary`}}
+	case reflect.TypeOf(result) == reflect.TypeOf(streamResult{}):
+		// the schema describes the data of every event of the stream
+		content[`text/event-stream`] = map[string]interface{}{`schema`: g.schema(reflect.TypeOf(result.(streamResult).event))}
+	default:
+		content[`application/json`] = map[string]interface{}{`schema`: g.schema(reflect.TypeOf(result))}
+	}
+	op[`responses`] = map[string]interface{}{
+		`200`:     map[string]interface{}{`description`: `OK`, `content`: content},
+		`default`: map[string]interface{}{`$ref`: `#/components/responses/Error`},
+	}
+	return `/` + strings.Join(pathNames, `/`), op
+}
+
+// getOpenAPISpec returns OpenAPI 3 document which is generated from the registered routes
+func getOpenAPISpec() map[string]interface{} {
+	g := &openAPIGenerator{schemas: make(map[string]interface{}), names: make(map[reflect.Type]string)}
+	paths := make(map[string]interface{})
+	for _, route := range apiRoutes {
+		uri, op := g.operation(route)
+		item, ok := paths[uri].(map[string]interface{})
+		if !ok {
+			item = make(map[string]interface{})
+			paths[uri] = item
+		}
+		item[strings.ToLower(route.method)] = op
+	}
+	errorContent := map[string]interface{}{`schema`: g.schema(reflect.TypeOf(errorResult{}))}
+	return map[string]interface{}{
+		`openapi`: `3.0.0`,
+		`info`: map[string]interface{}{
+			`title`:   `Apla API`,
+			`version`: consts.VERSION,
+		},
+		`servers`: []interface{}{map[string]interface{}{`url`: strings.TrimSuffix(consts.ApiPath, `/`)}},
+		`paths`:   paths,
+		`components`: map[string]interface{}{
+			`schemas`: g.schemas,
+			`responses`: map[string]interface{}{
+				`Error`: map[string]interface{}{
+					`description`: `Error`,
+					`content`:     map[string]interface{}{`application/json`: errorContent},
+				},
+			},
+			`securitySchemes`: map[string]interface{}{
+				`bearerAuth`: map[string]interface{}{`type`: `http`, `scheme`: `bearer`, `bearerFormat`: `JWT`},
+				`apiKeyAuth`: map[string]interface{}{`type`: `apiKey`, `in`: `header`, `name`: `Authorization`,
+					`description`: `ApiKey <ecosystem>:<secret>`},
+			},
+		},
+	}
+}
+
+func getOpenAPI(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) error {
+	openAPI.once.Do(func() {
+		openAPI.spec = getOpenAPISpec()
+	})
+	data.result = openAPI.spec
+	return nil
+}
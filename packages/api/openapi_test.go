@@ -0,0 +1,43 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"testing"
+
+	hr "github.com/julienschmidt/httprouter"
+)
+
+func TestRouteResults(t *testing.T) {
+	Route(hr.New())
+	if len(apiRoutes) == 0 {
+		t.Fatal(`routes are not registered`)
+	}
+	routes := make(map[string]bool)
+	for _, route := range apiRoutes {
+		key := route.method + ` ` + route.pattern
+		routes[key] = true
+		if _, ok := routeResults[key]; !ok {
+			t.Errorf(`result of route %s is not described`, key)
+		}
+	}
+	for key := range routeResults {
+		if !routes[key] {
+			t.Errorf(`result of unknown route %s is described`, key)
+		}
+	}
+}
@@ -85,6 +85,9 @@ func (h *contractHandlers) prepareMultipleContract(w http.ResponseWriter, r *htt
 	forSigns := []string{}
 	limitForsign := syspar.GetMaxForsignSize()
 	for _, c := range requests.Contracts {
+		if err := checkAPIKeyContract(w, data, c.Contract, logger); err != nil {
+			return err
+		}
 		var smartTx tx.SmartContract
 		contract, parerr, err := validateSmartContractJSON(r, data, c.Contract, c.Params)
 		if err != nil {
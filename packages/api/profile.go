@@ -0,0 +1,74 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+
+	"github.com/AplaProject/go-apla/packages/consts"
+	"github.com/AplaProject/go-apla/packages/script"
+	"github.com/AplaProject/go-apla/packages/smart"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ProfileResult is the result of the profiling of the contract
+type ProfileResult struct {
+	Result  string                 `json:"result"`
+	Message *txstatusError         `json:"errmsg,omitempty"`
+	Summary *script.ProfileSummary `json:"summary"`
+	Pprof   []byte                 `json:"pprof,omitempty"`
+}
+
+// ProfileContract runs the contract with the profiler, the changes of the contract are rolled back
+func ProfileContract(sc *smart.SmartContract) (*ProfileResult, error) {
+	defer sc.DbTransaction.Rollback()
+
+	sc.Profiler = script.NewProfiler()
+	result := &ProfileResult{}
+	ret, err := sc.CallContract(smart.CallInit | smart.CallCondition | smart.CallAction)
+	if err == nil {
+		result.Result = ret
+	} else if errResult := json.Unmarshal([]byte(err.Error()), &result.Message); errResult != nil {
+		log.WithFields(log.Fields{"type": consts.JSONUnmarshallError, "text": err.Error(),
+			"error": errResult}).Error("unmarshalling contract error")
+		result.Message = &txstatusError{Type: "panic", Error: err.Error()}
+	}
+	result.Summary = sc.Profiler.Summary()
+	var buf bytes.Buffer
+	if err = sc.Profiler.WritePprof(&buf); err != nil {
+		log.WithFields(log.Fields{"type": consts.IOError, "error": err}).Error("writing pprof profile")
+		return nil, err
+	}
+	result.Pprof = buf.Bytes()
+	return result, nil
+}
+
+func profileContract(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) error {
+	sc, err := dryRunContract(w, r, data, data.ParamString(`name`), logger)
+	if err != nil {
+		return err
+	}
+	result, err := ProfileContract(sc)
+	if err != nil {
+		return errorAPI(w, err, http.StatusInternalServerError)
+	}
+	data.result = result
+	return nil
+}
@@ -0,0 +1,130 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AplaProject/go-apla/packages/conf"
+	"github.com/AplaProject/go-apla/packages/consts"
+	"github.com/AplaProject/go-apla/packages/ratelimit"
+	"github.com/AplaProject/go-apla/packages/statsd"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type apiLimiters struct {
+	client    *ratelimit.Limiter
+	key       *ratelimit.Limiter
+	ecosystem *ratelimit.Limiter
+}
+
+func newAPILimiters(limits conf.APILimits) *apiLimiters {
+	return &apiLimiters{
+		client:    ratelimit.NewLimiter(limits.Client.Rate, limits.Client.Burst),
+		key:       ratelimit.NewLimiter(limits.Key.Rate, limits.Key.Burst),
+		ecosystem: ratelimit.NewLimiter(limits.Ecosystem.Rate, limits.Ecosystem.Burst),
+	}
+}
+
+type limitCheck struct {
+	name    string
+	limiter *ratelimit.Limiter
+	key     string
+}
+
+// limiters are created at the first request because the config is loaded after routing
+var limiters = struct {
+	sync.Mutex
+	def    *apiLimiters
+	routes map[string]*apiLimiters
+}{routes: make(map[string]*apiLimiters)}
+
+// getLimiters returns the limiters of the route. The routes without own settings share
+// the default limiters.
+func getLimiters(route string) *apiLimiters {
+	route = strings.ToLower(route)
+	limiters.Lock()
+	defer limiters.Unlock()
+	if l, ok := limiters.routes[route]; ok {
+		return l
+	}
+	var l *apiLimiters
+	for pattern, limits := range conf.Config.RateLimit.Routes {
+		if strings.ToLower(pattern) == route {
+			l = newAPILimiters(limits)
+			break
+		}
+	}
+	if l == nil {
+		if limiters.def == nil {
+			limiters.def = newAPILimiters(conf.Config.RateLimit.Default)
+		}
+		l = limiters.def
+	}
+	limiters.routes[route] = l
+	return l
+}
+
+// checkLimits returns the error if one of the limits has been exceeded
+func checkLimits(w http.ResponseWriter, data *apiData, logger *log.Entry, counterName string, checks []limitCheck) error {
+	for _, check := range checks {
+		ok, wait := check.limiter.Allow(check.key)
+		if ok {
+			continue
+		}
+		statsd.Client.Inc(counterName+statsd.RateLimited, 1, 1.0)
+		logger.WithFields(log.Fields{"type": consts.ParameterExceeded, "limit": check.name,
+			"key": check.key, "route": data.route}).Warning("too many requests")
+		seconds := int64((wait + time.Second - 1) / time.Second)
+		w.Header().Set("Retry-After", fmt.Sprint(seconds))
+		return errorAPI(w, `E_LIMITREQUESTS`, http.StatusTooManyRequests, seconds)
+	}
+	return nil
+}
+
+// clientRateLimit returns the handler which rejects the requests exceeding the limit of the client address.
+// It is called before the token is checked, so the flood of requests doesn't load the database.
+func clientRateLimit(counterName string) apiHandle {
+	return func(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) error {
+		client, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			client = r.RemoteAddr
+		}
+		return checkLimits(w, data, logger, counterName,
+			[]limitCheck{{`client`, getLimiters(data.route).client, client}})
+	}
+}
+
+// keyRateLimit returns the handler which rejects the requests exceeding the limits of the key and the ecosystem
+func keyRateLimit(counterName string) apiHandle {
+	return func(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) error {
+		if data.keyId == 0 {
+			return nil
+		}
+		l := getLimiters(data.route)
+		return checkLimits(w, data, logger, counterName, []limitCheck{
+			{`key`, l.key, fmt.Sprintf(`%d:%d`, data.ecosystemId, data.keyId)},
+			{`ecosystem`, l.ecosystem, fmt.Sprint(data.ecosystemId)},
+		})
+	}
+}
@@ -28,11 +28,13 @@ import (
 )
 
 func methodRoute(route *hr.Router, method, pattern, pars string, handler ...apiHandle) {
-	route.Handle(
-		method,
-		consts.ApiPath+pattern,
-		DefaultHandler(method, pattern, processParams(pars), append([]apiHandle{blockchainUpdatingState}, handler...)...),
-	)
+	params := processParams(pars)
+	apiRoutes = append(apiRoutes, newRouteInfo(method, pattern, params, handler))
+	handle := DefaultHandler(method, pattern, params, append([]apiHandle{blockchainUpdatingState}, handler...)...)
+	route.Handle(method, consts.ApiPath+pattern, handle)
+	if isBatchRoute(method, pattern) {
+		batchRouter.Handle(method, consts.ApiPath+pattern, handle)
+	}
 }
 
 // Route sets routing pathes
@@ -48,14 +50,19 @@ func Route(route *hr.Router) {
 		multiRequests: tx.NewMultiRequestBuffer(consts.TxRequestExpire),
 	}
 
+	apiRoutes = apiRoutes[:0]
+	batchRouter = hr.New()
 	route.Handle(`OPTIONS`, consts.ApiPath+`*name`, optionsHandler())
 	route.Handle(`GET`, consts.ApiPath+`data/:table/:id/:column/:hash`, dataHandler())
 
 	get(`contract/:name`, ``, authWallet, getContract)
 	get(`contracts`, `?limit ?offset:int64`, authWallet, getContracts)
 	get(`getuid`, ``, getUID)
-	get(`list/:name`, `?limit ?offset:int64,?columns:string`, authWallet, list)
-	get(`row/:name/:id`, `?columns:string`, authWallet, row)
+	get(`openapi.json`, ``, getOpenAPI)
+	get(`graphql`, `?query ?variables ?operationName:string`, authWallet, graphQL)
+	get(`graphql/schema`, ``, authWallet, graphQLSchema)
+	get(`list/:name`, `?limit ?offset ?after_id ?as_of_block:int64,?columns ?where ?order:string`, authWallet, list)
+	get(`row/:name/:id`, `?columns:string,?as_of_block:int64`, authWallet, row)
 	get(`interface/page/:name`, ``, authWallet, getPageRow)
 	get(`interface/menu/:name`, ``, authWallet, getMenuRow)
 	get(`interface/block/:name`, ``, authWallet, getBlockInterfaceRow)
@@ -80,6 +87,13 @@ func Route(route *hr.Router) {
 	post(`refresh`, `token:string,?expire:int64`, refresh)
 	post(`test/:name`, ``, getTest)
 	post(`content`, `template ?source:string`, jsonContent)
+	post(`batch`, `data:string`, authWallet, batch)
+	post(`debug/:name`, `?breakpoints:string`, authWallet, startDebug)
+	post(`debugcmd/:session`, `cmd:string`, authWallet, debugCommand)
+	post(`simulate/:name`, ``, authWallet, simulateContract)
+	post(`profile/:name`, ``, authWallet, profileContract)
+	post(`lint`, `source:string`, authWallet, lintContract)
+	post(`graphql`, `?query ?variables ?operationName:string`, authWallet, graphQL)
 	post(`updnotificator`, `ids:string`, updateNotificator)
 	get(`ecosystemparam/:name`, `?ecosystem:int64`, authWallet, ecosystemParam)
 	methodRoute(route, `POST`, `node/:name`, `?token_ecosystem:int64,?max_sum ?payover:string`, contractHandlers.nodeContract)
@@ -87,12 +101,20 @@ func Route(route *hr.Router) {
 	if !conf.Config.IsSupportingVDE() {
 		get(`txstatus/:hash`, ``, authWallet, txstatus)
 		get(`txstatusMultiple`, `data:string`, authWallet, txstatusMulti)
+		get(`txstream`, `hashes:string`, authWallet, txStream)
 		get(`appparam/:appid/:name`, `?ecosystem:int64`, authWallet, appParam)
 		get(`appparams/:appid`, `?ecosystem:int64,?names:string`, authWallet, appParams)
 		get(`history/:table/:id`, ``, authWallet, getHistory)
+		get(`events`, `?contract ?name ?where:string,?from_block ?to_block ?limit ?offset:int64`, authWallet, getEvents)
 		get(`balance/:wallet`, `?ecosystem:int64`, authWallet, balance)
 		get(`block/:id`, ``, getBlockInfo)
+		get(`explorer/block/:id`, ``, authWallet, getExplorerBlock)
+		get(`explorer/blocks`, `from_block:int64,?count:int64`, authWallet, getExplorerBlocks)
+		get(`explorer/tx/:hash`, ``, authWallet, getExplorerTx)
+		get(`explorer/key/:key_id`, `?limit ?offset:int64`, authWallet, getExplorerKeyTxs)
 		get(`maxblockid`, ``, getMaxBlockID)
+		get(`webhooks/deliveries`, `?webhook_id ?limit ?offset:int64,?status:string`, authWallet, getWebhookDeliveries)
+		get(`webhooks/deadletters`, `?webhook_id ?limit ?offset:int64`, authWallet, getWebhookDeadLetters)
 
 		get(`ecosystemparams`, `?ecosystem:int64,?names:string`, authWallet, ecosystemParams)
 		get(`systemparams`, `?names:string`, authWallet, systemParams)
@@ -18,6 +18,7 @@ package api
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/AplaProject/go-apla/packages/consts"
 	"github.com/AplaProject/go-apla/packages/converter"
@@ -36,7 +37,15 @@ func row(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entr
 		cols = converter.EscapeName(data.params[`columns`].(string))
 	}
 	table := converter.EscapeName(getPrefix(data) + `_` + data.params[`name`].(string))
-	row, err := model.GetOneRow(`SELECT `+cols+` FROM `+table+` WHERE id = ?`, data.params[`id`].(string)).String()
+	from, err := asOfBlockTable(table, data)
+	if err == model.ErrAsOfBlockDepth {
+		return errorAPI(w, err, http.StatusBadRequest)
+	}
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err, "table": table}).Error("getting table as of block")
+		return errorAPI(w, `E_QUERY`, http.StatusInternalServerError)
+	}
+	row, err := model.GetOneRowTransaction(data.tx, `SELECT `+cols+` FROM `+from+` WHERE id = ?`, data.params[`id`].(string)).String()
 	if err != nil {
 		logger.WithFields(log.Fields{"type": consts.DBError, "error": err, "table": data.params["name"].(string), "id": data.params["id"].(string)}).Error("getting one row")
 		return errorAPI(w, `E_QUERY`, http.StatusInternalServerError)
@@ -45,3 +54,13 @@ func row(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entr
 	data.result = &rowResult{Value: row}
 	return
 }
+
+// asOfBlockTable returns the expression of the table with the state after as_of_block
+// if this parameter has been specified
+func asOfBlockTable(table string, data *apiData) (string, error) {
+	blockID, _ := data.params[`as_of_block`].(int64)
+	if blockID <= 0 {
+		return table, nil
+	}
+	return model.GetTableAsOfBlock(data.tx, strings.Trim(table, `"`), blockID)
+}
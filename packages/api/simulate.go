@@ -0,0 +1,60 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/AplaProject/go-apla/packages/consts"
+	"github.com/AplaProject/go-apla/packages/smart"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type simulateResult struct {
+	Result   string            `json:"result"`
+	Fuel     int64             `json:"fuel"`
+	Builtins map[string]int64  `json:"builtins"`
+	Changes  []smart.RowChange `json:"changes"`
+	Message  *txstatusError    `json:"errmsg,omitempty"`
+}
+
+func simulateContract(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) error {
+	sc, err := dryRunContract(w, r, data, data.ParamString(`name`), logger)
+	if err != nil {
+		return err
+	}
+	defer sc.DbTransaction.Rollback()
+
+	sc.FuelStat = make(map[string]int64)
+	result := &simulateResult{Builtins: sc.FuelStat, Changes: make([]smart.RowChange, 0)}
+	ret, err := sc.CallContract(smart.CallInit | smart.CallCondition | smart.CallAction)
+	if err == nil {
+		result.Result = ret
+	} else if errResult := json.Unmarshal([]byte(err.Error()), &result.Message); errResult != nil {
+		logger.WithFields(log.Fields{"type": consts.JSONUnmarshallError, "text": err.Error(),
+			"error": errResult}).Error("unmarshalling contract error")
+		result.Message = &txstatusError{Type: "panic", Error: err.Error()}
+	}
+	result.Fuel = sc.TxFuel
+	if sc.RowChanges != nil {
+		result.Changes = sc.RowChanges
+	}
+	data.result = result
+	return nil
+}
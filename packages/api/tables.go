@@ -45,7 +45,7 @@ func tables(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.E
 
 	table := getPrefix(data) + `_tables`
 
-	count, err := model.GetRecordsCountTx(nil, table)
+	count, err := model.GetRecordsCountTx(data.tx, table)
 	if err != nil {
 		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("selecting records count from tables")
 		return errorAPI(w, err.Error(), http.StatusInternalServerError)
@@ -55,7 +55,7 @@ func tables(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.E
 	} else {
 		limit = 25
 	}
-	list, err := model.GetAll(`select name from "`+table+`" order by name`+
+	list, err := model.GetAllTx(data.tx, `select name from "`+table+`" order by name`+
 		fmt.Sprintf(` offset %d `, data.params[`offset`].(int64)), limit)
 	if err != nil {
 		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("selecting names from tables")
@@ -70,12 +70,12 @@ func tables(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.E
 		result.List[i].Name = item[`name`]
 		fullname := getPrefix(data) + `_` + item[`name`]
 		if item[`name`] == `keys` || item[`name`] == `members` {
-			err = model.DBConn.Table(fullname).Count(&maxid).Error
+			err = model.GetDB(data.tx).Table(fullname).Count(&maxid).Error
 			if err != nil {
 				logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("selecting count from table")
 			}
 		} else {
-			maxid, err = model.GetNextID(nil, fullname)
+			maxid, err = model.GetNextID(data.tx, fullname)
 			if err != nil {
 				logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting next id from table")
 			}
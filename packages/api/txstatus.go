@@ -29,8 +29,9 @@ import (
 )
 
 type txstatusError struct {
-	Type  string `json:"type,omitempty"`
-	Error string `json:"error,omitempty"`
+	Type  string   `json:"type,omitempty"`
+	Error string   `json:"error,omitempty"`
+	Trace []string `json:"trace,omitempty"`
 }
 
 type txstatusResult struct {
@@ -59,17 +60,23 @@ func getTxStatus(hash string, w http.ResponseWriter, logger *log.Entry) (*txstat
 		status.BlockID = converter.Int64ToStr(ts.BlockID)
 		status.Result = ts.Error
 	} else if len(ts.Error) > 0 {
-		if err := json.Unmarshal([]byte(ts.Error), &status.Message); err != nil {
-			logger.WithFields(log.Fields{"type": consts.JSONUnmarshallError, "text": ts.Error, "error": err}).Warn("unmarshalling txstatus error")
-			status.Message = &txstatusError{
-				Type:  "txError",
-				Error: ts.Error,
-			}
-		}
+		status.Message = getTxError(ts.Error, logger)
 	}
 	return &status, nil
 }
 
+// getTxError converts the text of the error of the transaction to the error object
+func getTxError(errText string, logger *log.Entry) (message *txstatusError) {
+	if err := json.Unmarshal([]byte(errText), &message); err != nil {
+		logger.WithFields(log.Fields{"type": consts.JSONUnmarshallError, "text": errText, "error": err}).Warn("unmarshalling txstatus error")
+		message = &txstatusError{
+			Type:  "txError",
+			Error: errText,
+		}
+	}
+	return
+}
+
 type multiTxStatusResult struct {
 	Results map[string]*txstatusResult `json:"results"`
 }
@@ -0,0 +1,156 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/AplaProject/go-apla/packages/consts"
+	"github.com/AplaProject/go-apla/packages/converter"
+	"github.com/AplaProject/go-apla/packages/model"
+	"github.com/AplaProject/go-apla/packages/txstream"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	txStreamMaxHashes = 100
+	txStreamPing      = 30 * time.Second
+)
+
+type txStreamEvent struct {
+	Hash    string         `json:"hash"`
+	Status  string         `json:"status"`
+	BlockID string         `json:"blockid,omitempty"`
+	Result  string         `json:"result,omitempty"`
+	Message *txstatusError `json:"errmsg,omitempty"`
+}
+
+func writeTxStreamEvent(w http.ResponseWriter, event *txstream.Event, logger *log.Entry) error {
+	out := txStreamEvent{Hash: event.Hash, Status: event.Status, Result: event.Result}
+	if event.BlockID > 0 {
+		out.BlockID = converter.Int64ToStr(event.BlockID)
+	}
+	if len(event.Error) > 0 {
+		out.Message = getTxError(event.Error, logger)
+	}
+	data, err := json.Marshal(out)
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.JSONMarshallError, "error": err}).Error("marshalling tx stream event")
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Status, data)
+	return err
+}
+
+// getTxStreamStatus returns the current status of the transaction, it is sent when the stream starts
+func getTxStreamStatus(hash string) (*txstream.Event, error) {
+	bin, err := hex.DecodeString(hash)
+	if err != nil {
+		return nil, err
+	}
+	ts := &model.TransactionStatus{}
+	found, err := ts.Get(bin)
+	if err != nil || !found {
+		return nil, err
+	}
+	event := &txstream.Event{Hash: hash, Status: txstream.StatusQueued}
+	if ts.BlockID > 0 {
+		event.Status = txstream.StatusAccepted
+		event.BlockID = ts.BlockID
+		event.Result = ts.Error
+	} else if len(ts.Error) > 0 {
+		event.Status = txstream.StatusRejected
+		event.Error = ts.Error
+	}
+	return event, nil
+}
+
+// txStream sends the statuses of the transactions as Server-Sent Events. The current statuses
+// are sent at first and then the changes: queued, accepted, rejected and rolledback.
+func txStream(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) error {
+	hashes := make([]string, 0)
+	for _, hash := range strings.Split(data.ParamString(`hashes`), `,`) {
+		hash = strings.ToLower(strings.TrimSpace(hash))
+		if len(hash) == 0 {
+			continue
+		}
+		if _, err := hex.DecodeString(hash); err != nil {
+			logger.WithFields(log.Fields{"type": consts.ConversionError, "error": err}).Error("decoding tx hash from hex")
+			return errorAPI(w, `E_HASHWRONG`, http.StatusBadRequest)
+		}
+		hashes = append(hashes, hash)
+	}
+	if len(hashes) == 0 {
+		return errorAPI(w, `E_UNDEFINEVAL`, http.StatusBadRequest, `hashes`)
+	}
+	if len(hashes) > txStreamMaxHashes {
+		return errorAPI(w, `E_LIMITHASHES`, http.StatusBadRequest, len(hashes))
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		logger.WithFields(log.Fields{"type": consts.IOError}).Error("streaming is not supported")
+		return errorAPI(w, `E_SERVER`, http.StatusInternalServerError)
+	}
+
+	// the subscription is created before reading the statuses so no change is lost
+	sub := txstream.Subscribe(hashes)
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	data.streamed = true
+
+	for _, hash := range hashes {
+		event, err := getTxStreamStatus(hash)
+		if err != nil {
+			logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting transaction status by hash")
+			return nil
+		}
+		if event != nil {
+			if err = writeTxStreamEvent(w, event, logger); err != nil {
+				return nil
+			}
+		}
+	}
+	flusher.Flush()
+
+	ping := time.NewTicker(txStreamPing)
+	defer ping.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return nil
+		case event := <-sub.C:
+			if err := writeTxStreamEvent(w, event, logger); err != nil {
+				return nil
+			}
+		case <-ping.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return nil
+			}
+		}
+		flusher.Flush()
+	}
+}
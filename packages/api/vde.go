@@ -139,6 +139,15 @@ func InitSmartContract(sc *smart.SmartContract, data []byte) error {
 					forv = strings.Join(slist, `,`)
 				}
 				v = list
+			default:
+				if len(script.RecordName(fitem.Type)) > 0 {
+					var s string
+					if err := converter.BinUnmarshal(&input, &s); err != nil {
+						return err
+					}
+					v, err = script.DecodeRecord(fitem.Type, s)
+					forv, isforv = s, true
+				}
 			}
 			sc.TxData[fitem.Name] = v
 			if err != nil {
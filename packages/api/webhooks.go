@@ -0,0 +1,75 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/AplaProject/go-apla/packages/consts"
+	"github.com/AplaProject/go-apla/packages/model"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const webhooksLimit = 100
+
+type webhookDeliveriesResult struct {
+	Count int64                   `json:"count"`
+	List  []model.WebhookDelivery `json:"list"`
+}
+
+type webhookDeadLettersResult struct {
+	Count int64                     `json:"count"`
+	List  []model.WebhookDeadLetter `json:"list"`
+}
+
+func webhooksPaging(data *apiData) (offset, limit int64) {
+	limit = data.ParamInt64(`limit`)
+	if limit <= 0 {
+		limit = 25
+	} else if limit > webhooksLimit {
+		limit = webhooksLimit
+	}
+	offset = data.ParamInt64(`offset`)
+	if offset < 0 {
+		offset = 0
+	}
+	return
+}
+
+func getWebhookDeliveries(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) error {
+	offset, limit := webhooksPaging(data)
+	list, count, err := model.GetWebhookDeliveries(data.ecosystemId, data.ParamInt64(`webhook_id`),
+		data.ParamString(`status`), offset, limit)
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting webhook deliveries")
+		return errorAPI(w, err, http.StatusInternalServerError)
+	}
+	data.result = &webhookDeliveriesResult{Count: count, List: list}
+	return nil
+}
+
+func getWebhookDeadLetters(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) error {
+	offset, limit := webhooksPaging(data)
+	list, count, err := model.GetWebhookDeadLetters(data.ecosystemId, data.ParamInt64(`webhook_id`), offset, limit)
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting webhook dead letters")
+		return errorAPI(w, err, http.StatusInternalServerError)
+	}
+	data.result = &webhookDeadLettersResult{Count: count, List: list}
+	return nil
+}
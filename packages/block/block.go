@@ -2,15 +2,21 @@ package block
 
 import (
 	"bytes"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"time"
 
+	"github.com/AplaProject/go-apla/packages/conf"
 	"github.com/AplaProject/go-apla/packages/conf/syspar"
 	"github.com/AplaProject/go-apla/packages/consts"
 	"github.com/AplaProject/go-apla/packages/converter"
 	"github.com/AplaProject/go-apla/packages/model"
+	"github.com/AplaProject/go-apla/packages/publisher"
+	"github.com/AplaProject/go-apla/packages/snapshot"
 	"github.com/AplaProject/go-apla/packages/transaction"
 	"github.com/AplaProject/go-apla/packages/transaction/custom"
+	"github.com/AplaProject/go-apla/packages/txstream"
 	"github.com/AplaProject/go-apla/packages/utils"
 
 	log "github.com/sirupsen/logrus"
@@ -24,8 +30,10 @@ type Block struct {
 	BinData      []byte
 	Transactions []*transaction.Transaction
 	SysUpdate    bool
-	GenBlock     bool // it equals true when we are generating a new block
-	StopCount    int  // The count of good tx in the block
+	GenBlock     bool              // it equals true when we are generating a new block
+	StopCount    int               // The count of good tx in the block
+	Events       []model.Event     // The events which have been emitted by the contracts of the block
+	TxEvents     []*txstream.Event // The statuses of the accepted transactions
 }
 
 func (b Block) String() string {
@@ -93,6 +101,11 @@ func (b *Block) PlaySafe() error {
 	}
 
 	dbTransaction.Commit()
+	go b.PublishEvents()
+	if snapshot.IsSnapshotBlock(b.Header.BlockID) {
+		// the hash of the snapshot is committed by the node which has generated the block
+		snapshot.Create(b.Header.BlockID, b.Header.KeyID == conf.Config.KeyID)
+	}
 	if b.SysUpdate {
 		b.SysUpdate = false
 		if err = syspar.SysUpdate(nil); err != nil {
@@ -103,6 +116,24 @@ func (b *Block) PlaySafe() error {
 	return nil
 }
 
+// PublishEvents sends the events and the statuses of transactions of the committed block to the subscribers
+func (b *Block) PublishEvents() {
+	for _, event := range b.TxEvents {
+		txstream.Publish(event)
+	}
+	for _, event := range b.Events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			log.WithFields(log.Fields{"type": consts.JSONMarshallError, "error": err}).Error("marshalling event")
+			continue
+		}
+		if _, err = publisher.WriteEvent(event.Ecosystem, data); err != nil {
+			log.WithFields(log.Fields{"type": consts.CentrifugoError, "error": err}).Error("publishing event")
+			return
+		}
+	}
+}
+
 func (b *Block) readPreviousBlockFromBlockchainTable() error {
 	if b.Header.BlockID == 1 {
 		b.PrevHeader = &utils.BlockData{}
@@ -125,6 +156,8 @@ func (b *Block) Play(dbTransaction *model.DbTransaction) error {
 	}
 
 	limits := NewLimits(b)
+	b.Events = nil
+	b.TxEvents = nil
 
 	txHashes := make([][]byte, 0, len(b.Transactions))
 	for _, btx := range b.Transactions {
@@ -197,6 +230,7 @@ func (b *Block) Play(dbTransaction *model.DbTransaction) error {
 			b.SysUpdate = true
 			t.SysUpdate = false
 		}
+		b.Events = append(b.Events, t.Events...)
 
 		if _, err := model.MarkTransactionUsed(t.DbTransaction, t.TxHash); err != nil {
 			logger.WithFields(log.Fields{"type": consts.DBError, "error": err, "tx_hash": t.TxHash}).Error("marking transaction used")
@@ -209,7 +243,10 @@ func (b *Block) Play(dbTransaction *model.DbTransaction) error {
 			logger.WithFields(log.Fields{"type": consts.DBError, "error": err, "tx_hash": t.TxHash}).Error("updating transaction status block id")
 			return err
 		}
-		if err := transaction.InsertInLogTx(t.DbTransaction, t.TxFullData, t.TxTime); err != nil {
+		b.TxEvents = append(b.TxEvents, &txstream.Event{Hash: hex.EncodeToString(t.TxHash),
+			Status: txstream.StatusAccepted, BlockID: b.Header.BlockID, Result: msg})
+		if err := transaction.InsertInLogTx(t.DbTransaction, t.TxFullData, t.TxTime, b.Header.BlockID, t.TxKeyID,
+			t.TxSpentFuel); err != nil {
 			return utils.ErrInfo(err)
 		}
 	}
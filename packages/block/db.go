@@ -15,14 +15,18 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+// CalcHash returns the hash of the block with the specified hash of the previous block
+func (b *Block) CalcHash(prevHash []byte) ([]byte, error) {
+	forSha := fmt.Sprintf("%d,%x,%s,%d,%d,%d,%d", b.Header.BlockID, prevHash, b.MrklRoot,
+		b.Header.Time, b.Header.EcosystemID, b.Header.KeyID, b.Header.NodePosition)
+	return crypto.DoubleHash([]byte(forSha))
+}
+
 // UpdBlockInfo updates info_block table
 func UpdBlockInfo(dbTransaction *model.DbTransaction, block *Block) error {
 	blockID := block.Header.BlockID
 	// for the local tests
-	forSha := fmt.Sprintf("%d,%x,%s,%d,%d,%d,%d", blockID, block.PrevHeader.Hash, block.MrklRoot,
-		block.Header.Time, block.Header.EcosystemID, block.Header.KeyID, block.Header.NodePosition)
-
-	hash, err := crypto.DoubleHash([]byte(forSha))
+	hash, err := block.CalcHash(block.PrevHeader.Hash)
 	if err != nil {
 		log.WithFields(log.Fields{"type": consts.CryptoError, "error": err}).Fatal("double hashing block")
 	}
@@ -187,3 +191,44 @@ func GetDataFromFirstBlock() (data *consts.FirstBlock, ok bool) {
 
 	return
 }
+
+// logTxBlocks is the number of blocks which are read at once by FillLogTransactions
+const logTxBlocks = 100
+
+// FillLogTransactions sets the block and the key of the transactions which have been logged before
+// log_transactions had these columns. The stored blocks are decoded to find their transactions.
+func FillLogTransactions() error {
+	count, err := model.GetLogTransactionsWithoutBlockCount()
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("counting log transactions without block")
+		return err
+	}
+	var from int64
+	for count > 0 {
+		blocks, err := model.GetBlockchain(from, from+logTxBlocks)
+		if err != nil {
+			log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting blocks")
+			return err
+		}
+		if len(blocks) == 0 {
+			break
+		}
+		for _, b := range blocks {
+			blk, err := UnmarshallBlock(bytes.NewBuffer(b.Data), true)
+			if err != nil {
+				log.WithFields(log.Fields{"type": consts.UnmarshallingError, "error": err, "block_id": b.ID}).Error("decoding block")
+				return err
+			}
+			for _, t := range blk.Transactions {
+				n, err := model.SetLogTransactionBlock(t.TxHash, b.ID, t.TxKeyID)
+				if err != nil {
+					log.WithFields(log.Fields{"type": consts.DBError, "error": err, "block_id": b.ID}).Error("setting block of log transaction")
+					return err
+				}
+				count -= n
+			}
+			from = b.ID
+		}
+	}
+	return nil
+}
@@ -0,0 +1,250 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package blockarchive contains the archive of blocks which is used to back up the blockchain and
+// to load it to the node without other nodes.
+//
+// The archive starts with the header which isn't compressed:
+//
+//	magic        8 bytes  "APLABLKS"
+//	version      1 byte   the version of the format, now it's 1
+//	compression  1 byte   the code of the compression of the rest of the archive, 0 is none and 1 is gzip
+//
+// The rest of the archive is the sequence of frames, one frame for every block:
+//
+//	block id     8 bytes  big endian
+//	length       4 bytes  big endian, the length of the block data
+//	data         the binary block as it's stored in block_chain
+//	checksum     8 bytes  big endian, crypto.CalcChecksum of the block id, the length and the data
+//
+// The blocks go one by one without gaps. The archive ends with the frame which has zero block id and
+// zero length, so the truncated archive is detected.
+package blockarchive
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+
+	"github.com/AplaProject/go-apla/packages/crypto"
+)
+
+const (
+	// Version is the version of the format which is written by Writer
+	Version = 1
+
+	magic          = "APLABLKS"
+	headerSize     = len(magic) + 2
+	frameHeadSize  = 12
+	checksumSize   = 8
+	writeBufferLen = 1 << 16
+)
+
+var (
+	// ErrFormat is returned if the file isn't the archive of blocks
+	ErrFormat = errors.New("incorrect format of block archive")
+	// ErrChecksum is returned if the checksum of the frame is wrong
+	ErrChecksum = errors.New("wrong checksum of block archive")
+	// ErrTruncated is returned if the archive ends without the final frame
+	ErrTruncated = errors.New("block archive is truncated")
+	// ErrOrder is returned if the blocks of the archive aren't consecutive
+	ErrOrder = errors.New("blocks of archive aren't consecutive")
+)
+
+// Block is the block of the archive
+type Block struct {
+	ID   int64
+	Data []byte
+}
+
+func frameHead(blockID int64, length int) []byte {
+	head := make([]byte, frameHeadSize)
+	binary.BigEndian.PutUint64(head, uint64(blockID))
+	binary.BigEndian.PutUint32(head[8:], uint32(length))
+	return head
+}
+
+func frameChecksum(head, data []byte) ([]byte, error) {
+	sum, err := crypto.CalcChecksum(append(append(make([]byte, 0, len(head)+len(data)), head...), data...))
+	if err != nil {
+		return nil, err
+	}
+	checksum := make([]byte, checksumSize)
+	binary.BigEndian.PutUint64(checksum, sum)
+	return checksum, nil
+}
+
+// Writer writes blocks to the archive
+type Writer struct {
+	buf  *bufio.Writer
+	w    io.WriteCloser
+	last int64
+}
+
+// NewWriter writes the header of the archive and returns the writer of blocks
+func NewWriter(w io.Writer, compression string) (*Writer, error) {
+	c, err := compressionByName(compression)
+	if err != nil {
+		return nil, err
+	}
+	header := append([]byte(magic), Version, c.Code)
+	if _, err = w.Write(header); err != nil {
+		return nil, err
+	}
+	buf := bufio.NewWriterSize(w, writeBufferLen)
+	cw, err := c.NewWriter(buf)
+	if err != nil {
+		return nil, err
+	}
+	return &Writer{buf: buf, w: cw}, nil
+}
+
+func (w *Writer) writeFrame(blockID int64, data []byte) error {
+	head := frameHead(blockID, len(data))
+	checksum, err := frameChecksum(head, data)
+	if err != nil {
+		return err
+	}
+	for _, part := range [][]byte{head, data, checksum} {
+		if _, err = w.w.Write(part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Write appends the block to the archive, the block has to be next to the previous one
+func (w *Writer) Write(blockID int64, data []byte) error {
+	if blockID <= 0 || (w.last > 0 && blockID != w.last+1) {
+		return ErrOrder
+	}
+	if len(data) == 0 || int64(len(data)) > math.MaxUint32 {
+		return fmt.Errorf("wrong size %d of block %d", len(data), blockID)
+	}
+	if err := w.writeFrame(blockID, data); err != nil {
+		return err
+	}
+	w.last = blockID
+	return nil
+}
+
+// Last returns the id of the last written block
+func (w *Writer) Last() int64 {
+	return w.last
+}
+
+// Close writes the final frame and flushes the archive, it doesn't close the underlying writer
+func (w *Writer) Close() error {
+	if err := w.writeFrame(0, nil); err != nil {
+		return err
+	}
+	if err := w.w.Close(); err != nil {
+		return err
+	}
+	return w.buf.Flush()
+}
+
+// Reader reads blocks from the archive
+type Reader struct {
+	r    *bufio.Reader
+	last int64
+	done bool
+
+	// Compression is the name of the compression of the archive
+	Compression string
+}
+
+// NewReader checks the header of the archive and returns the reader of blocks
+func NewReader(r io.Reader) (*Reader, error) {
+	header := make([]byte, headerSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, ErrFormat
+		}
+		return nil, err
+	}
+	if string(header[:len(magic)]) != magic {
+		return nil, ErrFormat
+	}
+	if header[len(magic)] != Version {
+		return nil, fmt.Errorf("unsupported version %d of block archive", header[len(magic)])
+	}
+	c, err := compressionByCode(header[len(magic)+1])
+	if err != nil {
+		return nil, err
+	}
+	cr, err := c.NewReader(bufio.NewReader(r))
+	if err != nil {
+		return nil, readError(err)
+	}
+	return &Reader{r: bufio.NewReader(cr), Compression: c.Name}, nil
+}
+
+// Next returns the next block of the archive or io.EOF after the final frame
+func (r *Reader) Next() (*Block, error) {
+	if r.done {
+		return nil, io.EOF
+	}
+	head := make([]byte, frameHeadSize)
+	if _, err := io.ReadFull(r.r, head); err != nil {
+		return nil, readError(err)
+	}
+	blockID := int64(binary.BigEndian.Uint64(head))
+	length := int64(binary.BigEndian.Uint32(head[8:]))
+	if blockID < 0 || (blockID == 0) != (length == 0) {
+		return nil, ErrFormat
+	}
+	// the data is read by parts, so the wrong length doesn't allocate the memory at once
+	data, err := ioutil.ReadAll(io.LimitReader(r.r, length))
+	if err != nil {
+		return nil, readError(err)
+	}
+	if int64(len(data)) != length {
+		return nil, ErrTruncated
+	}
+	checksum := make([]byte, checksumSize)
+	if _, err = io.ReadFull(r.r, checksum); err != nil {
+		return nil, readError(err)
+	}
+	expected, err := frameChecksum(head, data)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(checksum, expected) {
+		return nil, ErrChecksum
+	}
+	if blockID == 0 {
+		r.done = true
+		return nil, io.EOF
+	}
+	if r.last > 0 && blockID != r.last+1 {
+		return nil, ErrOrder
+	}
+	r.last = blockID
+	return &Block{ID: blockID, Data: data}, nil
+}
+
+func readError(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return ErrTruncated
+	}
+	return err
+}
@@ -0,0 +1,108 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package blockarchive
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+)
+
+func writeArchive(t *testing.T, compression string, from, to int64) []byte {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, compression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for id := from; id <= to; id++ {
+		if err = w.Write(id, []byte(fmt.Sprintf("block %d", id))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func readArchive(data []byte) ([]*Block, error) {
+	r, err := NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	var blocks []*Block
+	for {
+		b, err := r.Next()
+		if err == io.EOF {
+			return blocks, nil
+		}
+		if err != nil {
+			return blocks, err
+		}
+		blocks = append(blocks, b)
+	}
+}
+
+func TestArchive(t *testing.T) {
+	for _, compression := range Compressions() {
+		data := writeArchive(t, compression, 5, 104)
+		blocks, err := readArchive(data)
+		if err != nil {
+			t.Fatalf("%s: %s", compression, err)
+		}
+		if len(blocks) != 100 {
+			t.Fatalf("%s: want 100 blocks, got %d", compression, len(blocks))
+		}
+		for i, b := range blocks {
+			if b.ID != int64(i+5) || string(b.Data) != fmt.Sprintf("block %d", i+5) {
+				t.Errorf("%s: wrong block %d %s", compression, b.ID, b.Data)
+			}
+		}
+	}
+}
+
+func TestArchiveErrors(t *testing.T) {
+	data := writeArchive(t, CompressionNone, 1, 3)
+
+	if _, err := readArchive([]byte("APLA")); err != ErrFormat {
+		t.Errorf("want ErrFormat, got %v", err)
+	}
+	blocks, err := readArchive(data[:len(data)-frameHeadSize-checksumSize])
+	if err != ErrTruncated || len(blocks) != 3 {
+		t.Errorf("want ErrTruncated after 3 blocks, got %v after %d", err, len(blocks))
+	}
+	broken := append([]byte{}, data...)
+	broken[headerSize+frameHeadSize] ^= 0xff
+	if _, err = readArchive(broken); err != ErrChecksum {
+		t.Errorf("want ErrChecksum, got %v", err)
+	}
+
+	w, err := NewWriter(&bytes.Buffer{}, CompressionGzip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Write(1, []byte("1")); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Write(3, []byte("3")); err != ErrOrder {
+		t.Errorf("want ErrOrder, got %v", err)
+	}
+	if _, err = NewWriter(&bytes.Buffer{}, "unknown"); err == nil {
+		t.Error("unknown compression has to be rejected")
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package blockarchive
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"sync"
+)
+
+const (
+	// CompressionNone is the archive without compression
+	CompressionNone = "none"
+	// CompressionGzip is the archive compressed by gzip
+	CompressionGzip = "gzip"
+)
+
+// Compression describes the compression of frames. The code is written to the header of the archive.
+type Compression struct {
+	Code      byte
+	Name      string
+	NewWriter func(w io.Writer) (io.WriteCloser, error)
+	NewReader func(r io.Reader) (io.ReadCloser, error)
+}
+
+var (
+	compressionMutex sync.RWMutex
+	compressions     = make(map[string]*Compression)
+)
+
+// RegisterCompression adds the compression which can be used in archives
+func RegisterCompression(c *Compression) error {
+	compressionMutex.Lock()
+	defer compressionMutex.Unlock()
+	for _, item := range compressions {
+		if item.Code == c.Code || item.Name == c.Name {
+			return fmt.Errorf("compression %s (%d) has already been registered", c.Name, c.Code)
+		}
+	}
+	compressions[c.Name] = c
+	return nil
+}
+
+// Compressions returns the names of the registered compressions
+func Compressions() []string {
+	compressionMutex.RLock()
+	defer compressionMutex.RUnlock()
+	names := make([]string, 0, len(compressions))
+	for name := range compressions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func compressionByName(name string) (*Compression, error) {
+	compressionMutex.RLock()
+	defer compressionMutex.RUnlock()
+	if c, ok := compressions[name]; ok {
+		return c, nil
+	}
+	return nil, fmt.Errorf("unknown compression %s", name)
+}
+
+func compressionByCode(code byte) (*Compression, error) {
+	compressionMutex.RLock()
+	defer compressionMutex.RUnlock()
+	for _, c := range compressions {
+		if c.Code == code {
+			return c, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown compression %d", code)
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+func init() {
+	RegisterCompression(&Compression{
+		Code: 0,
+		Name: CompressionNone,
+		NewWriter: func(w io.Writer) (io.WriteCloser, error) {
+			return nopWriteCloser{w}, nil
+		},
+		NewReader: func(r io.Reader) (io.ReadCloser, error) {
+			return ioutil.NopCloser(r), nil
+		},
+	})
+	RegisterCompression(&Compression{
+		Code: 1,
+		Name: CompressionGzip,
+		NewWriter: func(w io.Writer) (io.WriteCloser, error) {
+			return gzip.NewWriter(w), nil
+		},
+		NewReader: func(r io.Reader) (io.ReadCloser, error) {
+			return gzip.NewReader(r)
+		},
+	})
+}
@@ -73,11 +73,38 @@ type TokenMovementConfig struct {
 	Subject  string
 }
 
+// RateLimit is the token bucket of API requests, the limit is off if Rate is zero
+type RateLimit struct {
+	Rate  float64 // requests per second
+	Burst int     // the maximum number of requests at once
+}
+
+// APILimits are the limits of API requests by the remote IP, by the key of the token
+// and by the ecosystem of the token
+type APILimits struct {
+	Client    RateLimit
+	Key       RateLimit
+	Ecosystem RateLimit
+}
+
+// RateLimitConfig is the configuration of the limits of API requests
+type RateLimitConfig struct {
+	Default APILimits
+	Routes  map[string]APILimits // the key is the method and the pattern of the route, e.g. "GET list/:name"
+}
+
+// WebhooksConfig is the configuration of the webhook dispatcher, the dispatcher is off by default
+type WebhooksConfig struct {
+	Dispatch bool              // the node sends the webhooks which are assigned to its key
+	Secrets  map[string]string // the secrets of webhooks, the key is the secret name of the webhook
+}
+
 // GlobalConfig is storing all startup config as global struct
 type GlobalConfig struct {
 	KeyID        int64  `toml:"-"`
 	ConfigPath   string `toml:"-"`
 	TestRollBack bool   `toml:"-"`
+	FastSync     bool   `toml:"-"` // the state is imported from the snapshot of other nodes
 
 	PrivateBlockchain bool
 	PidFilePath       string
@@ -86,10 +113,12 @@ type GlobalConfig struct {
 	KeysDir           string // place for private keys files: NodePrivateKey, PrivateKey
 	TempDir           string // temporary dir
 	FirstBlockPath    string
+	ContractsCacheDir string // place for compiled contracts, the cache is off if it is empty
 	TLS               bool   // TLS is on/off. It is required for https
 	TLSCert           string // TLSCert is a filepath of the fullchain of certificate.
 	TLSKey            string // TLSKey is a filepath of the private key.
 	RunningMode       string
+	TCPSecurity       string // off, prefer or require, see TCPSecurityMode
 
 	MaxPageGenerationTime int64 // in milliseconds
 
@@ -101,6 +130,8 @@ type GlobalConfig struct {
 	Centrifugo    CentrifugoConfig
 	Log           LogConfig
 	TokenMovement TokenMovementConfig
+	RateLimit     RateLimitConfig
+	Webhooks      WebhooksConfig
 
 	NodesAddr []string
 }
@@ -207,6 +238,10 @@ func FillRuntimePaths() error {
 		Config.FirstBlockPath = filepath.Join(Config.DataDir, consts.FirstBlockFilename)
 	}
 
+	if Config.ContractsCacheDir == "" {
+		Config.ContractsCacheDir = filepath.Join(Config.DataDir, consts.DefaultContractsCacheDirName)
+	}
+
 	if Config.PidFilePath == "" {
 		Config.PidFilePath = filepath.Join(Config.DataDir, consts.DefaultPidFilename)
 	}
@@ -265,3 +300,11 @@ func (c GlobalConfig) IsVDEMaster() bool {
 func (c GlobalConfig) IsSupportingVDE() bool {
 	return RunMode(c.RunningMode).IsSupportingVDE()
 }
+
+// TCPSecurityMode returns the mode of the secure channel, the secure channel is preferred by default
+func (c GlobalConfig) TCPSecurityMode() TCPSecurity {
+	if len(c.TCPSecurity) == 0 {
+		return TCPSecurityPrefer
+	}
+	return TCPSecurity(c.TCPSecurity)
+}
@@ -17,6 +17,7 @@
 package syspar
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -78,6 +79,8 @@ const (
 	NodeBanTime = `node_ban_time`
 	// LocalNodeBanTime is value of local ban time for bad nodes (in ms)
 	LocalNodeBanTime = `local_node_ban_time`
+	// SnapshotInterval is the number of blocks between the snapshots of the state, zero disables them
+	SnapshotInterval = `snapshot_interval`
 )
 
 var (
@@ -241,6 +244,18 @@ func GetNodeByHost(host string) (FullNode, error) {
 	return FullNode{}, fmt.Errorf("incorrect host")
 }
 
+// IsNodePublicKey returns true if the public key belongs to the full node
+func IsNodePublicKey(publicKey []byte) bool {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	for _, n := range nodesByPosition {
+		if bytes.Equal(n.PublicKey, publicKey) {
+			return true
+		}
+	}
+	return false
+}
+
 // GetNodeHostByPosition is retrieving node host by position
 func GetNodeHostByPosition(position int64) (string, error) {
 	mutex.RLock()
@@ -415,6 +430,11 @@ func GetLocalNodeBanTime() time.Duration {
 	return time.Millisecond * time.Duration(converter.StrToInt64(SysString(LocalNodeBanTime)))
 }
 
+// GetSnapshotInterval returns the number of blocks between the snapshots of the state
+func GetSnapshotInterval() int64 {
+	return SysInt64(SnapshotInterval)
+}
+
 // GetRemoteHosts returns array of hostnames excluding myself
 func GetRemoteHosts() []string {
 	ret := make([]string, 0)
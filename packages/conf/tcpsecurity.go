@@ -0,0 +1,23 @@
+package conf
+
+// TCPSecurity is the mode of the secure channel between nodes
+type TCPSecurity string
+
+// TCPSecurityOff const label for plain connections only
+const TCPSecurityOff TCPSecurity = "off"
+
+// TCPSecurityPrefer const label for secure connections with the fallback to plain ones
+const TCPSecurityPrefer TCPSecurity = "prefer"
+
+// TCPSecurityRequire const label for secure connections only
+const TCPSecurityRequire TCPSecurity = "require"
+
+// IsOff returns true if the secure channel isn't used
+func (ts TCPSecurity) IsOff() bool {
+	return ts == TCPSecurityOff
+}
+
+// IsRequired returns true if the node requests have to be sent by the secure channel
+func (ts TCPSecurity) IsRequired() bool {
+	return ts == TCPSecurityRequire
+}
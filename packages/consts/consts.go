@@ -21,10 +21,19 @@ import (
 )
 
 // VERSION is current version
-const VERSION = "0.1.6b13"
+const VERSION = "0.1.6b23"
 
 // BLOCK_VERSION is block version
-const BLOCK_VERSION = 1
+const BLOCK_VERSION = 4
+
+// BlockVersionAPIKeys is the first version of blocks which add API keys to the new ecosystems
+const BlockVersionAPIKeys = 2
+
+// BlockVersionWebhooks is the first version of blocks which add webhooks to the new ecosystems
+const BlockVersionWebhooks = 3
+
+// BlockVersionSnapshots is the first version of blocks which add the state snapshots to the first ecosystem
+const BlockVersionSnapshots = 4
 
 // NETWORK_ID is id of network
 const NETWORK_ID = 1
@@ -71,6 +80,12 @@ const DATA_TYPE_MAX_BLOCK_ID = 10
 // DATA_TYPE_BLOCK_BODY is body block datatype
 const DATA_TYPE_BLOCK_BODY = 7
 
+// DATA_TYPE_SECURE is the handshake of the secure channel between nodes
+const DATA_TYPE_SECURE = 100
+
+// DATA_TYPE_HELLO is the negotiation of the protocol version and capabilities between nodes
+const DATA_TYPE_HELLO = 101
+
 // UPD_AND_VER_URL is root url
 const UPD_AND_VER_URL = "http://apla.io"
 
@@ -157,9 +172,18 @@ const TCPConnTimeout = 5 * time.Second
 // TxRequestExpire is expiration time for request of transaction
 const TxRequestExpire = 1 * time.Minute
 
+// TxErrorSize is the maximum length of the error text of the transaction status
+const TxErrorSize = 1024
+
+// AsOfBlockDepth is the maximum number of blocks which as_of_block queries can go back
+const AsOfBlockDepth = 10000
+
 // DefaultTempDirName is default name of temporary directory
 const DefaultTempDirName = "apla-temp"
 
+// DefaultContractsCacheDirName is default name of directory for compiled contracts
+const DefaultContractsCacheDirName = "contracts-cache"
+
 // DefaultVDE allways is 1
 const DefaultVDE = 1
 
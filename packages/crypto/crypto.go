@@ -19,7 +19,7 @@ import (
 )
 
 // TODO In order to add new crypto provider with another key length it will be neccecary to fix constant blocksizes like
-// crypto func getSharedKey() pub.X = new(big.Int).SetBytes(public[0:32])
+// crypto func GetSharedKey() pub.X = new(big.Int).SetBytes(public[0:32])
 // egcons func checkKey() gSettings.Key = hex.EncodeToString(privKey[aes.BlockSize:])
 
 type cryptoProvider int
@@ -104,7 +104,7 @@ func SharedEncrypt(public, text []byte) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	shared, err := getSharedKey(priv, public)
+	shared, err := GetSharedKey(priv, public)
 	if err != nil {
 		return nil, err
 	}
@@ -207,7 +207,7 @@ func _PKCS7UnPadding(src []byte) ([]byte, error) {
 
 // GetSharedKey creates and returns the shared key = private * public.
 // public must be the public key from the different private key.
-func getSharedKey(private, public []byte) (shared []byte, err error) {
+func GetSharedKey(private, public []byte) (shared []byte, err error) {
 	var pubkeyCurve elliptic.Curve
 	switch ellipticSize {
 	case elliptic256:
@@ -75,6 +75,7 @@ func signECDSA(privateKey string, data string) (ret []byte, err error) {
 	priv := new(ecdsa.PrivateKey)
 	priv.PublicKey.Curve = pubkeyCurve
 	priv.D = bi
+	priv.PublicKey.X, priv.PublicKey.Y = pubkeyCurve.ScalarBaseMult(bi.Bytes())
 
 	signhash, err := Hash([]byte(data))
 	if err != nil {
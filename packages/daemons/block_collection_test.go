@@ -4,15 +4,12 @@ import (
 	"context"
 	"database/sql"
 	"net"
-	"os"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/jinzhu/gorm"
 
-	"io/ioutil"
-
 	"github.com/AplaProject/go-apla/packages/consts"
 	"github.com/AplaProject/go-apla/packages/converter"
 	"github.com/AplaProject/go-apla/packages/model"
@@ -136,21 +133,3 @@ func TestFirstBlock(t *testing.T) {
 	checkInfoBlock(t, 1)
 
 }
-
-func TestLoadFromFile(t *testing.T) {
-	g := initGorm(t)
-	defer g.Close()
-
-	fileName := getTmpFile(t)
-	defer os.Remove(fileName)
-	fileBlockBin := marshallFileBlock(getFirstBlock(t))
-	err := ioutil.WriteFile(fileName, fileBlockBin, os.ModeAppend)
-	if err != nil {
-		t.Fatalf("can't write to file: %s", err)
-	}
-
-	err = loadFromFile(context.Background(), fileName)
-	if err != nil {
-		t.Fatalf("load from file return error: %s", err)
-	}
-}
@@ -66,6 +66,13 @@ func InitialLoad(logger *log.Entry) error {
 		if err := firstLoad(logger); err != nil {
 			return err
 		}
+
+		if conf.Config.FastSync {
+			// the blocks after the snapshot are played as usual, all blocks are played if there is no snapshot
+			if err := fastSync(logger); err != nil {
+				logger.WithFields(log.Fields{"type": consts.SyncProcess, "error": err}).Error("fast synchronization")
+			}
+		}
 	}
 
 	return nil
@@ -148,7 +155,7 @@ func UpdateChain(ctx context.Context, d *daemon, host string, maxBlockID int64)
 		return ctx.Err()
 	}
 
-	playRawBlock := func(rawBlocksQueueCh chan []byte) error {
+	playRawBlock := func(rawBlocksQueueCh chan []byte, host string) error {
 		for rb := range rawBlocksQueueCh {
 			b, err := block.ProcessBlockWherePrevFromBlockchainTable(rb, true)
 			if err != nil {
@@ -197,15 +204,34 @@ func UpdateChain(ctx context.Context, d *daemon, host string, maxBlockID int64)
 
 	count := 0
 	var err error
+	// the blocks are downloaded from several nodes if the host supports the header chain
+	peers := downloadPeers(host, maxBlockID, d.logger)
+	parallel := true
 	for blockID := curBlock.BlockID + 1; blockID <= maxBlockID; blockID += int64(tcpserver.BlocksPerRequest) {
-		var rawBlocksChan chan []byte
-		rawBlocksChan, err = utils.GetBlocksBody(host, blockID, tcpserver.BlocksPerRequest, consts.DATA_TYPE_BLOCK_BODY, false)
+		var (
+			rawBlocksChan chan []byte
+			source        string
+		)
+		err = errHashesUnsupported
+		if parallel {
+			rawBlocksChan, source, err = downloadBlocksChan(ctx, peers, blockID, maxBlockID, d.logger)
+			if err == errHashesUnsupported {
+				parallel = false
+			} else if err != nil {
+				d.logger.WithFields(log.Fields{"error": err, "type": consts.BlockError}).Warning("downloading blocks from several nodes")
+			}
+		}
+		// the blocks are downloaded from the host if they haven't been downloaded from several nodes
+		if err != nil {
+			source = host
+			rawBlocksChan, err = utils.GetBlocksBody(host, blockID, tcpserver.BlocksPerRequest, consts.DATA_TYPE_BLOCK_BODY, false)
+		}
 		if err != nil {
 			d.logger.WithFields(log.Fields{"error": err, "type": consts.BlockError}).Error("getting block body")
 			break
 		}
 
-		err = playRawBlock(rawBlocksChan)
+		err = playRawBlock(rawBlocksChan, source)
 		if err != nil {
 			d.logger.WithFields(log.Fields{"error": err, "type": consts.BlockError}).Error("playing raw block")
 			break
@@ -332,6 +358,9 @@ func GetBlocks(blockID int64, host string) error {
 
 	// we have the slice of blocks for applying
 	// first of all we should rollback old blocks
+	if err = rollback.CheckBlockID(blockID - 1); err != nil {
+		return utils.ErrInfo(err)
+	}
 	block := &model.Block{}
 	myRollbackBlocks, err := block.GetBlocksFrom(blockID-1, "desc", 0)
 	if err != nil {
@@ -490,5 +519,13 @@ func processBlocks(blocks []*block.Block) error {
 		}
 	}
 
-	return dbTransaction.Commit()
+	if err = dbTransaction.Commit(); err != nil {
+		return err
+	}
+	go func() {
+		for i := len(blocks) - 1; i >= 0; i-- {
+			blocks[i].PublishEvents()
+		}
+	}()
+	return nil
 }
@@ -0,0 +1,342 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package daemons
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/AplaProject/go-apla/packages/block"
+	"github.com/AplaProject/go-apla/packages/conf/syspar"
+	"github.com/AplaProject/go-apla/packages/consts"
+	"github.com/AplaProject/go-apla/packages/tcpserver"
+	"github.com/AplaProject/go-apla/packages/utils"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	downloadRangeSize   = 100 // the number of blocks per request to one node
+	downloadMaxPeers    = 5   // the maximum number of nodes which blocks are downloaded from
+	downloadMaxFailures = 3   // the node isn't used after these failures
+	downloadMaxAttempts = 5   // the maximum number of attempts to download the range
+)
+
+var (
+	errHashesUnsupported = errors.New("node doesn't support the hashes of blocks")
+	errBadBody           = errors.New("block doesn't match the header chain")
+	errBadSign           = errors.New("block isn't signed by the full node")
+	errForkedChain       = errors.New("header chain doesn't match our blockchain")
+)
+
+// downloadPeer is the node which blocks are downloaded from
+type downloadPeer struct {
+	host     string
+	maxBlock int64
+	score    int64 // it is increased for every downloaded range and decreased for every failure
+	failures int
+}
+
+type blockRange struct {
+	from, count int64
+	attempts    int
+	failed      map[string]bool // the hosts which have failed this range
+}
+
+// downloadPeers returns the host with the max block and other unbanned nodes with their max blocks
+func downloadPeers(host string, maxBlockID int64, logger *log.Entry) []*downloadPeer {
+	peers := []*downloadPeer{{host: host, maxBlock: maxBlockID}}
+	hosts, err := filterBannedHosts(syspar.GetRemoteHosts())
+	if err != nil {
+		return peers
+	}
+	utils.ShuffleSlice(hosts)
+	candidates := make([]string, 0, downloadMaxPeers-1)
+	for _, h := range hosts {
+		if h = utils.GetHostPort(h); h != host && len(candidates) < downloadMaxPeers-1 {
+			candidates = append(candidates, h)
+		}
+	}
+	result := make([]*downloadPeer, len(candidates))
+	var wg sync.WaitGroup
+	for i, h := range candidates {
+		wg.Add(1)
+		go func(i int, h string) {
+			defer wg.Done()
+			if blockID, err := utils.GetHostBlockID(h, logger); err == nil {
+				result[i] = &downloadPeer{host: h, maxBlock: blockID}
+			}
+		}(i, h)
+	}
+	wg.Wait()
+	for _, p := range result {
+		if p != nil {
+			peers = append(peers, p)
+		}
+	}
+	return peers
+}
+
+// getHeaderChain returns the hashes of blocks from fromID-1 to toID
+func getHeaderChain(host string, fromID, toID int64) ([][]byte, error) {
+	peer, err := tcpserver.Connect(host)
+	if err != nil {
+		return nil, err
+	}
+	defer peer.Close()
+	if !peer.HasRequest(tcpserver.RequestTypeBlockHashes) {
+		return nil, errHashesUnsupported
+	}
+	if err = tcpserver.SendRequestType(tcpserver.RequestTypeBlockHashes, peer); err != nil {
+		return nil, err
+	}
+	count := toID - fromID + 2
+	if err = peer.SendRequest(&tcpserver.GetHashesRequest{BlockID: uint32(fromID - 1), Count: uint32(count)}); err != nil {
+		return nil, err
+	}
+	resp := &tcpserver.GetHashesResponse{}
+	if err = peer.ReadRequest(resp); err != nil {
+		return nil, err
+	}
+	if int64(len(resp.Hashes)) != count*consts.HashSize {
+		return nil, fmt.Errorf("wrong number of hashes %d, want %d", len(resp.Hashes)/consts.HashSize, count)
+	}
+	hashes := make([][]byte, count)
+	for i := range hashes {
+		hashes[i] = resp.Hashes[i*consts.HashSize : (i+1)*consts.HashSize]
+	}
+	return hashes, nil
+}
+
+// checkHeaderChain checks that the header chain starts with our last block and ends with the block
+// which is signed by the full node. The blocks between them are checked by the hashes when they are downloaded.
+func checkHeaderChain(host string, hashes [][]byte, fromID, toID int64) error {
+	prev, err := block.GetBlockDataFromBlockChain(fromID - 1)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(prev.Hash, hashes[0]) {
+		return errForkedChain
+	}
+	_, err = fetchRange(host, &blockRange{from: toID, count: 1}, fromID, hashes)
+	return err
+}
+
+// headerChain returns the checked header chain of the first node which can send it. The nodes which
+// have failed are skipped. It returns errHashesUnsupported if no node supports the header chain.
+func headerChain(peers []*downloadPeer, fromID, toID int64, logger *log.Entry) (string, [][]byte, error) {
+	var lastErr error
+	for _, p := range peers {
+		if p.maxBlock < toID {
+			continue
+		}
+		hashes, err := getHeaderChain(p.host, fromID, toID)
+		if err == nil {
+			err = checkHeaderChain(p.host, hashes, fromID, toID)
+		}
+		if err == nil {
+			return p.host, hashes, nil
+		}
+		if err != errHashesUnsupported {
+			lastErr = err
+			p.failures = downloadMaxFailures
+		}
+		logger.WithFields(log.Fields{"type": consts.BlockError, "error": err, "host": p.host}).Warning("getting header chain")
+	}
+	if lastErr == nil {
+		lastErr = errHashesUnsupported
+	}
+	return ``, nil, lastErr
+}
+
+// checkBlockBody compares the block with the hash from the header chain and checks the signature of the block
+func checkBlockBody(data []byte, blockID int64, prevHash, hash []byte) error {
+	b, err := block.UnmarshallBlock(bytes.NewBuffer(data), false)
+	if err != nil {
+		return errBadBody
+	}
+	if b.Header.BlockID != blockID {
+		return errBadBody
+	}
+	calc, err := b.CalcHash(prevHash)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(calc, hash) {
+		return errBadBody
+	}
+	b.PrevHeader = &utils.BlockData{BlockID: blockID - 1, Hash: prevHash}
+	if signed, err := b.CheckHash(); err != nil || !signed {
+		return errBadSign
+	}
+	return nil
+}
+
+// fetchRange downloads the range of blocks from the node, hashes[0] is the hash of the block fromID-1
+func fetchRange(host string, r *blockRange, fromID int64, hashes [][]byte) ([][]byte, error) {
+	peer, err := tcpserver.Connect(host)
+	if err != nil {
+		return nil, err
+	}
+	defer peer.Close()
+	if err = tcpserver.SendRequestType(tcpserver.RequestTypeBlockCollection, peer); err != nil {
+		return nil, err
+	}
+	// the legacy node ignores Count and sends BlocksPerRequest blocks, the rest is skipped
+	if err = peer.SendRequest(&tcpserver.GetBodiesRequest{BlockID: uint32(r.from), Count: uint32(r.count)}); err != nil {
+		return nil, err
+	}
+	bodies := make([][]byte, 0, r.count)
+	for blockID := r.from; blockID < r.from+r.count; blockID++ {
+		resp := &tcpserver.GetBodyResponse{}
+		if err = peer.ReadRequest(resp); err != nil {
+			return nil, err
+		}
+		off := blockID - fromID + 1
+		if err = checkBlockBody(resp.Data, blockID, hashes[off-1], hashes[off]); err != nil {
+			return nil, err
+		}
+		bodies = append(bodies, resp.Data)
+	}
+	return bodies, nil
+}
+
+// downloadBlocks downloads the blocks from fromID to toID from several nodes in parallel. The blocks
+// are checked by the header chain of the first node which can send it, the host of this node is returned.
+// The nodes aren't banned here, the host is banned if the downloaded blocks don't pass the validation.
+func downloadBlocks(ctx context.Context, peers []*downloadPeer, fromID, toID int64, logger *log.Entry) (string, [][]byte, error) {
+	host, hashes, err := headerChain(peers, fromID, toID, logger)
+	if err != nil {
+		return ``, nil, err
+	}
+	pending := make([]*blockRange, 0)
+	for from := fromID; from <= toID; from += downloadRangeSize {
+		count := int64(downloadRangeSize)
+		if from+count-1 > toID {
+			count = toID - from + 1
+		}
+		pending = append(pending, &blockRange{from: from, count: count, failed: make(map[string]bool)})
+	}
+	bodies := make([][]byte, toID-fromID+1)
+	for len(pending) > 0 {
+		if ctx.Err() != nil {
+			return ``, nil, ctx.Err()
+		}
+		active := make([]*downloadPeer, 0, len(peers))
+		for _, p := range peers {
+			if p.failures < downloadMaxFailures {
+				active = append(active, p)
+			}
+		}
+		sort.SliceStable(active, func(i, j int) bool { return active[i].score > active[j].score })
+
+		// the ranges are distributed by turns starting with the best nodes
+		tasks := make(map[*downloadPeer][]*blockRange)
+		next := 0
+		for _, r := range pending {
+			var assigned bool
+			// the range is retried on the failed node only if other nodes can't download it
+			for _, skipFailed := range []bool{true, false} {
+				for i := 0; i < len(active) && !assigned; i++ {
+					p := active[(next+i)%len(active)]
+					if p.maxBlock >= r.from+r.count-1 && !(skipFailed && r.failed[p.host]) {
+						tasks[p] = append(tasks[p], r)
+						next = (next + i + 1) % len(active)
+						assigned = true
+					}
+				}
+			}
+			if !assigned {
+				return ``, nil, fmt.Errorf("there are no nodes to download blocks from %d", r.from)
+			}
+		}
+
+		var (
+			wg     sync.WaitGroup
+			mutex  sync.Mutex
+			failed []*blockRange
+		)
+		for p, ranges := range tasks {
+			wg.Add(1)
+			go func(p *downloadPeer, ranges []*blockRange) {
+				defer wg.Done()
+				for i, r := range ranges {
+					data, err := fetchRange(p.host, r, fromID, hashes)
+					mutex.Lock()
+					if err == nil {
+						copy(bodies[r.from-fromID:], data)
+						p.score++
+						mutex.Unlock()
+						continue
+					}
+					logger.WithFields(log.Fields{"type": consts.BlockError, "error": err, "host": p.host, "block_id": r.from}).Warning("downloading blocks")
+					p.score--
+					p.failures++
+					// the node isn't used in this round if it sends the wrong blocks
+					if err == errBadBody || err == errBadSign {
+						p.failures = downloadMaxFailures
+					}
+					r.attempts++
+					r.failed[p.host] = true
+					failed = append(failed, r)
+					// the rest ranges are downloaded from other nodes
+					if p.failures >= downloadMaxFailures {
+						failed = append(failed, ranges[i+1:]...)
+						mutex.Unlock()
+						return
+					}
+					mutex.Unlock()
+				}
+			}(p, ranges)
+		}
+		wg.Wait()
+
+		for _, r := range failed {
+			if r.attempts >= downloadMaxAttempts {
+				return ``, nil, fmt.Errorf("blocks from %d have not been downloaded", r.from)
+			}
+		}
+		pending = failed
+	}
+	for _, p := range peers {
+		logger.WithFields(log.Fields{"host": p.host, "score": p.score, "failures": p.failures}).Debug("node score of downloading blocks")
+	}
+	return host, bodies, nil
+}
+
+// downloadBlocksChan downloads BlocksPerRequest blocks from blockID and puts them in the channel. It returns
+// the host of the header chain which the blocks have been checked by.
+func downloadBlocksChan(ctx context.Context, peers []*downloadPeer, blockID, maxBlockID int64, logger *log.Entry) (chan []byte, string, error) {
+	toID := blockID + int64(tcpserver.BlocksPerRequest) - 1
+	if toID > maxBlockID {
+		toID = maxBlockID
+	}
+	host, bodies, err := downloadBlocks(ctx, peers, blockID, toID, logger)
+	if err != nil {
+		return nil, ``, err
+	}
+	rawBlocksCh := make(chan []byte, len(bodies))
+	for _, body := range bodies {
+		rawBlocksCh <- body
+	}
+	close(rawBlocksCh)
+	return rawBlocksCh, host, nil
+}
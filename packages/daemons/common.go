@@ -51,6 +51,7 @@ var daemonsList = map[string]func(context.Context, *daemon) error{
 	"Confirmations":     Confirmations,
 	"Notificator":       Notificate,
 	"Scheduler":         Scheduler,
+	"WebhookDispatcher": WebhookDispatcher,
 }
 
 var serverList = []string{
@@ -62,6 +63,7 @@ var serverList = []string{
 	"Confirmations",
 	"Notificator",
 	"Scheduler",
+	"WebhookDispatcher",
 }
 
 var rollbackList = []string{
@@ -165,5 +167,12 @@ func getDaemonsToStart() []string {
 		}
 	}
 
-	return serverList
+	daemons := make([]string, 0, len(serverList))
+	for _, name := range serverList {
+		if name == "WebhookDispatcher" && !conf.Config.Webhooks.Dispatch {
+			continue
+		}
+		daemons = append(daemons, name)
+	}
+	return daemons
 }
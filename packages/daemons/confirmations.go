@@ -149,17 +149,14 @@ func confirmationsBlocks(ctx context.Context, d *daemon, lastBlockID, startBlock
 }
 
 func checkConf(host string, blockID int64, logger *log.Entry) string {
-	conn, err := net.DialTimeout("tcp", host, 5*time.Second)
+	peer, err := tcpserver.Connect(host)
 	if err != nil {
 		logger.WithFields(log.Fields{"type": consts.ConnectionError, "error": err, "host": host, "block_id": blockID}).Debug("dialing to host")
 		return "0"
 	}
-	defer conn.Close()
+	defer peer.Close()
 
-	conn.SetReadDeadline(time.Now().Add(consts.READ_TIMEOUT * time.Second))
-	conn.SetWriteDeadline(time.Now().Add(consts.WRITE_TIMEOUT * time.Second))
-
-	if err = tcpserver.SendRequestType(tcpserver.RequestTypeConfirmation, conn); err != nil {
+	if err = tcpserver.SendRequestType(tcpserver.RequestTypeConfirmation, peer); err != nil {
 		logger.WithFields(log.Fields{"type": consts.IOError, "error": err, "host": host, "block_id": blockID}).Error("sending request type")
 		return "0"
 	}
@@ -167,13 +164,13 @@ func checkConf(host string, blockID int64, logger *log.Entry) string {
 	req := &tcpserver.ConfirmRequest{
 		BlockID: uint32(blockID),
 	}
-	if err = tcpserver.SendRequest(req, conn); err != nil {
+	if err = peer.SendRequest(req); err != nil {
 		logger.WithFields(log.Fields{"type": consts.IOError, "error": err, "host": host, "block_id": blockID}).Error("sending confirmation request")
 		return "0"
 	}
 
 	resp := &tcpserver.ConfirmResponse{}
-	err = tcpserver.ReadRequest(resp, conn)
+	err = peer.ReadRequest(resp)
 	if err != nil {
 		logger.WithFields(log.Fields{"type": consts.IOError, "error": err, "host": host, "block_id": blockID}).Error("receiving confirmation response")
 		return "0"
@@ -0,0 +1,390 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package daemons
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AplaProject/go-apla/packages/block"
+	"github.com/AplaProject/go-apla/packages/conf"
+	"github.com/AplaProject/go-apla/packages/conf/syspar"
+	"github.com/AplaProject/go-apla/packages/consts"
+	"github.com/AplaProject/go-apla/packages/converter"
+	"github.com/AplaProject/go-apla/packages/model"
+	"github.com/AplaProject/go-apla/packages/snapshot"
+	"github.com/AplaProject/go-apla/packages/tcpserver"
+	"github.com/AplaProject/go-apla/packages/utils"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	errSnapshotUnsupported = errors.New("node doesn't support snapshots")
+	errSnapshotNotFound    = errors.New("there is no snapshot confirmed by the quorum of full nodes")
+	errSnapshotProof       = errors.New("hash of snapshot isn't committed by the signed block")
+	errHeaderChainQuorum   = errors.New("header chain isn't confirmed by the quorum of full nodes")
+)
+
+const (
+	newSnapshotContract = "@1NewSnapshot"
+	headerChainPart     = tcpserver.HashesPerRequest - 1 // the number of blocks per request of the header chain
+)
+
+// snapshotOffer is the answer of the node about the snapshot
+type snapshotOffer struct {
+	host        string
+	blockID     int64
+	manifest    []byte
+	commitBlock []byte // the block which contains the transaction of the snapshot
+}
+
+func requestSnapshot(host string, blockID int64, chunk int) (*tcpserver.SnapshotResponse, error) {
+	peer, err := tcpserver.Connect(host)
+	if err != nil {
+		return nil, err
+	}
+	defer peer.Close()
+	if !peer.HasRequest(tcpserver.RequestTypeSnapshot) {
+		return nil, errSnapshotUnsupported
+	}
+	if err = tcpserver.SendRequestType(tcpserver.RequestTypeSnapshot, peer); err != nil {
+		return nil, err
+	}
+	if err = peer.SendRequest(&tcpserver.SnapshotRequest{BlockID: uint32(blockID), Chunk: uint32(chunk)}); err != nil {
+		return nil, err
+	}
+	resp := &tcpserver.SnapshotResponse{}
+	if err = peer.ReadRequest(resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// requestOffers asks all nodes about the snapshot of the block, zero is the latest snapshot
+func requestOffers(hosts []string, blockID int64, logger *log.Entry) []*snapshotOffer {
+	result := make([]*snapshotOffer, len(hosts))
+	var wg sync.WaitGroup
+	for i, host := range hosts {
+		wg.Add(1)
+		go func(i int, host string) {
+			defer wg.Done()
+			resp, err := requestSnapshot(host, blockID, 0)
+			if err != nil {
+				logger.WithFields(log.Fields{"type": consts.NetworkError, "error": err, "host": host}).Warning("requesting snapshot")
+				return
+			}
+			result[i] = &snapshotOffer{host: host, blockID: int64(resp.BlockID), manifest: resp.Data,
+				commitBlock: resp.CommitBlock}
+		}(i, host)
+	}
+	wg.Wait()
+	offers := make([]*snapshotOffer, 0, len(hosts))
+	for _, offer := range result {
+		if offer != nil {
+			offers = append(offers, offer)
+		}
+	}
+	return offers
+}
+
+// fullNodeHosts returns the hosts of the full nodes except our node
+func fullNodeHosts() map[string]bool {
+	hosts := make(map[string]bool)
+	for _, host := range syspar.GetRemoteHosts() {
+		hosts[utils.GetHostPort(host)] = true
+	}
+	return hosts
+}
+
+// snapshotQuorum returns the number of the full nodes which must confirm the snapshot and the header chain
+func snapshotQuorum(fullNodes map[string]bool) int {
+	return len(fullNodes)/2 + 1
+}
+
+// fetchHeaderChain downloads the header chain from the block fromID-1 to toID by parts. It returns the digest
+// of the chain and the hashes of the blocks toID-1 and toID. The chain must start with the hash of our block fromID-1.
+func fetchHeaderChain(host string, fromID, toID int64, first []byte) (digest, prevHash, hash []byte, err error) {
+	h := sha256.New()
+	hash = first
+	for from := fromID; from <= toID; from += headerChainPart {
+		to := from + headerChainPart - 1
+		if to > toID {
+			to = toID
+		}
+		hashes, err := getHeaderChain(host, from, to)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if !bytes.Equal(hashes[0], hash) {
+			return nil, nil, nil, errForkedChain
+		}
+		for _, item := range hashes[1:] {
+			h.Write(item)
+		}
+		prevHash, hash = hashes[len(hashes)-2], hashes[len(hashes)-1]
+	}
+	return h.Sum(nil), prevHash, hash, nil
+}
+
+// commitHeader returns the hashes of the block toID-1 and toID by the header chain which starts with our last block.
+// The same header chain must be sent by the quorum of full nodes.
+func commitHeader(fullNodes map[string]bool, toID int64, logger *log.Entry) ([]byte, []byte, error) {
+	last := &model.Block{}
+	if _, err := last.GetMaxBlock(); err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting max block")
+		return nil, nil, err
+	}
+	if last.ID >= toID {
+		return nil, nil, errForkedChain
+	}
+	type header struct {
+		digest, prevHash, hash []byte
+	}
+	hosts := make([]string, 0, len(fullNodes))
+	for host := range fullNodes {
+		hosts = append(hosts, host)
+	}
+	result := make([]*header, len(hosts))
+	var wg sync.WaitGroup
+	for i, host := range hosts {
+		wg.Add(1)
+		go func(i int, host string) {
+			defer wg.Done()
+			digest, prevHash, hash, err := fetchHeaderChain(host, last.ID+1, toID, last.Hash)
+			if err != nil {
+				logger.WithFields(log.Fields{"type": consts.BlockError, "error": err, "host": host}).Warning("getting header chain")
+				return
+			}
+			result[i] = &header{digest: digest, prevHash: prevHash, hash: hash}
+		}(i, host)
+	}
+	wg.Wait()
+	votes := make(map[string]int)
+	for _, item := range result {
+		if item == nil {
+			continue
+		}
+		key := string(item.digest)
+		if votes[key]++; votes[key] >= snapshotQuorum(fullNodes) {
+			return item.prevHash, item.hash, nil
+		}
+	}
+	return nil, nil, errHeaderChainQuorum
+}
+
+// checkSnapshotProof checks that the hash of the snapshot has been committed by the transaction NewSnapshot
+// of the block which is signed by the full node and matches the header chain of the quorum of full nodes
+func checkSnapshotProof(m *snapshot.Manifest, hash []byte, commitBlock []byte, fullNodes map[string]bool,
+	logger *log.Entry) error {
+	if len(commitBlock) == 0 {
+		return errSnapshotProof
+	}
+	b, err := block.UnmarshallBlock(bytes.NewBuffer(commitBlock), false)
+	if err != nil {
+		return err
+	}
+	if b.Header.BlockID <= m.BlockID {
+		return errSnapshotProof
+	}
+	hexHash := hex.EncodeToString(hash)
+	committed := false
+	for _, t := range b.Transactions {
+		if t.TxContract == nil || t.TxContract.Name != newSnapshotContract {
+			continue
+		}
+		if converter.StrToInt64(fmt.Sprint(t.TxData["BlockID"])) == m.BlockID &&
+			strings.ToLower(fmt.Sprint(t.TxData["Hash"])) == hexHash {
+			committed = true
+			break
+		}
+	}
+	if !committed {
+		return errSnapshotProof
+	}
+	prevHash, blockHash, err := commitHeader(fullNodes, b.Header.BlockID, logger)
+	if err != nil {
+		return err
+	}
+	calc, err := b.CalcHash(prevHash)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(calc, blockHash) {
+		return errBadBody
+	}
+	b.PrevHeader = &utils.BlockData{BlockID: b.Header.BlockID - 1, Hash: prevHash}
+	if signed, err := b.CheckHash(); err != nil || !signed {
+		return errSnapshotProof
+	}
+	return nil
+}
+
+// confirmSnapshot returns the manifest whose hash is sent by the quorum of full nodes and has been committed
+// by the block of the header chain which is confirmed by the quorum of full nodes, and the nodes which have
+// this snapshot
+func confirmSnapshot(offers []*snapshotOffer, fullNodes map[string]bool, logger *log.Entry) (*snapshot.Manifest, []string) {
+	manifests := make(map[string]*snapshot.Manifest)
+	sources := make(map[string][]*snapshotOffer)
+	votes := make(map[string]int)
+	var quorum string
+	for _, offer := range offers {
+		if len(offer.manifest) == 0 {
+			continue
+		}
+		m, err := snapshot.UnmarshalManifest(offer.manifest)
+		if err == nil {
+			err = m.Verify()
+		}
+		var hash []byte
+		if err == nil {
+			hash, err = m.Hash()
+		}
+		if err != nil {
+			logger.WithFields(log.Fields{"type": consts.InvalidObject, "error": err, "host": offer.host}).Warning("checking snapshot")
+			continue
+		}
+		key := string(hash)
+		if _, ok := manifests[key]; !ok {
+			manifests[key] = m
+		}
+		sources[key] = append(sources[key], offer)
+		if fullNodes[offer.host] {
+			if votes[key]++; votes[key] >= snapshotQuorum(fullNodes) {
+				quorum = key
+			}
+		}
+	}
+	if len(quorum) == 0 {
+		return nil, nil
+	}
+	m := manifests[quorum]
+	for _, offer := range sources[quorum] {
+		err := checkSnapshotProof(m, []byte(quorum), offer.commitBlock, fullNodes, logger)
+		if err == nil {
+			hosts := make([]string, 0, len(sources[quorum]))
+			for _, source := range sources[quorum] {
+				hosts = append(hosts, source.host)
+			}
+			return m, hosts
+		}
+		logger.WithFields(log.Fields{"type": consts.InvalidObject, "error": err, "host": offer.host}).Warning("checking proof of snapshot")
+	}
+	return nil, nil
+}
+
+// chooseSnapshot returns the latest snapshot which is confirmed by the quorum of full nodes
+func chooseSnapshot(hosts []string, logger *log.Entry) (*snapshot.Manifest, []string, error) {
+	fullNodes := fullNodeHosts()
+	offers := requestOffers(hosts, 0, logger)
+	// the hash of the latest snapshot can be not committed yet, so the previous one is tried too
+	candidates := make(map[int64]bool)
+	interval := syspar.GetSnapshotInterval()
+	for _, offer := range offers {
+		if offer.blockID > 0 {
+			candidates[offer.blockID] = true
+			if interval > 0 && offer.blockID > interval {
+				candidates[offer.blockID-interval] = true
+			}
+		}
+	}
+	blocks := make([]int64, 0, len(candidates))
+	for blockID := range candidates {
+		blocks = append(blocks, blockID)
+	}
+	sort.Slice(blocks, func(i, j int) bool { return blocks[i] > blocks[j] })
+	for _, blockID := range blocks {
+		if m, sources := confirmSnapshot(requestOffers(hosts, blockID, logger), fullNodes, logger); m != nil {
+			return m, sources, nil
+		}
+		logger.WithFields(log.Fields{"type": consts.NotFound, "block_id": blockID}).Warning("snapshot isn't confirmed by the quorum of full nodes")
+	}
+	return nil, nil, errSnapshotNotFound
+}
+
+// fetchChunk downloads the chunk from one of nodes which have the snapshot
+func fetchChunk(sources []string, m *snapshot.Manifest, index int, logger *log.Entry) ([]byte, error) {
+	for i := range sources {
+		host := sources[(index+i)%len(sources)]
+		resp, err := requestSnapshot(host, m.BlockID, index+1)
+		if err == nil {
+			if err = m.CheckChunk(index, resp.Data); err == nil {
+				return resp.Data, nil
+			}
+		}
+		logger.WithFields(log.Fields{"type": consts.NetworkError, "error": err, "host": host, "chunk": index}).Warning("downloading chunk of snapshot")
+	}
+	return nil, fmt.Errorf("chunk %d of snapshot %d has not been downloaded", index, m.BlockID)
+}
+
+// snapshotHosts returns the nodes from the system parameters and the config
+func snapshotHosts() []string {
+	hosts := make([]string, 0)
+	used := make(map[string]bool)
+	for _, host := range append(syspar.GetRemoteHosts(), conf.GetNodesAddr()...) {
+		host = utils.GetHostPort(host)
+		if !used[host] {
+			used[host] = true
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+
+// fastSync imports the latest snapshot of the state whose hash is sent by more than half of the full nodes
+// known by our state and is committed by the block of the header chain which is sent by more than half of
+// the full nodes too. The rest blocks are downloaded by BlocksCollection.
+func fastSync(logger *log.Entry) error {
+	if err := syspar.SysUpdate(nil); err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("updating syspar")
+		return err
+	}
+	hosts := snapshotHosts()
+	if len(hosts) == 0 {
+		return ErrNodesUnavailable
+	}
+
+	DBLock()
+	defer DBUnlock()
+
+	m, sources, err := chooseSnapshot(hosts, logger)
+	if err != nil {
+		return err
+	}
+	st := time.Now()
+	logger.WithFields(log.Fields{"block_id": m.BlockID, "chunks": len(m.Chunks), "nodes": len(sources)}).Info("importing snapshot")
+	err = snapshot.Import(m, func(index int) ([]byte, error) {
+		return fetchChunk(sources, m, index, logger)
+	})
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err, "block_id": m.BlockID}).Error("importing snapshot")
+		return err
+	}
+	if err = syspar.SysUpdate(nil); err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("updating syspar")
+		return err
+	}
+	logger.WithFields(log.Fields{"block_id": m.BlockID, "duration": time.Since(st).String()}).Info("snapshot has been imported")
+	return nil
+}
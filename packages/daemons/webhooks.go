@@ -0,0 +1,312 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package daemons
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AplaProject/go-apla/packages/block"
+	"github.com/AplaProject/go-apla/packages/conf"
+	"github.com/AplaProject/go-apla/packages/conf/syspar"
+	"github.com/AplaProject/go-apla/packages/consts"
+	"github.com/AplaProject/go-apla/packages/converter"
+	"github.com/AplaProject/go-apla/packages/model"
+	"github.com/AplaProject/go-apla/packages/webhook"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	webhookBlocksLimit     = 100
+	webhookDeliveriesLimit = 20
+	webhookTimeout         = 10 * time.Second
+)
+
+var webhookClient = webhook.NewClient(webhookTimeout)
+
+// WebhookDispatcher creates the deliveries of webhooks for new blocks and sends them. The node sends only
+// the webhooks which are assigned to it, so every webhook is sent by one full node.
+func WebhookDispatcher(ctx context.Context, d *daemon) error {
+	d.sleepTime = 2 * time.Second
+	if syspar.GetNode(conf.Config.KeyID) == nil {
+		return nil
+	}
+	if err := enqueueWebhooks(d.logger); err != nil {
+		return err
+	}
+	return deliverWebhooks(ctx, d.logger)
+}
+
+// webhookEvents returns the events of the transactions of the block
+func webhookEvents(b *model.Block) ([]*webhook.Event, error) {
+	blk, err := block.UnmarshallBlock(bytes.NewBuffer(b.Data), true)
+	if err != nil {
+		return nil, err
+	}
+	rollbacks, err := (&model.RollbackTx{}).GetBlockRollbackTransactions(nil, b.ID)
+	if err != nil {
+		return nil, err
+	}
+	events := make([]*webhook.Event, 0, len(blk.Transactions))
+	for _, t := range blk.Transactions {
+		event := &webhook.Event{
+			BlockID:   b.ID,
+			BlockTime: b.Time,
+			TxHash:    hex.EncodeToString(t.TxHash),
+			KeyID:     t.TxKeyID,
+			Changes:   make([]webhook.Change, 0),
+		}
+		if t.TxContract != nil {
+			event.Contract = t.TxContract.Name
+		}
+		if t.TxHeader != nil {
+			event.Ecosystem = t.TxHeader.EcosystemID
+		}
+		for _, rb := range rollbacks {
+			if bytes.Equal(rb.TxHash, t.TxHash) {
+				event.Changes = append(event.Changes, webhook.Change{Table: rb.NameTable, ID: rb.TableID})
+			}
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// eventEcosystems returns the ecosystems whose webhooks can be interested in the event
+func eventEcosystems(event *webhook.Event) []int64 {
+	ecosystems := make([]int64, 0, 1)
+	used := make(map[int64]bool)
+	add := func(id int64) {
+		if id > 0 && !used[id] {
+			used[id] = true
+			ecosystems = append(ecosystems, id)
+		}
+	}
+	add(event.Ecosystem)
+	for _, change := range event.Changes {
+		if off := strings.IndexByte(change.Table, '_'); off > 0 {
+			add(converter.StrToInt64(change.Table[:off]))
+		}
+	}
+	return ecosystems
+}
+
+// enqueueWebhooks creates the pending deliveries for the blocks which have been received since the last call.
+// The deliveries are created only for the blocks which are deeper than rollback_blocks_1, so the
+// transactions of the blocks can't be rolled back by forks.
+func enqueueWebhooks(logger *log.Entry) error {
+	last := &model.Block{}
+	found, err := last.GetMaxBlock()
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting max block")
+		return err
+	}
+	if !found {
+		return nil
+	}
+	confirmed := last.ID - syspar.GetRbBlocks1()
+	if confirmed < 0 {
+		confirmed = 0
+	}
+	state := &model.WebhookState{}
+	found, err = state.Get()
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting webhook state")
+		return err
+	}
+	// the webhooks are sent only for new blocks
+	if !found || state.BlockID > last.ID {
+		return state.Set(nil, confirmed)
+	}
+	if state.BlockID >= confirmed {
+		return nil
+	}
+	blocks, err := (&model.Block{}).GetBlocksFrom(state.BlockID, "asc", webhookBlocksLimit)
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting blocks")
+		return err
+	}
+	webhooks := make(map[int64][]model.Webhook)
+	for i := range blocks {
+		b := &blocks[i]
+		if b.ID > confirmed {
+			break
+		}
+		events, err := webhookEvents(b)
+		if err != nil {
+			logger.WithFields(log.Fields{"type": consts.UnmarshallingError, "error": err, "block_id": b.ID}).Error("decoding block")
+			return err
+		}
+		dbTransaction, err := model.StartTransaction()
+		if err != nil {
+			logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("starting transaction")
+			return err
+		}
+		for _, event := range events {
+			for _, ecosystemID := range eventEcosystems(event) {
+				list, ok := webhooks[ecosystemID]
+				if !ok {
+					if model.IsTable(fmt.Sprintf("%d_webhooks", ecosystemID)) {
+						if list, err = model.GetActiveWebhooks(ecosystemID, conf.Config.KeyID); err != nil {
+							logger.WithFields(log.Fields{"type": consts.DBError, "error": err, "ecosystem": ecosystemID}).Error("getting webhooks")
+							dbTransaction.Rollback()
+							return err
+						}
+					}
+					webhooks[ecosystemID] = list
+				}
+				if err = enqueueEvent(dbTransaction, ecosystemID, list, event, logger); err != nil {
+					dbTransaction.Rollback()
+					return err
+				}
+			}
+		}
+		// the deliveries of the block and the state are saved together, so they are never duplicated
+		if err = state.Set(dbTransaction, b.ID); err != nil {
+			logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("saving webhook state")
+			dbTransaction.Rollback()
+			return err
+		}
+		if err = dbTransaction.Commit(); err != nil {
+			logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("committing webhook deliveries")
+			return err
+		}
+	}
+	return nil
+}
+
+func enqueueEvent(dbTransaction *model.DbTransaction, ecosystemID int64, webhooks []model.Webhook,
+	event *webhook.Event, logger *log.Entry) error {
+	for _, hook := range webhooks {
+		contracts, err := hook.ContractList()
+		if err != nil {
+			logger.WithFields(log.Fields{"type": consts.JSONUnmarshallError, "error": err, "webhook": hook.ID}).Error("unmarshalling contracts of webhook")
+			continue
+		}
+		tables, err := hook.TableList()
+		if err != nil {
+			logger.WithFields(log.Fields{"type": consts.JSONUnmarshallError, "error": err, "webhook": hook.ID}).Error("unmarshalling tables of webhook")
+			continue
+		}
+		matched, ok := webhook.Match(ecosystemID, contracts, tables, event)
+		if !ok {
+			continue
+		}
+		payload, err := json.Marshal(webhook.Payload{WebhookID: hook.ID, Webhook: hook.Name, Event: *matched})
+		if err != nil {
+			logger.WithFields(log.Fields{"type": consts.JSONMarshallError, "error": err}).Error("marshalling webhook payload")
+			return err
+		}
+		hash, _ := hex.DecodeString(event.TxHash)
+		now := time.Now().Unix()
+		delivery := &model.WebhookDelivery{
+			Ecosystem:   ecosystemID,
+			WebhookID:   hook.ID,
+			BlockID:     event.BlockID,
+			TxHash:      hash,
+			Payload:     string(payload),
+			Status:      model.WebhookPending,
+			NextAttempt: now,
+			CreatedAt:   now,
+		}
+		if err = delivery.Create(dbTransaction); err != nil {
+			logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("creating webhook delivery")
+			return err
+		}
+	}
+	return nil
+}
+
+// deliverWebhooks sends the pending deliveries
+func deliverWebhooks(ctx context.Context, logger *log.Entry) error {
+	deliveries, err := model.GetPendingWebhookDeliveries(time.Now().Unix(), webhookDeliveriesLimit)
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting pending webhook deliveries")
+		return err
+	}
+	var wg sync.WaitGroup
+	for i := range deliveries {
+		wg.Add(1)
+		go func(delivery *model.WebhookDelivery) {
+			defer wg.Done()
+			deliverWebhook(ctx, delivery, logger)
+		}(&deliveries[i])
+	}
+	wg.Wait()
+	return nil
+}
+
+func deliverWebhook(ctx context.Context, delivery *model.WebhookDelivery, logger *log.Entry) {
+	logger = logger.WithFields(log.Fields{"delivery": delivery.ID, "webhook": delivery.WebhookID, "ecosystem": delivery.Ecosystem})
+	hook := &model.Webhook{}
+	found, err := hook.SetTablePrefix(delivery.Ecosystem).Get(delivery.WebhookID)
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting webhook")
+		return
+	}
+	if !found || hook.Deleted != 0 || hook.NodeID != conf.Config.KeyID {
+		delivery.Status = model.WebhookCanceled
+		if err = delivery.Save(); err != nil {
+			logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("saving webhook delivery")
+		}
+		return
+	}
+	var code int
+	if secret, ok := conf.Config.Webhooks.Secrets[hook.SecretName]; ok {
+		code, err = webhook.Send(ctx, webhookClient, hook.URL, secret, hook.ID, delivery.ID, []byte(delivery.Payload))
+	} else {
+		err = fmt.Errorf(`secret %s of webhook is not configured`, hook.SecretName)
+	}
+	now := time.Now().Unix()
+	delivery.Attempts++
+	delivery.ResponseCode = int64(code)
+	if err == nil {
+		delivery.Status = model.WebhookDelivered
+		delivery.DeliveredAt = now
+		delivery.Error = ``
+	} else {
+		logger.WithFields(log.Fields{"type": consts.NetworkError, "error": err, "attempts": delivery.Attempts}).Warning("sending webhook")
+		delivery.Error = err.Error()
+		delivery.NextAttempt = now + int64(webhook.Backoff(delivery.Attempts)/time.Second)
+		if delivery.Attempts >= webhook.MaxAttempts {
+			delivery.Status = model.WebhookFailed
+			letter := &model.WebhookDeadLetter{
+				DeliveryID: delivery.ID,
+				Ecosystem:  delivery.Ecosystem,
+				WebhookID:  delivery.WebhookID,
+				Payload:    delivery.Payload,
+				Attempts:   delivery.Attempts,
+				Error:      delivery.Error,
+				Time:       now,
+			}
+			if err = letter.Create(); err != nil {
+				logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("creating webhook dead letter")
+				return
+			}
+		}
+	}
+	if err = delivery.Save(); err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("saving webhook delivery")
+	}
+}
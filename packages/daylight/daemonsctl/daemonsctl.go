@@ -27,6 +27,8 @@ func RunAllDaemons() error {
 		if data, ok := block.GetDataFromFirstBlock(); ok {
 			syspar.SetFirstBlockData(data)
 		}
+		// the transactions which have been logged before the update don't have the block
+		go block.FillLogTransactions()
 	}
 
 	log.Info("load contracts")
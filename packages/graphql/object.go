@@ -0,0 +1,71 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+type objectItem struct {
+	key   string
+	value interface{}
+}
+
+// Object is the result object which keeps the order of fields as they are requested
+type Object struct {
+	items []objectItem
+}
+
+// Set adds the field to the object
+func (o *Object) Set(key string, value interface{}) {
+	o.items = append(o.items, objectItem{key: key, value: value})
+}
+
+// Get returns the value of the field
+func (o *Object) Get(key string) (interface{}, bool) {
+	for _, item := range o.items {
+		if item.key == key {
+			return item.value, true
+		}
+	}
+	return nil, false
+}
+
+// MarshalJSON encodes the object with the fields in the requested order
+func (o *Object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, item := range o.items {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(item.key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(item.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
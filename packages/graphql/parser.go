@@ -0,0 +1,531 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package graphql parses GraphQL queries. Only the query operations are supported,
+// fragments and directives aren't supported. The values of variables are substituted
+// into the arguments of the fields while parsing.
+package graphql
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Field is the field of the selection set
+type Field struct {
+	Alias  string
+	Name   string
+	Args   map[string]interface{}
+	Fields []*Field
+	Line   int
+	Column int
+}
+
+// Key returns the name of the field in the result
+func (f *Field) Key() string {
+	if len(f.Alias) > 0 {
+		return f.Alias
+	}
+	return f.Name
+}
+
+// Query is the parsed query operation
+type Query struct {
+	Name   string
+	Fields []*Field
+}
+
+// Error is the error of parsing with the position in the source
+type Error struct {
+	Message string
+	Line    int
+	Column  int
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf(`%s [Ln:%d Col:%d]`, e.Message, e.Line, e.Column)
+}
+
+const (
+	tokEOF = iota
+	tokPunct
+	tokName
+	tokInt
+	tokFloat
+	tokString
+)
+
+type token struct {
+	kind   int
+	value  string
+	line   int
+	column int
+}
+
+type parser struct {
+	input     string
+	pos       int
+	line      int
+	lineStart int
+	tok       token
+	variables map[string]interface{}
+	defaults  map[string]interface{}
+}
+
+// Parse parses the query with the specified values of variables. If the document contains
+// several operations then operationName selects the operation.
+func Parse(input string, variables map[string]interface{}, operationName string) (*Query, error) {
+	p := &parser{input: input, line: 1, variables: variables}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	var query *Query
+	for p.tok.kind != tokEOF {
+		op, err := p.parseOperation()
+		if err != nil {
+			return nil, err
+		}
+		if len(operationName) == 0 || op.Name == operationName {
+			if query != nil {
+				return nil, p.errorf(`operation name must be specified`)
+			}
+			query = op
+		}
+	}
+	if query == nil {
+		if len(operationName) > 0 {
+			return nil, &Error{Message: fmt.Sprintf(`unknown operation %s`, operationName), Line: 1, Column: 1}
+		}
+		return nil, p.errorf(`query is empty`)
+	}
+	return query, nil
+}
+
+func (p *parser) errorf(format string, params ...interface{}) error {
+	return &Error{Message: fmt.Sprintf(format, params...), Line: p.tok.line, Column: p.tok.column}
+}
+
+func (p *parser) parseOperation() (*Query, error) {
+	query := &Query{}
+	p.defaults = make(map[string]interface{})
+	if p.tok.kind == tokName {
+		if p.tok.value != `query` {
+			return nil, p.errorf(`operation %s is not supported`, p.tok.value)
+		}
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		if p.tok.kind == tokName {
+			query.Name = p.tok.value
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+		}
+		if p.is(`(`) {
+			if err := p.parseVariables(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	fields, err := p.parseSelection()
+	if err != nil {
+		return nil, err
+	}
+	query.Fields = fields
+	return query, nil
+}
+
+// parseVariables parses the definitions of variables, only the default values are used
+func (p *parser) parseVariables() error {
+	if err := p.next(); err != nil {
+		return err
+	}
+	for !p.is(`)`) {
+		if !p.is(`$`) {
+			return p.errorf(`must be variable`)
+		}
+		if err := p.next(); err != nil {
+			return err
+		}
+		if p.tok.kind != tokName {
+			return p.errorf(`must be name of variable`)
+		}
+		name := p.tok.value
+		if err := p.next(); err != nil {
+			return err
+		}
+		if err := p.expect(`:`); err != nil {
+			return err
+		}
+		if err := p.skipType(); err != nil {
+			return err
+		}
+		if p.is(`=`) {
+			if err := p.next(); err != nil {
+				return err
+			}
+			value, err := p.parseValue(true)
+			if err != nil {
+				return err
+			}
+			p.defaults[name] = value
+		}
+	}
+	return p.next()
+}
+
+func (p *parser) skipType() error {
+	switch {
+	case p.is(`[`):
+		if err := p.next(); err != nil {
+			return err
+		}
+		if err := p.skipType(); err != nil {
+			return err
+		}
+		if err := p.expect(`]`); err != nil {
+			return err
+		}
+	case p.tok.kind == tokName:
+		if err := p.next(); err != nil {
+			return err
+		}
+	default:
+		return p.errorf(`must be type`)
+	}
+	if p.is(`!`) {
+		return p.next()
+	}
+	return nil
+}
+
+func (p *parser) parseSelection() ([]*Field, error) {
+	if err := p.expect(`{`); err != nil {
+		return nil, err
+	}
+	fields := make([]*Field, 0)
+	for !p.is(`}`) {
+		if p.tok.kind != tokName {
+			if p.tok.kind == tokEOF {
+				return nil, p.errorf(`unexpected end of query`)
+			}
+			if p.is(`...`) {
+				return nil, p.errorf(`fragments are not supported`)
+			}
+			return nil, p.errorf(`must be name of field`)
+		}
+		field := &Field{Name: p.tok.value, Line: p.tok.line, Column: p.tok.column}
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		if p.is(`:`) {
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+			if p.tok.kind != tokName {
+				return nil, p.errorf(`must be name of field`)
+			}
+			field.Alias = field.Name
+			field.Name = p.tok.value
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+		}
+		if p.is(`(`) {
+			args, err := p.parseArguments()
+			if err != nil {
+				return nil, err
+			}
+			field.Args = args
+		}
+		if p.is(`@`) {
+			return nil, p.errorf(`directives are not supported`)
+		}
+		if p.is(`{`) {
+			sub, err := p.parseSelection()
+			if err != nil {
+				return nil, err
+			}
+			field.Fields = sub
+		}
+		fields = append(fields, field)
+	}
+	if len(fields) == 0 {
+		return nil, p.errorf(`selection must have fields`)
+	}
+	return fields, p.next()
+}
+
+func (p *parser) parseArguments() (map[string]interface{}, error) {
+	args := make(map[string]interface{})
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	for !p.is(`)`) {
+		if p.tok.kind != tokName {
+			return nil, p.errorf(`must be name of argument`)
+		}
+		name := p.tok.value
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		if err := p.expect(`:`); err != nil {
+			return nil, err
+		}
+		value, err := p.parseValue(false)
+		if err != nil {
+			return nil, err
+		}
+		args[name] = value
+	}
+	return args, p.next()
+}
+
+func (p *parser) parseValue(isConst bool) (interface{}, error) {
+	var value interface{}
+	tok := p.tok
+	switch tok.kind {
+	case tokInt:
+		val, err := strconv.ParseInt(tok.value, 10, 64)
+		if err != nil {
+			return nil, p.errorf(`wrong int value %s`, tok.value)
+		}
+		value = val
+	case tokFloat:
+		val, err := strconv.ParseFloat(tok.value, 64)
+		if err != nil {
+			return nil, p.errorf(`wrong float value %s`, tok.value)
+		}
+		value = val
+	case tokString:
+		value = tok.value
+	case tokName:
+		switch tok.value {
+		case `true`:
+			value = true
+		case `false`:
+			value = false
+		case `null`:
+		default:
+			value = tok.value
+		}
+	case tokPunct:
+		switch tok.value {
+		case `$`:
+			if isConst {
+				return nil, p.errorf(`variable is not allowed`)
+			}
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+			if p.tok.kind != tokName {
+				return nil, p.errorf(`must be name of variable`)
+			}
+			if val, ok := p.variables[p.tok.value]; ok {
+				value = val
+			} else if val, ok := p.defaults[p.tok.value]; ok {
+				value = val
+			}
+		case `[`:
+			list := make([]interface{}, 0)
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+			for !p.is(`]`) {
+				item, err := p.parseValue(isConst)
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, item)
+			}
+			value = list
+		case `{`:
+			obj := make(map[string]interface{})
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+			for !p.is(`}`) {
+				// the names of operators of where conditions are allowed as the keys ($gt, $or etc.)
+				var prefix string
+				if p.is(`$`) {
+					prefix = `$`
+					if err := p.next(); err != nil {
+						return nil, err
+					}
+				}
+				if p.tok.kind != tokName {
+					return nil, p.errorf(`must be name of field`)
+				}
+				name := prefix + p.tok.value
+				if err := p.next(); err != nil {
+					return nil, err
+				}
+				if err := p.expect(`:`); err != nil {
+					return nil, err
+				}
+				item, err := p.parseValue(isConst)
+				if err != nil {
+					return nil, err
+				}
+				obj[name] = item
+			}
+			value = obj
+		default:
+			return nil, p.errorf(`unexpected %s`, tok.value)
+		}
+	default:
+		return nil, p.errorf(`unexpected end of query`)
+	}
+	return value, p.next()
+}
+
+func (p *parser) is(punct string) bool {
+	return p.tok.kind == tokPunct && p.tok.value == punct
+}
+
+func (p *parser) expect(punct string) error {
+	if !p.is(punct) {
+		return p.errorf(`must be '%s'`, punct)
+	}
+	return p.next()
+}
+
+// next reads the next token. Whitespaces, commas and comments are skipped.
+func (p *parser) next() error {
+	for p.pos < len(p.input) {
+		ch := p.input[p.pos]
+		if ch == '#' {
+			for p.pos < len(p.input) && p.input[p.pos] != '\n' {
+				p.pos++
+			}
+			continue
+		}
+		if ch == '\n' {
+			p.line++
+			p.lineStart = p.pos + 1
+		} else if ch != ' ' && ch != '\t' && ch != '\r' && ch != ',' {
+			break
+		}
+		p.pos++
+	}
+	p.tok = token{line: p.line, column: p.pos - p.lineStart + 1}
+	if p.pos >= len(p.input) {
+		p.tok.kind = tokEOF
+		return nil
+	}
+	start := p.pos
+	ch := p.input[p.pos]
+	switch {
+	case isNameStart(ch):
+		for p.pos < len(p.input) && (isNameStart(p.input[p.pos]) || isDigit(p.input[p.pos])) {
+			p.pos++
+		}
+		p.tok.kind = tokName
+	case isDigit(ch) || ch == '-':
+		p.tok.kind = tokInt
+		p.pos++
+		for p.pos < len(p.input) {
+			ch = p.input[p.pos]
+			if ch == '.' || ch == 'e' || ch == 'E' || ((ch == '+' || ch == '-') && p.tok.kind == tokFloat &&
+				(p.input[p.pos-1] == 'e' || p.input[p.pos-1] == 'E')) {
+				p.tok.kind = tokFloat
+			} else if !isDigit(ch) {
+				break
+			}
+			p.pos++
+		}
+	case ch == '"':
+		value, err := p.readString()
+		if err != nil {
+			return err
+		}
+		p.tok.kind = tokString
+		p.tok.value = value
+		return nil
+	case strings.HasPrefix(p.input[p.pos:], `...`):
+		p.pos += 3
+		p.tok.kind = tokPunct
+	case strings.IndexByte(`!$():=@[]{|}`, ch) >= 0:
+		p.pos++
+		p.tok.kind = tokPunct
+	default:
+		r, _ := utf8.DecodeRuneInString(p.input[p.pos:])
+		return p.errorf(`unexpected character %q`, r)
+	}
+	p.tok.value = p.input[start:p.pos]
+	return nil
+}
+
+func (p *parser) readString() (string, error) {
+	var out bytes.Buffer
+	p.pos++
+	for p.pos < len(p.input) {
+		ch := p.input[p.pos]
+		switch ch {
+		case '"':
+			p.pos++
+			return out.String(), nil
+		case '\n':
+			return ``, p.errorf(`unterminated string`)
+		case '\\':
+			p.pos++
+			if p.pos >= len(p.input) {
+				return ``, p.errorf(`unterminated string`)
+			}
+			switch esc := p.input[p.pos]; esc {
+			case 'n':
+				out.WriteByte('\n')
+			case 't':
+				out.WriteByte('\t')
+			case 'r':
+				out.WriteByte('\r')
+			case 'b':
+				out.WriteByte('\b')
+			case 'f':
+				out.WriteByte('\f')
+			case 'u':
+				if p.pos+5 > len(p.input) {
+					return ``, p.errorf(`wrong escape sequence`)
+				}
+				code, err := strconv.ParseUint(p.input[p.pos+1:p.pos+5], 16, 32)
+				if err != nil {
+					return ``, p.errorf(`wrong escape sequence`)
+				}
+				out.WriteRune(rune(code))
+				p.pos += 4
+			case '"', '\\', '/':
+				out.WriteByte(esc)
+			default:
+				return ``, p.errorf(`wrong escape sequence`)
+			}
+		default:
+			out.WriteByte(ch)
+		}
+		p.pos++
+	}
+	return ``, p.errorf(`unterminated string`)
+}
+
+func isNameStart(ch byte) bool {
+	return ch == '_' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
+}
+
+func isDigit(ch byte) bool {
+	return ch >= '0' && ch <= '9'
+}
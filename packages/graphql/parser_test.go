@@ -0,0 +1,95 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type TestQuery struct {
+	Input  string
+	Output string
+}
+
+func fieldsString(fields []*Field) string {
+	list := make([]string, len(fields))
+	for i, field := range fields {
+		out := field.Name
+		if len(field.Alias) > 0 {
+			out = field.Alias + `:` + out
+		}
+		if len(field.Args) > 0 {
+			args, _ := json.Marshal(field.Args)
+			out += string(args)
+		}
+		if len(field.Fields) > 0 {
+			out += `{` + fieldsString(field.Fields) + `}`
+		}
+		list[i] = out
+	}
+	return strings.Join(list, ` `)
+}
+
+func TestParse(t *testing.T) {
+	variables := map[string]interface{}{`limit`: 10}
+	test := []TestQuery{
+		{`{ keys { id amount } }`, `keys{id amount}`},
+		{`query Wallets($limit: Int, $name: String = "John") {
+			# list of members
+			rich: keys(limit: $limit, where: {amount: {$gt: "100"}}) { id member { member_name } }
+			members(where: {member_name: $name}, order: ["id"]) { id }
+		}`, `rich:keys{"limit":10,"where":{"amount":{"$gt":"100"}}}{id member{member_name}} members{"order":["id"],"where":{"member_name":"John"}}{id}`},
+		{`{ history(id: 5, ok: true, rate: -1.5) { id } }`, `history{"id":5,"ok":true,"rate":-1.5}{id}`},
+		{`mutation { keys { id } }`, `operation mutation is not supported [Ln:1 Col:1]`},
+		{`{ keys { ...KeyFields } }`, `fragments are not supported [Ln:1 Col:10]`},
+		{`{ keys(where: "id) { id } }`, `unterminated string [Ln:1 Col:15]`},
+		{`{ keys { id }`, `unexpected end of query [Ln:1 Col:14]`},
+		{``, `query is empty [Ln:1 Col:1]`},
+	}
+	for _, item := range test {
+		query, err := Parse(item.Input, variables, ``)
+		var out string
+		if err != nil {
+			out = err.Error()
+		} else {
+			out = fieldsString(query.Fields)
+		}
+		if out != item.Output {
+			t.Errorf("wrong query %s:\n%s != %s", item.Input, out, item.Output)
+		}
+	}
+}
+
+func TestObject(t *testing.T) {
+	obj := &Object{}
+	obj.Set(`z`, 1)
+	obj.Set(`a`, []interface{}{`x`, nil})
+	obj.Set(`m`, &Object{})
+	out, err := json.Marshal(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != `{"z":1,"a":["x",null],"m":{}}` {
+		t.Errorf("wrong object %s", out)
+	}
+	if v, ok := obj.Get(`z`); !ok || fmt.Sprint(v) != `1` {
+		t.Errorf("wrong value %v", v)
+	}
+}
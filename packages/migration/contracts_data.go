@@ -11,3 +11,208 @@ INSERT INTO "%[1]d_contracts" ("id", "name", "value", "wallet_id","active", "con
 		  }
 		}', '%[2]d', '0', 'ContractConditions("MainCondition")');
 `
+
+// apiKeysContractsSQL adds the contracts of API keys if they don't exist. The member creates API key
+// for own key_id, the role of API key must be the role of the member.
+var apiKeysContractsSQL = `
+INSERT INTO "%[1]d_contracts" ("id", "name", "value", "wallet_id", "conditions", "app_id")
+SELECT (SELECT COALESCE(max(id), 0) FROM "%[1]d_contracts") + c.num, c.name, c.value, '%[2]d',
+	'ContractConditions("MainCondition")', 1
+FROM (VALUES (1, 'NewAPIKey', 'contract NewAPIKey {
+    data {
+        Name string
+        KeyHash string
+        RoleId int "optional"
+        Routes string "optional"
+    }
+
+    conditions {
+        $KeyHash = ToLower($KeyHash)
+        if Size($KeyHash) != 64 {
+            warning "KeyHash must be the hex hash of the secret"
+        }
+        HexToBytes($KeyHash)
+        if DBFind("api_keys").Columns("id").Where("key_hash = $", $KeyHash).One("id") {
+            warning "API key already exists"
+        }
+        if $RoleId != 0 && !DBFind("roles_participants").Columns("id").Where("member->member_id = $ and role->id = $ and deleted = 0", Str($key_id), Str($RoleId)).One("id") {
+            warning Sprintf("Key %%d is not a member of role %%d", $key_id, $RoleId)
+        }
+        if Size($Routes) == 0 {
+            $Routes = ` + "`" + `["read"]` + "`" + `
+        }
+        if GetType(JSONDecode($Routes)) != "[]interface {}" {
+            warning "Routes must be JSON array"
+        }
+    }
+
+    action {
+        $result = DBInsert("api_keys", "name,key_hash,key_id,role_id,routes", $Name, $KeyHash, $key_id, $RoleId, $Routes)
+    }
+}'),
+(2, 'RevokeAPIKey', 'contract RevokeAPIKey {
+    data {
+        Id int
+    }
+
+    conditions {
+        var api_key map
+        api_key = DBFind("api_keys").Columns("key_id,deleted").WhereId($Id).Row()
+        if !api_key {
+            warning Sprintf("API key %%d has not been found", $Id)
+        }
+        if Int(api_key["deleted"]) != 0 {
+            warning "API key has already been revoked"
+        }
+        if Int(api_key["key_id"]) != $key_id {
+            ContractConditions("MainCondition")
+        }
+    }
+
+    action {
+        DBUpdate("api_keys", $Id, "deleted", 1)
+    }
+}')) AS c(num, name, value)
+WHERE NOT EXISTS (SELECT 1 FROM "%[1]d_contracts" WHERE name = 'NewAPIKey');
+`
+
+// webhooksContractsSQL adds the contracts of webhooks if they don't exist. The secret of webhook is not stored in blockchain,
+// the webhook contains the name of the secret which is configured on the dispatching full node.
+var webhooksContractsSQL = `
+INSERT INTO "%[1]d_contracts" ("id", "name", "value", "wallet_id", "conditions", "app_id")
+SELECT (SELECT COALESCE(max(id), 0) FROM "%[1]d_contracts") + c.num, c.name, c.value, '%[2]d',
+	'ContractConditions("MainCondition")', 1
+FROM (VALUES (1, 'NewWebhook', 'contract NewWebhook {
+    data {
+        Name string
+        Url string
+        Contracts string "optional"
+        Tables string "optional"
+        NodeId int
+        SecretName string
+    }
+
+    func checkNode(keyID int) {
+        var nodes array
+        nodes = JSONDecode(SysParamString("full_nodes"))
+        var i int
+        while i < Len(nodes) {
+            var node map
+            node = nodes[i]
+            if Int(node["key_id"]) == keyID {
+                return
+            }
+            i = i + 1
+        }
+        warning Sprintf("Full node %%d has not been found", keyID)
+    }
+
+    conditions {
+        if !HasPrefix($Url, "http://") && !HasPrefix($Url, "https://") {
+            warning "Url must be HTTP or HTTPS address"
+        }
+        if Size($SecretName) == 0 {
+            warning "SecretName must be specified"
+        }
+        checkNode($NodeId)
+        if Size($Contracts) == 0 {
+            $Contracts = "[]"
+        }
+        if Size($Tables) == 0 {
+            $Tables = "[]"
+        }
+        if GetType(JSONDecode($Contracts)) != "[]interface {}" || GetType(JSONDecode($Tables)) != "[]interface {}" {
+            warning "Contracts and Tables must be JSON arrays"
+        }
+        if $Contracts == "[]" && $Tables == "[]" {
+            warning "Contracts or Tables must be specified"
+        }
+        if DBFind("webhooks").Columns("id").Where("name = $ and deleted = 0", $Name).One("id") {
+            warning Sprintf("Webhook %%s already exists", $Name)
+        }
+    }
+
+    action {
+        $result = DBInsert("webhooks", "name,url,contracts,tables,node_id,secret_name", $Name, $Url, $Contracts, $Tables, $NodeId, $SecretName)
+    }
+}'),
+(2, 'EditWebhook', 'contract EditWebhook {
+    data {
+        Id int
+        Url string "optional"
+        Contracts string "optional"
+        Tables string "optional"
+        NodeId int "optional"
+        SecretName string "optional"
+    }
+
+    func checkNode(keyID int) {
+        var nodes array
+        nodes = JSONDecode(SysParamString("full_nodes"))
+        var i int
+        while i < Len(nodes) {
+            var node map
+            node = nodes[i]
+            if Int(node["key_id"]) == keyID {
+                return
+            }
+            i = i + 1
+        }
+        warning Sprintf("Full node %%d has not been found", keyID)
+    }
+
+    conditions {
+        $deleted = DBFind("webhooks").Columns("deleted").WhereId($Id).One("deleted")
+        if !$deleted || Int($deleted) != 0 {
+            warning Sprintf("Webhook %%d has not been found", $Id)
+        }
+        if Size($Url) > 0 && !HasPrefix($Url, "http://") && !HasPrefix($Url, "https://") {
+            warning "Url must be HTTP or HTTPS address"
+        }
+        if $NodeId != 0 {
+            checkNode($NodeId)
+        }
+        if Size($Contracts) > 0 && GetType(JSONDecode($Contracts)) != "[]interface {}" {
+            warning "Contracts must be JSON array"
+        }
+        if Size($Tables) > 0 && GetType(JSONDecode($Tables)) != "[]interface {}" {
+            warning "Tables must be JSON array"
+        }
+    }
+
+    action {
+        if Size($Url) > 0 {
+            DBUpdate("webhooks", $Id, "url", $Url)
+        }
+        if Size($Contracts) > 0 {
+            DBUpdate("webhooks", $Id, "contracts", $Contracts)
+        }
+        if Size($Tables) > 0 {
+            DBUpdate("webhooks", $Id, "tables", $Tables)
+        }
+        if $NodeId != 0 {
+            DBUpdate("webhooks", $Id, "node_id", $NodeId)
+        }
+        if Size($SecretName) > 0 {
+            DBUpdate("webhooks", $Id, "secret_name", $SecretName)
+        }
+    }
+}'),
+(3, 'DeleteWebhook', 'contract DeleteWebhook {
+    data {
+        Id int
+    }
+
+    conditions {
+        $deleted = DBFind("webhooks").Columns("deleted").WhereId($Id).One("deleted")
+        if !$deleted || Int($deleted) != 0 {
+            warning Sprintf("Webhook %%d has not been found", $Id)
+        }
+    }
+
+    action {
+        DBUpdate("webhooks", $Id, "deleted", 1)
+    }
+}')) AS c(num, name, value)
+WHERE NOT EXISTS (SELECT 1 FROM "%[1]d_contracts" WHERE name = 'NewWebhook');
+`
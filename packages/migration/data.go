@@ -149,4 +149,107 @@ var (
 		DROP TABLE IF EXISTS "stop_daemons"; CREATE TABLE "stop_daemons" (
 		"stop_time" int NOT NULL DEFAULT '0'
 		);`
+
+	migrationTxStatusError = `ALTER TABLE "transactions_status" ALTER COLUMN "error" TYPE varchar(1024);`
+
+	migrationEvents = `DO $$
+	DECLARE
+		eco record;
+	BEGIN
+		IF to_regclass('"1_ecosystems"') IS NOT NULL THEN
+			FOR eco IN SELECT id FROM "1_ecosystems" LOOP
+				EXECUTE format('CREATE TABLE IF NOT EXISTS "%1$s_events" (
+					"id" bigint NOT NULL DEFAULT ''0'',
+					"contract" varchar(255) NOT NULL DEFAULT '''',
+					"name" varchar(255) NOT NULL DEFAULT '''',
+					"data" jsonb NOT NULL DEFAULT ''{}'',
+					"key_id" bigint NOT NULL DEFAULT ''0'',
+					"block_id" bigint NOT NULL DEFAULT ''0'',
+					"txhash" bytea NOT NULL DEFAULT '''',
+					CONSTRAINT "%1$s_events_pkey" PRIMARY KEY ("id")
+				);
+				CREATE INDEX IF NOT EXISTS "%1$s_events_index_contract" ON "%1$s_events" (contract, name);
+				CREATE INDEX IF NOT EXISTS "%1$s_events_index_name" ON "%1$s_events" (name);
+				CREATE INDEX IF NOT EXISTS "%1$s_events_index_block" ON "%1$s_events" (block_id);
+				CREATE INDEX IF NOT EXISTS "%1$s_events_index_data" ON "%1$s_events"
+					USING GIN (data jsonb_path_ops);', eco.id);
+			END LOOP;
+		END IF;
+	END $$;`
+
+	migrationLogTxBlocks = `ALTER TABLE "log_transactions"
+		ADD COLUMN IF NOT EXISTS "block_id" bigint NOT NULL DEFAULT '0',
+		ADD COLUMN IF NOT EXISTS "key_id" bigint NOT NULL DEFAULT '0';
+	CREATE INDEX IF NOT EXISTS "log_transactions_index_block" ON "log_transactions" (block_id);
+	CREATE INDEX IF NOT EXISTS "log_transactions_index_key" ON "log_transactions" (key_id, block_id);`
+
+	// migrationAPIKeysEcosystems adds API keys to the existing ecosystems
+	migrationAPIKeysEcosystems = ecosystemsMigration(apiKeysSchemaSQL + apiKeysContractsSQL)
+
+	// migrationWebhooksEcosystems adds webhooks to the existing ecosystems
+	migrationWebhooksEcosystems = ecosystemsMigration(webhooksSchemaSQL + webhooksContractsSQL)
+
+	// migrationSnapshots adds the state snapshots to the first ecosystem of the existing chain
+	migrationSnapshots = firstEcosystemMigration(firstSnapshotsSchemaSQL + firstSnapshotContractsSQL)
+
+	migrationLogTxFuel = `ALTER TABLE "log_transactions"
+		ADD COLUMN IF NOT EXISTS "fuel" bigint NOT NULL DEFAULT '0';`
+
+	migrationAPIKeys = `DROP SEQUENCE IF EXISTS api_key_log_id_seq CASCADE;
+	CREATE SEQUENCE api_key_log_id_seq START WITH 1;
+	CREATE TABLE IF NOT EXISTS "api_key_log" (
+		"id" bigint NOT NULL default nextval('api_key_log_id_seq'),
+		"ecosystem" bigint NOT NULL DEFAULT '0',
+		"api_key_id" bigint NOT NULL DEFAULT '0',
+		"key_id" bigint NOT NULL DEFAULT '0',
+		"route" varchar(255) NOT NULL DEFAULT '',
+		"remote" varchar(255) NOT NULL DEFAULT '',
+		"allowed" boolean NOT NULL DEFAULT 'false',
+		"time" bigint NOT NULL DEFAULT '0'
+	);
+	ALTER SEQUENCE api_key_log_id_seq owned by api_key_log.id;
+	ALTER TABLE ONLY "api_key_log" ADD CONSTRAINT api_key_log_pkey PRIMARY KEY (id);
+	CREATE INDEX "api_key_log_index_key" ON "api_key_log" (ecosystem, api_key_id, time);`
+
+	migrationWebhooks = `DROP SEQUENCE IF EXISTS webhook_deliveries_id_seq CASCADE;
+	CREATE SEQUENCE webhook_deliveries_id_seq START WITH 1;
+	CREATE TABLE IF NOT EXISTS "webhook_deliveries" (
+		"id" bigint NOT NULL default nextval('webhook_deliveries_id_seq'),
+		"ecosystem" bigint NOT NULL DEFAULT '0',
+		"webhook_id" bigint NOT NULL DEFAULT '0',
+		"block_id" bigint NOT NULL DEFAULT '0',
+		"tx_hash" bytea NOT NULL DEFAULT '',
+		"payload" text NOT NULL DEFAULT '',
+		"status" varchar(32) NOT NULL DEFAULT '',
+		"attempts" int NOT NULL DEFAULT '0',
+		"next_attempt" bigint NOT NULL DEFAULT '0',
+		"response_code" int NOT NULL DEFAULT '0',
+		"error" varchar(1024) NOT NULL DEFAULT '',
+		"created_at" bigint NOT NULL DEFAULT '0',
+		"delivered_at" bigint NOT NULL DEFAULT '0'
+	);
+	ALTER SEQUENCE webhook_deliveries_id_seq owned by webhook_deliveries.id;
+	ALTER TABLE ONLY "webhook_deliveries" ADD CONSTRAINT webhook_deliveries_pkey PRIMARY KEY (id);
+	CREATE INDEX "webhook_deliveries_index_status" ON "webhook_deliveries" (status, next_attempt);
+	CREATE INDEX "webhook_deliveries_index_webhook" ON "webhook_deliveries" (ecosystem, webhook_id);
+
+	DROP SEQUENCE IF EXISTS webhook_dead_letters_id_seq CASCADE;
+	CREATE SEQUENCE webhook_dead_letters_id_seq START WITH 1;
+	CREATE TABLE IF NOT EXISTS "webhook_dead_letters" (
+		"id" bigint NOT NULL default nextval('webhook_dead_letters_id_seq'),
+		"delivery_id" bigint NOT NULL DEFAULT '0',
+		"ecosystem" bigint NOT NULL DEFAULT '0',
+		"webhook_id" bigint NOT NULL DEFAULT '0',
+		"payload" text NOT NULL DEFAULT '',
+		"attempts" int NOT NULL DEFAULT '0',
+		"error" varchar(1024) NOT NULL DEFAULT '',
+		"time" bigint NOT NULL DEFAULT '0'
+	);
+	ALTER SEQUENCE webhook_dead_letters_id_seq owned by webhook_dead_letters.id;
+	ALTER TABLE ONLY "webhook_dead_letters" ADD CONSTRAINT webhook_dead_letters_pkey PRIMARY KEY (id);
+	CREATE INDEX "webhook_dead_letters_index_webhook" ON "webhook_dead_letters" (ecosystem, webhook_id);
+
+	CREATE TABLE IF NOT EXISTS "webhook_state" (
+		"block_id" bigint NOT NULL DEFAULT '0'
+	);`
 )
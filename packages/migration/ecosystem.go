@@ -2,6 +2,8 @@ package migration
 
 import (
 	"strings"
+
+	"github.com/AplaProject/go-apla/packages/consts"
 )
 
 // GetEcosystemScript returns script to create ecosystem
@@ -22,6 +24,64 @@ func GetEcosystemScript() string {
 	return strings.Join(scripts, "\r\n")
 }
 
+// GetEcosystemFeaturesScript returns script to add the features of the ecosystem which are created
+// by the blocks of the specified version. They are added after the contracts of the first ecosystem,
+// so their identifiers follow the existing contracts. The existing ecosystems get these features
+// by the migrations.
+func GetEcosystemFeaturesScript(blockVersion int) string {
+	var script string
+	if blockVersion >= consts.BlockVersionAPIKeys {
+		script += apiKeysSchemaSQL + apiKeysContractsSQL
+	}
+	if blockVersion >= consts.BlockVersionWebhooks {
+		script += webhooksSchemaSQL + webhooksContractsSQL
+	}
+	return script
+}
+
+// ecosystemsMigration returns the migration which runs the script of the ecosystem for every existing
+// ecosystem. The script gets the identifier of the ecosystem and the wallet of its first contract.
+func ecosystemsMigration(script string) string {
+	script = strings.NewReplacer(`%[1]d`, `%1$s`, `%[2]d`, `%2$s`).Replace(script)
+	return `DO $$
+	DECLARE
+		eco record;
+		wallet bigint;
+	BEGIN
+		IF to_regclass('"1_ecosystems"') IS NOT NULL THEN
+			FOR eco IN SELECT id FROM "1_ecosystems" ORDER BY id LOOP
+				EXECUTE format('SELECT wallet_id FROM %I WHERE id = 1', eco.id || '_contracts') INTO wallet;
+				EXECUTE format($script$` + script + `$script$, eco.id, COALESCE(wallet, 0));
+			END LOOP;
+		END IF;
+	END $$;`
+}
+
+// firstEcosystemMigration returns the migration which runs the script of the first ecosystem if the first
+// ecosystem exists. The script gets the wallet of the first contract.
+func firstEcosystemMigration(script string) string {
+	script = strings.Replace(script, `%[1]d`, `%1$s`, -1)
+	return `DO $$
+	DECLARE
+		wallet bigint;
+	BEGIN
+		IF to_regclass('"1_contracts"') IS NOT NULL THEN
+			SELECT wallet_id INTO wallet FROM "1_contracts" WHERE id = 1;
+			EXECUTE format($script$` + script + `$script$, COALESCE(wallet, 0));
+		END IF;
+	END $$;`
+}
+
+// GetFirstEcosystemFeaturesScript returns script to add the features of the first ecosystem which
+// are created by the blocks of the specified version. The existing chains get these features by the migrations.
+func GetFirstEcosystemFeaturesScript(blockVersion int) string {
+	var script string
+	if blockVersion >= consts.BlockVersionSnapshots {
+		script += firstSnapshotsSchemaSQL + firstSnapshotContractsSQL
+	}
+	return script
+}
+
 // GetFirstEcosystemScript returns script to update with additional data for first ecosystem
 func GetFirstEcosystemScript() string {
 	scripts := []string{
@@ -264,4 +324,73 @@ var schemaEcosystem = `DROP TABLE IF EXISTS "%[1]d_keys"; CREATE TABLE "%[1]d_ke
 			"member_id" bigint NOT NULL DEFAULT '0'
 		);
 		ALTER TABLE ONLY "%[1]d_buffer_data" ADD CONSTRAINT "%[1]d_buffer_data_pkey" PRIMARY KEY ("id");
+
+		DROP TABLE IF EXISTS "%[1]d_events";
+		CREATE TABLE "%[1]d_events" (
+			"id" bigint NOT NULL DEFAULT '0',
+			"contract" varchar(255) NOT NULL DEFAULT '',
+			"name" varchar(255) NOT NULL DEFAULT '',
+			"data" jsonb NOT NULL DEFAULT '{}',
+			"key_id" bigint NOT NULL DEFAULT '0',
+			"block_id" bigint NOT NULL DEFAULT '0',
+			"txhash" bytea NOT NULL DEFAULT ''
+		);
+		ALTER TABLE ONLY "%[1]d_events" ADD CONSTRAINT "%[1]d_events_pkey" PRIMARY KEY ("id");
+		CREATE INDEX "%[1]d_events_index_contract" ON "%[1]d_events" (contract, name);
+		CREATE INDEX "%[1]d_events_index_name" ON "%[1]d_events" (name);
+		CREATE INDEX "%[1]d_events_index_block" ON "%[1]d_events" (block_id);
+		CREATE INDEX "%[1]d_events_index_data" ON "%[1]d_events" USING GIN (data jsonb_path_ops);
+`
+
+// apiKeysSchemaSQL creates the table of API keys in the ecosystem if it doesn't exist
+var apiKeysSchemaSQL = `CREATE TABLE IF NOT EXISTS "%[1]d_api_keys" (
+		"id" bigint NOT NULL DEFAULT '0',
+		"name" varchar(255) NOT NULL DEFAULT '',
+		"key_hash" varchar(64) NOT NULL DEFAULT '',
+		"key_id" bigint NOT NULL DEFAULT '0',
+		"role_id" bigint NOT NULL DEFAULT '0',
+		"routes" jsonb NOT NULL DEFAULT '[]',
+		"deleted" bigint NOT NULL DEFAULT '0',
+		CONSTRAINT "%[1]d_api_keys_pkey" PRIMARY KEY ("id")
+	);
+	CREATE UNIQUE INDEX IF NOT EXISTS "%[1]d_api_keys_index_key_hash" ON "%[1]d_api_keys" (key_hash);
+	INSERT INTO "%[1]d_tables" ("id", "name", "permissions", "columns", "conditions")
+	SELECT (SELECT COALESCE(max(id), 0) FROM "%[1]d_tables") + 1, 'api_keys',
+		'{"insert": "ContractAccess(\"NewAPIKey\")", "update": "ContractAccess(\"RevokeAPIKey\")",
+			"new_column": "ContractConditions(\"MainCondition\")"}',
+		'{"name": "false",
+			"key_hash": "false",
+			"key_id": "false",
+			"role_id": "false",
+			"routes": "false",
+			"deleted": "ContractAccess(\"RevokeAPIKey\")"}',
+		'ContractAccess("@1EditTable")'
+	WHERE NOT EXISTS (SELECT 1 FROM "%[1]d_tables" WHERE name = 'api_keys');
+`
+
+// webhooksSchemaSQL creates the table of webhooks in the ecosystem if it doesn't exist
+var webhooksSchemaSQL = `CREATE TABLE IF NOT EXISTS "%[1]d_webhooks" (
+		"id" bigint NOT NULL DEFAULT '0',
+		"name" varchar(255) NOT NULL DEFAULT '',
+		"url" varchar(1024) NOT NULL DEFAULT '',
+		"contracts" jsonb NOT NULL DEFAULT '[]',
+		"tables" jsonb NOT NULL DEFAULT '[]',
+		"node_id" bigint NOT NULL DEFAULT '0',
+		"secret_name" varchar(255) NOT NULL DEFAULT '',
+		"deleted" bigint NOT NULL DEFAULT '0',
+		CONSTRAINT "%[1]d_webhooks_pkey" PRIMARY KEY ("id")
+	);
+	INSERT INTO "%[1]d_tables" ("id", "name", "permissions", "columns", "conditions")
+	SELECT (SELECT COALESCE(max(id), 0) FROM "%[1]d_tables") + 1, 'webhooks',
+		'{"insert": "ContractConditions(\"MainCondition\")", "update": "ContractConditions(\"MainCondition\")",
+			"new_column": "ContractConditions(\"MainCondition\")"}',
+		'{"name": "ContractConditions(\"MainCondition\")",
+			"url": "ContractConditions(\"MainCondition\")",
+			"contracts": "ContractConditions(\"MainCondition\")",
+			"tables": "ContractConditions(\"MainCondition\")",
+			"node_id": "ContractConditions(\"MainCondition\")",
+			"secret_name": "ContractConditions(\"MainCondition\")",
+			"deleted": "ContractConditions(\"MainCondition\")"}',
+		'ContractAccess("@1EditTable")'
+	WHERE NOT EXISTS (SELECT 1 FROM "%[1]d_tables" WHERE name = 'webhooks');
 `
@@ -3,10 +3,63 @@ package migration
 import (
 	"fmt"
 	"io/ioutil"
+	"strings"
 	"testing"
+
+	"github.com/AplaProject/go-apla/packages/consts"
 )
 
 func TestGetEcosystemScript(t *testing.T) {
 	str := fmt.Sprintf(GetFirstEcosystemScript(), -1744264011260937456)
 	ioutil.WriteFile("/home/losaped/ecosystem_test.sql", []byte(str), 0777)
 }
+
+func TestEcosystemsMigration(t *testing.T) {
+	for table, script := range map[string]string{
+		"api_keys": migrationAPIKeysEcosystems,
+		"webhooks": migrationWebhooksEcosystems,
+	} {
+		// the script is the argument of format function, so it must not contain other verbs
+		rest := strings.NewReplacer(`%%`, ``, `%1$s`, ``, `%2$s`, ``, `%I`, ``).Replace(script)
+		if strings.Contains(rest, `%`) {
+			t.Errorf(`wrong format verbs in the migration of %s`, table)
+		}
+		if strings.Contains(script, `%[1]d`) || !strings.Contains(script, `"%1$s_`+table+`"`) {
+			t.Errorf(`the identifier of the ecosystem isn't replaced in the migration of %s`, table)
+		}
+	}
+}
+
+func TestFirstEcosystemMigration(t *testing.T) {
+	rest := strings.NewReplacer(`%%`, ``, `%1$s`, ``).Replace(migrationSnapshots)
+	if strings.Contains(rest, `%`) {
+		t.Error(`wrong format verbs in the migration of snapshots`)
+	}
+	if !strings.Contains(migrationSnapshots, `'%1$s'`) {
+		t.Error(`the wallet isn't replaced in the migration of snapshots`)
+	}
+}
+
+func TestGetFirstEcosystemFeaturesScript(t *testing.T) {
+	if strings.Contains(GetFirstEcosystemFeaturesScript(consts.BlockVersionSnapshots-1), `1_snapshots`) {
+		t.Error(`snapshots must not be created by the old blocks`)
+	}
+	if !strings.Contains(GetFirstEcosystemFeaturesScript(consts.BlockVersionSnapshots), `1_snapshots`) {
+		t.Error(`snapshots must be created by the new blocks`)
+	}
+}
+
+func TestGetEcosystemFeaturesScript(t *testing.T) {
+	if strings.Contains(GetEcosystemFeaturesScript(consts.BlockVersionAPIKeys-1), `_api_keys`) {
+		t.Error(`API keys must not be created by the old blocks`)
+	}
+	if !strings.Contains(GetEcosystemFeaturesScript(consts.BlockVersionAPIKeys), `_api_keys`) {
+		t.Error(`API keys must be created by the new blocks`)
+	}
+	if strings.Contains(GetEcosystemFeaturesScript(consts.BlockVersionWebhooks-1), `_webhooks`) {
+		t.Error(`webhooks must not be created by the old blocks`)
+	}
+	if !strings.Contains(GetEcosystemFeaturesScript(consts.BlockVersionWebhooks), `_webhooks`) {
+		t.Error(`webhooks must be created by the new blocks`)
+	}
+}
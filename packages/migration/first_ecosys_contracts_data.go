@@ -2614,3 +2614,52 @@ VALUES ('2', 'DelApplication', 'contract DelApplication {
     }
 }', %[1]d, 'ContractConditions("MainCondition")', 2);
 `
+
+// firstSnapshotContractsSQL adds the contracts of the state snapshots to the first ecosystem if they don't exist
+var firstSnapshotContractsSQL = `
+INSERT INTO "1_contracts" (id, name, value, wallet_id, conditions, app_id)
+SELECT (SELECT COALESCE(max(id), 0) FROM "1_contracts") + c.num, c.name, c.value, '%[1]d',
+	'ContractConditions("MainCondition")', c.app
+FROM (VALUES (1, 'NewSnapshot', 'contract NewSnapshot {
+    data {
+        BlockID int
+        Hash string
+    }
+
+    conditions {
+        ContractConditions("NodeOwnerCondition")
+        $Hash = ToLower($Hash)
+        if Size($Hash) != 64 {
+            warning "Hash must be the hex hash of the snapshot"
+        }
+        HexToBytes($Hash)
+        var interval int
+        interval = SysParamInt("snapshot_interval")
+        if interval <= 0 || $BlockID <= 0 || $BlockID / interval * interval != $BlockID || $BlockID >= $block {
+            warning Sprintf("Block %%d can not have snapshot", $BlockID)
+        }
+        if DBFind("@1_snapshots").Columns("id").Where("block_id = $", $BlockID).One("id") {
+            warning Sprintf("Snapshot of block %%d already exists", $BlockID)
+        }
+    }
+
+    action {
+        DBInsert("@1_snapshots", "block_id,hash,key_id,time", $BlockID, $Hash, $key_id, $block_time)
+    }
+}', 1),
+(2, 'snapshot_interval', 'contract snapshot_interval {
+    data {
+      Value string
+    }
+
+    conditions {
+      if Size($Value) == 0 {
+        warning "Value was not received"
+      }
+      if Int($Value) < 0 {
+        warning "Value must not be negative"
+      }
+    }
+}', 2)) AS c(num, name, value, app)
+WHERE NOT EXISTS (SELECT 1 FROM "1_contracts" WHERE name = c.name);
+`
@@ -68,3 +68,26 @@ DROP TABLE IF EXISTS "1_system_parameters";
 	);
 	ALTER TABLE ONLY "1_node_ban_logs" ADD CONSTRAINT "1_node_ban_logs_pkey" PRIMARY KEY ("id");
 `
+
+// firstSnapshotsSchemaSQL creates the table of state snapshots and the parameter of their interval
+// in the first ecosystem if they don't exist
+var firstSnapshotsSchemaSQL = `CREATE TABLE IF NOT EXISTS "1_snapshots" (
+		"id" bigint NOT NULL DEFAULT '0',
+		"block_id" bigint NOT NULL DEFAULT '0',
+		"hash" varchar(64) NOT NULL DEFAULT '',
+		"key_id" bigint NOT NULL DEFAULT '0',
+		"time" bigint NOT NULL DEFAULT '0',
+		CONSTRAINT "1_snapshots_pkey" PRIMARY KEY ("id")
+	);
+	CREATE UNIQUE INDEX IF NOT EXISTS "1_snapshots_index_block_id" ON "1_snapshots" ("block_id");
+	INSERT INTO "1_tables" ("id", "name", "permissions", "columns", "conditions")
+	SELECT (SELECT COALESCE(max(id), 0) FROM "1_tables") + 1, 'snapshots',
+		'{"insert": "ContractAccess(\"NewSnapshot\")", "update": "false",
+			"new_column": "ContractConditions(\"MainCondition\")"}',
+		'{"block_id": "false", "hash": "false", "key_id": "false", "time": "false"}',
+		'ContractConditions("MainCondition")'
+	WHERE NOT EXISTS (SELECT 1 FROM "1_tables" WHERE name = 'snapshots');
+	INSERT INTO "1_system_parameters" ("id", "name", "value", "conditions")
+	SELECT (SELECT COALESCE(max(id), 0) FROM "1_system_parameters") + 1, 'snapshot_interval', '10000', 'true'
+	WHERE NOT EXISTS (SELECT 1 FROM "1_system_parameters" WHERE name = 'snapshot_interval');
+`
@@ -13,6 +13,33 @@ var migrations = []*migration{
 
 	// Initial schema
 	&migration{"0.1.6b9", migrationInitialSchema},
+
+	// Events of contracts in every ecosystem
+	&migration{"0.1.6b14", migrationEvents},
+
+	// Block and key of logged transactions for the explorer
+	&migration{"0.1.6b15", migrationLogTxBlocks},
+
+	// API keys of ecosystems and the log of their usage
+	&migration{"0.1.6b16", migrationAPIKeys},
+
+	// Webhooks of ecosystems, their deliveries and dead letters
+	&migration{"0.1.6b17", migrationWebhooks},
+
+	// Error of transaction status contains the stack trace of contracts
+	&migration{"0.1.6b19", migrationTxStatusError},
+
+	// Fuel spent by logged transactions for the explorer
+	&migration{"0.1.6b20", migrationLogTxFuel},
+
+	// API keys of the existing ecosystems
+	&migration{"0.1.6b21", migrationAPIKeysEcosystems},
+
+	// Webhooks of the existing ecosystems
+	&migration{"0.1.6b22", migrationWebhooksEcosystems},
+
+	// Snapshots of the state for the fast synchronization
+	&migration{"0.1.6b23", migrationSnapshots},
 }
 
 type migration struct {
@@ -0,0 +1,68 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const apiKeyTableSuffix = "_api_keys"
+
+// APIKey is model
+type APIKey struct {
+	tableName string
+	ID        int64  `gorm:"primary_key;not null"`
+	Name      string `gorm:"not null"`
+	KeyHash   string `gorm:"not null"`
+	KeyID     int64  `gorm:"not null"`
+	RoleID    int64  `gorm:"not null"`
+	Routes    string `gorm:"not null;type:jsonb(PostgreSQL)"`
+	Deleted   int64  `gorm:"not null"`
+}
+
+// SetTablePrefix is setting table prefix
+func (m *APIKey) SetTablePrefix(prefix int64) *APIKey {
+	m.tableName = fmt.Sprintf("%d%s", prefix, apiKeyTableSuffix)
+	return m
+}
+
+// TableName returns name of table
+func (m APIKey) TableName() string {
+	return m.tableName
+}
+
+// GetByHash is retrieving the active API key by the hash of its secret
+func (m *APIKey) GetByHash(keyHash string) (bool, error) {
+	return isFound(DBConn.Where("key_hash = ? AND deleted = 0", keyHash).First(m))
+}
+
+// RouteList returns the allowed routes of API key
+func (m *APIKey) RouteList() ([]string, error) {
+	var routes []string
+	if len(m.Routes) == 0 {
+		return routes, nil
+	}
+	err := json.Unmarshal([]byte(m.Routes), &routes)
+	return routes, err
+}
+
+// APIKeyLog is model
+type APIKeyLog struct {
+	ID        int64  `gorm:"primary_key;not null"`
+	Ecosystem int64  `gorm:"not null"`
+	APIKeyID  int64  `gorm:"column:api_key_id;not null"`
+	KeyID     int64  `gorm:"not null"`
+	Route     string `gorm:"not null"`
+	Remote    string `gorm:"not null"`
+	Allowed   bool   `gorm:"not null"`
+	Time      int64  `gorm:"not null"`
+}
+
+// TableName returns name of table
+func (APIKeyLog) TableName() string {
+	return "api_key_log"
+}
+
+// Create is creating record of model
+func (l *APIKeyLog) Create() error {
+	return DBConn.Create(l).Error
+}
@@ -26,9 +26,9 @@ func (sp *AppParam) Get(transaction *DbTransaction, app int64, name string) (boo
 }
 
 // GetAllAppParameters is returning all state parameters
-func (sp *AppParam) GetAllAppParameters(app int64) ([]AppParam, error) {
+func (sp *AppParam) GetAllAppParameters(transaction *DbTransaction, app int64) ([]AppParam, error) {
 	parameters := make([]AppParam, 0)
-	err := DBConn.Table(sp.TableName()).Find(&parameters).Error
+	err := GetDB(transaction).Table(sp.TableName()).Find(&parameters).Error
 	if err != nil {
 		return nil, err
 	}
@@ -30,6 +30,13 @@ func (b *Block) Get(blockID int64) (bool, error) {
 	return isFound(DBConn.Where("id = ?", blockID).First(b))
 }
 
+// IsBlock returns true if the block is in the blockchain
+func IsBlock(blockID int64) (bool, error) {
+	var count int64
+	err := DBConn.Model(&Block{}).Where("id = ?", blockID).Count(&count).Error
+	return count > 0, err
+}
+
 // GetMaxBlock returns last block existence
 func (b *Block) GetMaxBlock() (bool, error) {
 	return isFound(DBConn.Last(b))
@@ -79,6 +86,13 @@ func (b *Block) GetBlocksFrom(startFromID int64, ordering string, limit int32) (
 	return *blockchain, err
 }
 
+// GetBlockHashes returns the hashes of blocks from startID in ascending order
+func GetBlockHashes(startID int64, limit int32) ([][]byte, error) {
+	var hashes [][]byte
+	err := DBConn.Model(&Block{}).Where("id >= ?", startID).Order("id").Limit(limit).Pluck("hash", &hashes).Error
+	return hashes, err
+}
+
 // GetReverseBlockchain returns records of blocks in reverse ordering
 func (b *Block) GetReverseBlockchain(endBlockID int64, limit int32) ([]Block, error) {
 	var err error
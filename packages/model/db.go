@@ -11,6 +11,7 @@ import (
 	"github.com/AplaProject/go-apla/packages/crypto"
 	"github.com/AplaProject/go-apla/packages/migration"
 	"github.com/AplaProject/go-apla/packages/migration/vde"
+	"github.com/AplaProject/go-apla/packages/txstream"
 
 	"github.com/jinzhu/gorm"
 	log "github.com/sirupsen/logrus"
@@ -64,7 +65,8 @@ func GormClose() error {
 
 // DbTransaction is gorm.DB wrapper
 type DbTransaction struct {
-	conn *gorm.DB
+	conn     *gorm.DB
+	onCommit []func()
 }
 
 // StartTransaction is beginning transaction
@@ -82,12 +84,29 @@ func StartTransaction() (*DbTransaction, error) {
 
 // Rollback is transaction rollback
 func (tr *DbTransaction) Rollback() {
+	tr.onCommit = nil
 	tr.conn.Rollback()
 }
 
 // Commit is transaction commit
 func (tr *DbTransaction) Commit() error {
-	return tr.conn.Commit().Error
+	if err := tr.conn.Commit().Error; err != nil {
+		return err
+	}
+	for _, f := range tr.onCommit {
+		f()
+	}
+	tr.onCommit = nil
+	return nil
+}
+
+// OnCommit calls f after the transaction has been committed. f is called at once if there is no transaction.
+func (tr *DbTransaction) OnCommit(f func()) {
+	if tr == nil || tr.conn == nil {
+		f()
+		return
+	}
+	tr.onCommit = append(tr.onCommit, f)
 }
 
 // Connection returns connection of database
@@ -110,6 +129,13 @@ func (tr *DbTransaction) ReleaseSavepoint(idTx int) error {
 	return tr.Connection().Exec(fmt.Sprintf("RELEASE SAVEPOINT \"tx-%d\";", idTx)).Error
 }
 
+// SetTimeouts limits the time of waiting for locks and of the execution of statements
+// until the end of the transaction
+func (tr *DbTransaction) SetTimeouts(lockTimeout, statementTimeout time.Duration) error {
+	return tr.Connection().Exec(fmt.Sprintf("SET LOCAL lock_timeout = %d; SET LOCAL statement_timeout = %d;",
+		lockTimeout/time.Millisecond, statementTimeout/time.Millisecond)).Error
+}
+
 // GetDB is returning gorm.DB
 func GetDB(tr *DbTransaction) *gorm.DB {
 	if tr != nil && tr.conn != nil {
@@ -138,8 +164,9 @@ func GetRecordsCountTx(db *DbTransaction, tableName string) (int64, error) {
 	return count, err
 }
 
-// ExecSchemaEcosystem is executing ecosystem schema
-func ExecSchemaEcosystem(db *DbTransaction, id int, wallet int64, name string, founder int64) error {
+// ExecSchemaEcosystem is executing ecosystem schema, the features of the ecosystem depend on the version
+// of the block which creates it
+func ExecSchemaEcosystem(db *DbTransaction, id int, wallet int64, name string, founder int64, blockVersion int) error {
 	q := fmt.Sprintf(migration.GetEcosystemScript(), id, wallet, name, founder)
 	if err := GetDB(db).Exec(q).Error; err != nil {
 		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("executing ecosystem schema")
@@ -151,6 +178,18 @@ func ExecSchemaEcosystem(db *DbTransaction, id int, wallet int64, name string, f
 			log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("executing first ecosystem schema")
 		}
 	}
+	if script := migration.GetEcosystemFeaturesScript(blockVersion); len(script) > 0 {
+		if err := GetDB(db).Exec(fmt.Sprintf(script, id, wallet)).Error; err != nil {
+			log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("executing ecosystem features schema")
+			return err
+		}
+	}
+	if script := migration.GetFirstEcosystemFeaturesScript(blockVersion); id == 1 && len(script) > 0 {
+		if err := GetDB(db).Exec(fmt.Sprintf(script, wallet)).Error; err != nil {
+			log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("executing first ecosystem features schema")
+			return err
+		}
+	}
 	return nil
 }
 
@@ -210,7 +249,9 @@ func SendTx(txType int64, adminWallet int64, data []byte) ([]byte, error) {
 		Hash: hash,
 		Data: data,
 	}
-	err = qtx.Create()
+	if err = qtx.Create(); err == nil {
+		txstream.Queued(hash)
+	}
 	return hash, err
 }
 
@@ -0,0 +1,92 @@
+package model
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+const eventsTableSuffix = "_events"
+
+// Event is the event which has been emitted by the contract with EmitEvent function
+type Event struct {
+	tableName string
+	ID        int64  `gorm:"primary_key;not null" json:"id"`
+	Ecosystem int64  `gorm:"-" json:"ecosystem"`
+	Contract  string `gorm:"not null;size:255" json:"contract"`
+	Name      string `gorm:"not null;size:255" json:"name"`
+	Data      string `gorm:"not null;type:jsonb(PostgreSQL)" json:"-"`
+	KeyID     int64  `gorm:"not null" json:"key_id"`
+	BlockID   int64  `gorm:"not null" json:"block_id"`
+	TxHash    []byte `gorm:"column:txhash;not null" json:"-"`
+}
+
+// EventFilter contains the conditions of selecting events
+type EventFilter struct {
+	Contract  string
+	Name      string
+	Data      string // JSON object, the data of events must contain all its fields
+	FromBlock int64
+	ToBlock   int64
+	Offset    int64
+	Limit     int64
+}
+
+// SetTablePrefix is setting table prefix
+func (e *Event) SetTablePrefix(prefix int64) *Event {
+	e.Ecosystem = prefix
+	e.tableName = EventsTableName(prefix)
+	return e
+}
+
+// TableName returns table name
+func (e *Event) TableName() string {
+	return e.tableName
+}
+
+// MarshalJSON encodes the event with the data as JSON object
+func (e Event) MarshalJSON() ([]byte, error) {
+	type event Event
+	data := e.Data
+	if len(data) == 0 {
+		data = `{}`
+	}
+	return json.Marshal(struct {
+		event
+		Data   json.RawMessage `json:"data"`
+		TxHash string          `json:"txhash"`
+	}{event(e), json.RawMessage(data), hex.EncodeToString(e.TxHash)})
+}
+
+// GetEvents returns the events of the ecosystem which match the filter
+func GetEvents(ecosystem int64, filter *EventFilter) ([]Event, error) {
+	query := DBConn.Table(EventsTableName(ecosystem))
+	if len(filter.Contract) > 0 {
+		query = query.Where("contract = ?", filter.Contract)
+	}
+	if len(filter.Name) > 0 {
+		query = query.Where("name = ?", filter.Name)
+	}
+	if len(filter.Data) > 0 {
+		query = query.Where("data @> ?::jsonb", filter.Data)
+	}
+	if filter.FromBlock > 0 {
+		query = query.Where("block_id >= ?", filter.FromBlock)
+	}
+	if filter.ToBlock > 0 {
+		query = query.Where("block_id <= ?", filter.ToBlock)
+	}
+	events := make([]Event, 0)
+	if err := query.Order("id").Offset(filter.Offset).Limit(filter.Limit).Find(&events).Error; err != nil {
+		return nil, err
+	}
+	for i := range events {
+		events[i].Ecosystem = ecosystem
+	}
+	return events, nil
+}
+
+// EventsTableName returns name of events table
+func EventsTableName(prefix int64) string {
+	return fmt.Sprintf("%d%s", prefix, eventsTableSuffix)
+}
@@ -0,0 +1,25 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventMarshalJSON(t *testing.T) {
+	event := Event{ID: 3, Contract: `@1Transfer`, Name: `paid`, Data: `{"amount":"10"}`, KeyID: -5,
+		BlockID: 12, TxHash: []byte{0xab, 0x01}}
+	event.SetTablePrefix(2)
+	assert.Equal(t, `2_events`, event.TableName())
+
+	out, err := json.Marshal(event)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"id":3,"ecosystem":2,"contract":"@1Transfer","name":"paid","key_id":-5,`+
+		`"block_id":12,"data":{"amount":"10"},"txhash":"ab01"}`, string(out))
+
+	out, err = json.Marshal([]Event{{Name: `empty`}})
+	assert.NoError(t, err)
+	assert.Equal(t, `[{"id":0,"ecosystem":0,"contract":"","name":"empty","key_id":0,"block_id":0,`+
+		`"data":{},"txhash":""}]`, string(out))
+}
@@ -3,6 +3,7 @@ package model
 import (
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/AplaProject/go-apla/packages/consts"
@@ -11,6 +12,9 @@ import (
 
 const historyTableSuffix = "_history"
 
+// CommissionComment is the comment of the history records of payments for the execution of contracts
+const CommissionComment = "Commission for execution of %s contract"
+
 var errLowBalance = errors.New("not enough APL on the balance")
 
 // History represent record of history table
@@ -64,6 +68,20 @@ func GetExcessCommonTokenMovementPerDay(tx *DbTransaction) (amount decimal.Decim
 	return res.Amount, err
 }
 
+// GetTxCommission returns the sum which has been paid for the execution of the contract of the transaction
+func GetTxCommission(tableName string, blockID int64, txHash []byte) (decimal.Decimal, error) {
+	type result struct {
+		Amount decimal.Decimal
+	}
+
+	var res result
+	err := DBConn.Table(tableName).Select("COALESCE(SUM(amount), 0) as amount").
+		Where("block_id = ? AND txhash = ? AND comment LIKE ?", blockID, txHash,
+			strings.Replace(CommissionComment, "%s", "%", 1)).Scan(&res).Error
+
+	return res.Amount, err
+}
+
 // GetExcessFromToTokenMovementPerDay returns from to pairs where sum of amount greather than fromToPerDayLimit per 24 hours
 func GetExcessFromToTokenMovementPerDay(tx *DbTransaction) (excess []APLTransfer, err error) {
 	db := GetDB(tx)
@@ -2,8 +2,11 @@ package model
 
 // LogTransaction is model
 type LogTransaction struct {
-	Hash []byte `gorm:"primary_key;not null"`
-	Time int64  `gorm:"not null"`
+	Hash    []byte `gorm:"primary_key;not null"`
+	Time    int64  `gorm:"not null"`
+	BlockID int64  `gorm:"not null"`
+	KeyID   int64  `gorm:"not null"`
+	Fuel    int64  `gorm:"not null"`
 }
 
 // GetByHash returns LogTransactions existence by hash
@@ -30,3 +33,41 @@ func GetLogTransactionsCount(hash []byte) (int64, error) {
 	}
 	return rowsCount, nil
 }
+
+// GetLogTransactionsByKey returns the transactions of the key starting with the last block
+func GetLogTransactionsByKey(keyID int64, offset, limit int) ([]LogTransaction, error) {
+	var list []LogTransaction
+	err := DBConn.Where("key_id = ?", keyID).Order("block_id desc, time desc").Offset(offset).
+		Limit(limit).Find(&list).Error
+	return list, err
+}
+
+// GetLogTransactionsFuel returns the fuel spent by the logged transactions of the block by their hashes
+func GetLogTransactionsFuel(blockID int64) (map[string]int64, error) {
+	var list []LogTransaction
+	if err := DBConn.Where("block_id = ?", blockID).Find(&list).Error; err != nil {
+		return nil, err
+	}
+	fuel := make(map[string]int64, len(list))
+	for _, ltx := range list {
+		fuel[string(ltx.Hash)] = ltx.Fuel
+	}
+	return fuel, nil
+}
+
+// GetLogTransactionsWithoutBlockCount returns the number of the transactions which have been logged
+// without the block
+func GetLogTransactionsWithoutBlockCount() (int64, error) {
+	var rowsCount int64
+	if err := DBConn.Table("log_transactions").Where("block_id = 0").Count(&rowsCount).Error; err != nil {
+		return 0, err
+	}
+	return rowsCount, nil
+}
+
+// SetLogTransactionBlock sets the block and the key of the transaction if they haven't been logged
+func SetLogTransactionBlock(hash []byte, blockID, keyID int64) (int64, error) {
+	query := DBConn.Exec("UPDATE log_transactions SET block_id = ?, key_id = ? WHERE hash = ? AND block_id = 0",
+		blockID, keyID, hash)
+	return query.RowsAffected, query.Error
+}
@@ -1,5 +1,13 @@
 package model
 
+import (
+	"fmt"
+	"strings"
+
+	"github.com/AplaProject/go-apla/packages/consts"
+	"github.com/AplaProject/go-apla/packages/converter"
+)
+
 // RollbackTx is model
 type RollbackTx struct {
 	ID        int64  `gorm:"primary_key;not null" json:"-"`
@@ -56,3 +64,62 @@ func (rt *RollbackTx) Create(transaction *DbTransaction) error {
 func (rt *RollbackTx) Get(dbTransaction *DbTransaction, transactionHash []byte, tableName string) (bool, error) {
 	return isFound(GetDB(dbTransaction).Where("tx_hash = ? AND table_name = ?", transactionHash, tableName).First(rt))
 }
+
+// ErrAsOfBlockDepth is returned if the block of as_of_block query is too old
+var ErrAsOfBlockDepth = fmt.Errorf("block must be not older than %d blocks", consts.AsOfBlockDepth)
+
+// GetTableAsOfBlock returns SQL expression which can be used in the queries instead of the table.
+// It contains the rows of the table as they were after the specified block. The current values
+// are replaced with the values from rollback_tx records which have been written after the block,
+// the rows inserted after the block are skipped. The block can't be older than AsOfBlockDepth blocks.
+func GetTableAsOfBlock(transaction *DbTransaction, tableName string, blockID int64) (string, error) {
+	escaped := `"` + strings.Replace(tableName, `"`, `""`, -1) + `"`
+	ib := &InfoBlock{}
+	if err := GetDB(transaction).Last(ib).Error; err != nil {
+		return ``, err
+	}
+	if ib.BlockID-blockID > consts.AsOfBlockDepth {
+		return ``, ErrAsOfBlockDepth
+	}
+	if blockID >= ib.BlockID {
+		return escaped, nil
+	}
+	schema, err := GetTableSchema(transaction, tableName)
+	if err != nil {
+		return ``, err
+	}
+	return tableAsOfBlock(tableName, schema.Columns, blockID), nil
+}
+
+// tableAsOfBlock builds the expression of the table. Every rollback_tx record contains the previous
+// values of the changed columns, so the value of the column is taken from the first record after
+// the block which contains this column. The record with empty data means that the row has been inserted.
+func tableAsOfBlock(tableName string, columns []TableColumn, blockID int64) string {
+	escaped := `"` + strings.Replace(tableName, `"`, `""`, -1) + `"`
+	exprs := make([]string, len(columns))
+	for i, col := range columns {
+		name := strings.Replace(col.Name, `"`, `""`, -1)
+		key := `'` + strings.Replace(col.Name, `'`, `''`, -1) + `'`
+		value := fmt.Sprintf(`NULLIF("_h".data ->> %s, 'NULL')`, key)
+		switch {
+		case converter.IsByteColumn(tableName, col.Name):
+			// these columns are written to rollback_tx in the hexadecimal form
+			value = fmt.Sprintf(`decode(%s, 'hex')`, value)
+		case col.Type == `bytea`:
+			value = fmt.Sprintf(`convert_to(%s, 'UTF8')`, value)
+		default:
+			value = fmt.Sprintf(`CAST(%s AS %s)`, value, col.Type)
+		}
+		exprs[i] = fmt.Sprintf(`CASE WHEN "_h".data -> %s IS NOT NULL THEN %s ELSE "_t"."%s" END AS "%[3]s"`,
+			key, value, name)
+	}
+	return fmt.Sprintf(`(SELECT %s FROM %s AS "_t" LEFT JOIN `+
+		`(SELECT table_id, bool_or(inserted) AS inserted, `+
+		`jsonb_object_agg(key, value) FILTER (WHERE key IS NOT NULL) AS data FROM `+
+		`(SELECT DISTINCT ON ("_r".table_id, "_v".key) "_r".table_id, "_r".data = '' AS inserted, "_v".key, "_v".value `+
+		`FROM rollback_tx AS "_r" LEFT JOIN LATERAL jsonb_each(NULLIF("_r".data, '')::jsonb) AS "_v" ON true `+
+		`WHERE "_r".table_name = '%s' AND "_r".block_id > %d ORDER BY "_r".table_id, "_v".key, "_r".id) AS "_c" `+
+		`GROUP BY table_id) AS "_h" ON "_h".table_id = "_t".id::text `+
+		`WHERE "_h".inserted IS NOT TRUE) AS %[2]s`,
+		strings.Join(exprs, `, `), escaped, strings.Replace(tableName, `'`, `''`, -1), blockID)
+}
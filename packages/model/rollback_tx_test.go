@@ -0,0 +1,31 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTableAsOfBlock(t *testing.T) {
+	columns := []TableColumn{{Name: `id`, Type: `bigint`}, {Name: `pub`, Type: `bytea`},
+		{Name: `name`, Type: `character varying(255)`}}
+	out := tableAsOfBlock(`1_keys`, columns, 10)
+	assert.Equal(t, `(SELECT `+
+		`CASE WHEN "_h".data -> 'id' IS NOT NULL THEN CAST(NULLIF("_h".data ->> 'id', 'NULL') AS bigint) ELSE "_t"."id" END AS "id", `+
+		`CASE WHEN "_h".data -> 'pub' IS NOT NULL THEN decode(NULLIF("_h".data ->> 'pub', 'NULL'), 'hex') ELSE "_t"."pub" END AS "pub", `+
+		`CASE WHEN "_h".data -> 'name' IS NOT NULL THEN CAST(NULLIF("_h".data ->> 'name', 'NULL') AS character varying(255)) ELSE "_t"."name" END AS "name" `+
+		`FROM "1_keys" AS "_t" LEFT JOIN (SELECT table_id, bool_or(inserted) AS inserted, `+
+		`jsonb_object_agg(key, value) FILTER (WHERE key IS NOT NULL) AS data FROM `+
+		`(SELECT DISTINCT ON ("_r".table_id, "_v".key) "_r".table_id, "_r".data = '' AS inserted, "_v".key, "_v".value `+
+		`FROM rollback_tx AS "_r" LEFT JOIN LATERAL jsonb_each(NULLIF("_r".data, '')::jsonb) AS "_v" ON true `+
+		`WHERE "_r".table_name = '1_keys' AND "_r".block_id > 10 ORDER BY "_r".table_id, "_v".key, "_r".id) AS "_c" `+
+		`GROUP BY table_id) AS "_h" ON "_h".table_id = "_t".id::text WHERE "_h".inserted IS NOT TRUE) AS "1_keys"`, out)
+}
+
+func TestTableAsOfBlockBytes(t *testing.T) {
+	columns := []TableColumn{{Name: `data`, Type: `bytea`}}
+	assert.Contains(t, tableAsOfBlock(`1_binaries`, columns, 10),
+		`THEN decode(NULLIF("_h".data ->> 'data', 'NULL'), 'hex') ELSE "_t"."data" END AS "data"`)
+	assert.Contains(t, tableAsOfBlock(`1_mytable`, columns, 10),
+		`THEN convert_to(NULLIF("_h".data ->> 'data', 'NULL'), 'UTF8') ELSE "_t"."data" END AS "data"`)
+}
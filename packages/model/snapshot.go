@@ -0,0 +1,271 @@
+package model
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Snapshot is the hash of the state snapshot which has been committed to the blockchain
+type Snapshot struct {
+	ID      int64
+	BlockID int64
+	Hash    string
+	KeyID   int64
+	Time    int64
+}
+
+// TableName returns name of table
+func (Snapshot) TableName() string {
+	return "1_snapshots"
+}
+
+// GetByBlockID is retrieving the snapshot of the block
+func (s *Snapshot) GetByBlockID(blockID int64) (bool, error) {
+	return isFound(DBConn.Where("block_id = ?", blockID).First(s))
+}
+
+// GetCommitBlockID returns the block which contains the transaction of the snapshot
+func (s *Snapshot) GetCommitBlockID() (int64, bool, error) {
+	rb := &RollbackTx{}
+	found, err := isFound(DBConn.Where("table_name = ? AND table_id = ?", s.TableName(), fmt.Sprint(s.ID)).First(rb))
+	return rb.BlockID, found, err
+}
+
+// TableColumn is the column of the table in the state snapshot
+type TableColumn struct {
+	Name    string
+	Type    string
+	NotNull bool
+	Default string
+}
+
+// TableSchema is the definition of the table in the state snapshot
+type TableSchema struct {
+	Name        string
+	Columns     []TableColumn
+	Constraints []string // CONSTRAINT "name" definition
+	Indexes     []string // CREATE INDEX statements
+	PrimaryKey  []string
+}
+
+// StartReadOnlyTransaction starts the transaction which sees the state of the database at the moment of the call
+func StartReadOnlyTransaction() (*DbTransaction, error) {
+	transaction, err := StartTransaction()
+	if err != nil {
+		return nil, err
+	}
+	if err = transaction.conn.Exec(`SET TRANSACTION ISOLATION LEVEL REPEATABLE READ READ ONLY`).Error; err != nil {
+		transaction.Rollback()
+		return nil, err
+	}
+	// the snapshot of the database is taken by the first query of the transaction
+	var one int
+	if err = transaction.conn.Raw(`SELECT 1`).Row().Scan(&one); err != nil {
+		transaction.Rollback()
+		return nil, err
+	}
+	return transaction, nil
+}
+
+// GetStateTables returns the tables of the state, these are the tables of ecosystems and the log of transactions
+func GetStateTables(transaction *DbTransaction) ([]string, error) {
+	var tables []string
+	err := GetDB(transaction).Raw(`SELECT table_name FROM information_schema.tables
+		WHERE table_schema = 'public' AND table_type = 'BASE TABLE' AND
+			(table_name ~ '^[0-9]+_' AND table_name !~ '^[0-9]+_vde_' OR table_name = 'log_transactions')
+		ORDER BY table_name COLLATE "C"`).Pluck("table_name", &tables).Error
+	return tables, err
+}
+
+// IsStateTable returns true if the table belongs to the state
+func IsStateTable(name string) bool {
+	if name == "log_transactions" {
+		return true
+	}
+	off := strings.IndexByte(name, '_')
+	if off <= 0 || strings.HasPrefix(name[off:], "_vde_") || strings.ContainsAny(name, `"\`) {
+		return false
+	}
+	for _, ch := range name[:off] {
+		if ch < '0' || ch > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// GetTableSchema returns the definition of the table
+func GetTableSchema(transaction *DbTransaction, name string) (*TableSchema, error) {
+	db := GetDB(transaction)
+	table := fmt.Sprintf(`"%s"`, name)
+	schema := &TableSchema{Name: name}
+	rows, err := db.Raw(`SELECT a.attname, format_type(a.atttypid, a.atttypmod), a.attnotnull,
+			COALESCE(pg_get_expr(d.adbin, d.adrelid), '')
+		FROM pg_attribute a LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+		WHERE a.attrelid = ?::regclass AND a.attnum > 0 AND NOT a.attisdropped ORDER BY a.attnum`, table).Rows()
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var column TableColumn
+		if err = rows.Scan(&column.Name, &column.Type, &column.NotNull, &column.Default); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		schema.Columns = append(schema.Columns, column)
+	}
+	rows.Close()
+
+	rows, err = db.Raw(`SELECT conname, pg_get_constraintdef(oid) FROM pg_constraint
+		WHERE conrelid = ?::regclass ORDER BY conname COLLATE "C"`, table).Rows()
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var conName, conDef string
+		if err = rows.Scan(&conName, &conDef); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		schema.Constraints = append(schema.Constraints, fmt.Sprintf(`CONSTRAINT "%s" %s`, conName, conDef))
+	}
+	rows.Close()
+
+	err = db.Raw(`SELECT indexdef FROM pg_indexes WHERE schemaname = 'public' AND tablename = ? AND
+			indexname NOT IN (SELECT conname FROM pg_constraint WHERE conrelid = ?::regclass)
+		ORDER BY indexname COLLATE "C"`, name, table).Pluck("indexdef", &schema.Indexes).Error
+	if err != nil {
+		return nil, err
+	}
+	err = db.Raw(`SELECT a.attname FROM pg_index i
+		JOIN pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = ANY(i.indkey)
+		WHERE i.indrelid = ?::regclass AND i.indisprimary
+		ORDER BY array_position(i.indkey::int2[], a.attnum)`, table).Pluck("attname", &schema.PrimaryKey).Error
+	return schema, err
+}
+
+func isTextType(columnType string) bool {
+	return strings.HasPrefix(columnType, "character") || columnType == "text"
+}
+
+// ReadTableRows calls f for every row of the table in the order of the primary key. The values are
+// the text representations of the columns or nil.
+func ReadTableRows(transaction *DbTransaction, schema *TableSchema, f func([]interface{}) error) error {
+	columns := make([]string, 0, len(schema.Columns))
+	types := make(map[string]string)
+	for _, column := range schema.Columns {
+		columns = append(columns, fmt.Sprintf(`"%s"::text`, column.Name))
+		types[column.Name] = column.Type
+	}
+	order := make([]string, 0, len(columns))
+	for _, name := range schema.PrimaryKey {
+		if isTextType(types[name]) {
+			// the order mustn't depend on the locale of the database
+			order = append(order, fmt.Sprintf(`"%s" COLLATE "C"`, name))
+		} else {
+			order = append(order, fmt.Sprintf(`"%s"`, name))
+		}
+	}
+	if len(order) == 0 {
+		for i := range columns {
+			order = append(order, fmt.Sprint(i+1))
+		}
+	}
+	rows, err := GetDB(transaction).Raw(fmt.Sprintf(`SELECT %s FROM "%s" ORDER BY %s`,
+		strings.Join(columns, ","), schema.Name, strings.Join(order, ","))).Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		values := make([]sql.NullString, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err = rows.Scan(pointers...); err != nil {
+			return err
+		}
+		row := make([]interface{}, len(columns))
+		for i, value := range values {
+			if value.Valid {
+				row[i] = value.String
+			}
+		}
+		if err = f(row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// CreateSnapshotTable drops the table if it exists and creates it by the definition
+func CreateSnapshotTable(transaction *DbTransaction, schema *TableSchema) error {
+	db := GetDB(transaction)
+	if err := db.Exec(fmt.Sprintf(`DROP TABLE IF EXISTS "%s"`, schema.Name)).Error; err != nil {
+		return err
+	}
+	defs := make([]string, 0, len(schema.Columns)+len(schema.Constraints))
+	for _, column := range schema.Columns {
+		def := fmt.Sprintf(`"%s" %s`, column.Name, column.Type)
+		if column.NotNull {
+			def += ` NOT NULL`
+		}
+		if len(column.Default) > 0 {
+			def += ` DEFAULT ` + column.Default
+		}
+		defs = append(defs, def)
+	}
+	defs = append(defs, schema.Constraints...)
+	if err := db.Exec(fmt.Sprintf(`CREATE TABLE "%s" (%s)`, schema.Name, strings.Join(defs, ","))).Error; err != nil {
+		return err
+	}
+	for _, index := range schema.Indexes {
+		if err := db.Exec(index).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// InsertSnapshotRows inserts the rows which have been read by ReadTableRows
+func InsertSnapshotRows(transaction *DbTransaction, schema *TableSchema, rows [][]interface{}) error {
+	if len(rows) == 0 || len(schema.Columns) == 0 {
+		return nil
+	}
+	columns := make([]string, 0, len(schema.Columns))
+	casts := make([]string, 0, len(schema.Columns))
+	for _, column := range schema.Columns {
+		columns = append(columns, fmt.Sprintf(`"%s"`, column.Name))
+		casts = append(casts, fmt.Sprintf(`CAST(? AS %s)`, column.Type))
+	}
+	valueTemplate := fmt.Sprintf("(%s)", strings.Join(casts, ","))
+	// PostgreSQL doesn't allow more than 65535 parameters in the query
+	batch := 65535 / len(columns)
+	if batch > maxBatchRows {
+		batch = maxBatchRows
+	}
+	for len(rows) > 0 {
+		count := len(rows)
+		if count > batch {
+			count = batch
+		}
+		templates := make([]string, 0, count)
+		values := make([]interface{}, 0, count*len(columns))
+		for _, row := range rows[:count] {
+			if len(row) != len(columns) {
+				return fmt.Errorf("wrong number of values of table %s", schema.Name)
+			}
+			templates = append(templates, valueTemplate)
+			values = append(values, row...)
+		}
+		query := fmt.Sprintf(`INSERT INTO "%s" (%s) VALUES %s`, schema.Name, strings.Join(columns, ","),
+			strings.Join(templates, ","))
+		if err := GetDB(transaction).Exec(query, values...).Error; err != nil {
+			return err
+		}
+		rows = rows[count:]
+	}
+	return nil
+}
@@ -29,9 +29,9 @@ func (sp *StateParameter) Get(transaction *DbTransaction, name string) (bool, er
 }
 
 // GetAllStateParameters is returning all state parameters
-func (sp *StateParameter) GetAllStateParameters() ([]StateParameter, error) {
+func (sp *StateParameter) GetAllStateParameters(transaction *DbTransaction) ([]StateParameter, error) {
 	parameters := make([]StateParameter, 0)
-	err := DBConn.Table(sp.TableName()).Find(&parameters).Error
+	err := GetDB(transaction).Table(sp.TableName()).Find(&parameters).Error
 	if err != nil {
 		return nil, err
 	}
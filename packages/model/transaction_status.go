@@ -7,7 +7,7 @@ type TransactionStatus struct {
 	Type     int64  `gorm:"not null"`
 	WalletID int64  `gorm:"not null"`
 	BlockID  int64  `gorm:"not null"`
-	Error    string `gorm:"not null;size 255"`
+	Error    string `gorm:"not null;size 1024"`
 }
 
 // TableName returns name of table
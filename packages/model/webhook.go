@@ -0,0 +1,195 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const webhookTableSuffix = "_webhooks"
+
+// The statuses of webhook deliveries
+const (
+	WebhookPending   = "pending"
+	WebhookDelivered = "delivered"
+	WebhookFailed    = "failed"
+	WebhookCanceled  = "canceled"
+)
+
+// Webhook is model
+type Webhook struct {
+	tableName  string
+	ID         int64  `gorm:"primary_key;not null"`
+	Name       string `gorm:"not null"`
+	URL        string `gorm:"column:url;not null"`
+	Contracts  string `gorm:"not null;type:jsonb(PostgreSQL)"`
+	Tables     string `gorm:"not null;type:jsonb(PostgreSQL)"`
+	NodeID     int64  `gorm:"column:node_id;not null"`
+	SecretName string `gorm:"not null"`
+	Deleted    int64  `gorm:"not null"`
+}
+
+// SetTablePrefix is setting table prefix
+func (w *Webhook) SetTablePrefix(prefix int64) *Webhook {
+	w.tableName = fmt.Sprintf("%d%s", prefix, webhookTableSuffix)
+	return w
+}
+
+// TableName returns name of table
+func (w Webhook) TableName() string {
+	return w.tableName
+}
+
+// Get is retrieving model from database
+func (w *Webhook) Get(id int64) (bool, error) {
+	return isFound(DBConn.Where("id = ?", id).First(w))
+}
+
+// ContractList returns the names of contracts which are watched by webhook
+func (w *Webhook) ContractList() ([]string, error) {
+	return unmarshalList(w.Contracts)
+}
+
+// TableList returns the names of tables which are watched by webhook
+func (w *Webhook) TableList() ([]string, error) {
+	return unmarshalList(w.Tables)
+}
+
+func unmarshalList(data string) ([]string, error) {
+	var list []string
+	if len(data) == 0 {
+		return list, nil
+	}
+	err := json.Unmarshal([]byte(data), &list)
+	return list, err
+}
+
+// GetActiveWebhooks returns the webhooks of ecosystem which have not been deleted and are sent by the node
+func GetActiveWebhooks(ecosystemID, nodeID int64) ([]Webhook, error) {
+	var webhooks []Webhook
+	err := DBConn.Table(fmt.Sprintf("%d%s", ecosystemID, webhookTableSuffix)).
+		Where("deleted = 0 and node_id = ?", nodeID).Order("id").Find(&webhooks).Error
+	return webhooks, err
+}
+
+// WebhookDelivery is model
+type WebhookDelivery struct {
+	ID           int64  `gorm:"primary_key;not null" json:"id"`
+	Ecosystem    int64  `gorm:"not null" json:"ecosystem"`
+	WebhookID    int64  `gorm:"not null" json:"webhook_id"`
+	BlockID      int64  `gorm:"not null" json:"block_id"`
+	TxHash       []byte `gorm:"not null" json:"-"`
+	Payload      string `gorm:"not null" json:"payload"`
+	Status       string `gorm:"not null" json:"status"`
+	Attempts     int64  `gorm:"not null" json:"attempts"`
+	NextAttempt  int64  `gorm:"not null" json:"next_attempt"`
+	ResponseCode int64  `gorm:"not null" json:"response_code"`
+	Error        string `gorm:"not null" json:"error"`
+	CreatedAt    int64  `gorm:"not null" json:"created_at"`
+	DeliveredAt  int64  `gorm:"not null" json:"delivered_at"`
+}
+
+// TableName returns name of table
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
+
+// Create is creating record of model
+func (d *WebhookDelivery) Create(transaction *DbTransaction) error {
+	return GetDB(transaction).Create(d).Error
+}
+
+// Save is saving record of model
+func (d *WebhookDelivery) Save() error {
+	return DBConn.Save(d).Error
+}
+
+// GetPendingWebhookDeliveries returns the deliveries which must be sent at the specified time
+func GetPendingWebhookDeliveries(now int64, limit int) ([]WebhookDelivery, error) {
+	var deliveries []WebhookDelivery
+	err := DBConn.Where("status = ? AND next_attempt <= ?", WebhookPending, now).
+		Order("next_attempt, id").Limit(limit).Find(&deliveries).Error
+	return deliveries, err
+}
+
+// GetWebhookDeliveries returns the deliveries of ecosystem. The webhook and the status are optional.
+func GetWebhookDeliveries(ecosystemID, webhookID int64, status string, offset, limit int64) ([]WebhookDelivery, int64, error) {
+	var (
+		deliveries []WebhookDelivery
+		count      int64
+	)
+	query := DBConn.Model(&WebhookDelivery{}).Where("ecosystem = ?", ecosystemID)
+	if webhookID > 0 {
+		query = query.Where("webhook_id = ?", webhookID)
+	}
+	if len(status) > 0 {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+	err := query.Order("id desc").Offset(offset).Limit(limit).Find(&deliveries).Error
+	return deliveries, count, err
+}
+
+// WebhookDeadLetter is model
+type WebhookDeadLetter struct {
+	ID         int64  `gorm:"primary_key;not null" json:"id"`
+	DeliveryID int64  `gorm:"not null" json:"delivery_id"`
+	Ecosystem  int64  `gorm:"not null" json:"ecosystem"`
+	WebhookID  int64  `gorm:"not null" json:"webhook_id"`
+	Payload    string `gorm:"not null" json:"payload"`
+	Attempts   int64  `gorm:"not null" json:"attempts"`
+	Error      string `gorm:"not null" json:"error"`
+	Time       int64  `gorm:"not null" json:"time"`
+}
+
+// TableName returns name of table
+func (WebhookDeadLetter) TableName() string {
+	return "webhook_dead_letters"
+}
+
+// Create is creating record of model
+func (l *WebhookDeadLetter) Create() error {
+	return DBConn.Create(l).Error
+}
+
+// GetWebhookDeadLetters returns the dead letters of ecosystem, the webhook is optional
+func GetWebhookDeadLetters(ecosystemID, webhookID int64, offset, limit int64) ([]WebhookDeadLetter, int64, error) {
+	var (
+		letters []WebhookDeadLetter
+		count   int64
+	)
+	query := DBConn.Model(&WebhookDeadLetter{}).Where("ecosystem = ?", ecosystemID)
+	if webhookID > 0 {
+		query = query.Where("webhook_id = ?", webhookID)
+	}
+	if err := query.Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+	err := query.Order("id desc").Offset(offset).Limit(limit).Find(&letters).Error
+	return letters, count, err
+}
+
+// WebhookState is model, it contains the last block which has been checked by the webhook dispatcher
+type WebhookState struct {
+	BlockID int64 `gorm:"not null"`
+}
+
+// TableName returns name of table
+func (WebhookState) TableName() string {
+	return "webhook_state"
+}
+
+// Get is retrieving model from database
+func (s *WebhookState) Get() (bool, error) {
+	return isFound(DBConn.First(s))
+}
+
+// Set saves the last checked block
+func (s *WebhookState) Set(transaction *DbTransaction, blockID int64) error {
+	if err := GetDB(transaction).Delete(&WebhookState{}).Error; err != nil {
+		return err
+	}
+	s.BlockID = blockID
+	return GetDB(transaction).Create(s).Error
+}
@@ -0,0 +1,361 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package network contains the secure channel of node-to-node connections.
+//
+// The client sends the request type consts.DATA_TYPE_SECURE and its hello, the server answers
+// with the status and its hello. The hello contains the protocol version, the node key from
+// full_nodes, the ephemeral key, the nonce and the signature of these values by the node key.
+// The signature of the server also covers the ephemeral key and the nonce of the client.
+// Both sides derive the keys of the session from the shared ephemeral key and the nonces,
+// then all data is sent in AES-GCM frames.
+package network
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	crand "crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+
+	"github.com/AplaProject/go-apla/packages/consts"
+	"github.com/AplaProject/go-apla/packages/converter"
+	"github.com/AplaProject/go-apla/packages/crypto"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// SecureVersion is the latest version of the secure channel
+	SecureVersion = 1
+	// MinSecureVersion is the oldest supported version of the secure channel
+	MinSecureVersion = 1
+
+	nonceSize    = 32
+	maxFieldSize = 1024
+	maxFrameSize = 65536
+)
+
+// The statuses of the handshake
+const (
+	statusOK = iota
+	statusVersion
+	statusUnknownNode
+	statusSignature
+)
+
+var (
+	// ErrVersion is returned if the versions of nodes are incompatible
+	ErrVersion = errors.New("unsupported version of secure channel")
+	// ErrUnknownNode is returned if the node key isn't registered in full_nodes
+	ErrUnknownNode = errors.New("unknown node key")
+	// ErrSignature is returned if the hello has the wrong signature
+	ErrSignature = errors.New("incorrect signature of hello")
+	// ErrFrame is returned if the frame can't be decrypted
+	ErrFrame = errors.New("incorrect frame")
+)
+
+// Identity is the key pair of the node
+type Identity struct {
+	PrivateKey string // hex
+	PublicKey  []byte
+}
+
+// KeyChecker returns true if the node key is allowed to connect
+type KeyChecker func(publicKey []byte) bool
+
+type hello struct {
+	Version      uint16
+	PublicKey    []byte
+	EphemeralKey []byte
+	Nonce        []byte
+	Signature    []byte
+}
+
+func newHello(version uint16, identity *Identity) (*hello, []byte, error) {
+	private, public, err := crypto.GenBytesKeys()
+	if err != nil {
+		return nil, nil, err
+	}
+	h := &hello{
+		Version:      version,
+		PublicKey:    identity.PublicKey,
+		EphemeralKey: public,
+		Nonce:        make([]byte, nonceSize),
+	}
+	if _, err = crand.Read(h.Nonce); err != nil {
+		return nil, nil, err
+	}
+	return h, converter.FillLeft(private), nil
+}
+
+// signData returns the signed data of hello, peer is the hello of the client for the server
+func (h *hello) signData(role string, peer *hello) string {
+	var buf bytes.Buffer
+	buf.WriteString(role)
+	buf.Write(converter.DecToBin(int64(h.Version), 2))
+	buf.Write(h.PublicKey)
+	buf.Write(h.EphemeralKey)
+	buf.Write(h.Nonce)
+	if peer != nil {
+		buf.Write(peer.EphemeralKey)
+		buf.Write(peer.Nonce)
+	}
+	return buf.String()
+}
+
+func (h *hello) sign(privateKey, role string, peer *hello) (err error) {
+	h.Signature, err = crypto.Sign(privateKey, h.signData(role, peer))
+	return
+}
+
+func (h *hello) check(role string, peer *hello) error {
+	if len(h.Nonce) != nonceSize {
+		return ErrSignature
+	}
+	ok, err := crypto.CheckSign(h.PublicKey, h.signData(role, peer), h.Signature)
+	if err != nil || !ok {
+		return ErrSignature
+	}
+	return nil
+}
+
+func (h *hello) write(w io.Writer) error {
+	var buf bytes.Buffer
+	buf.Write(converter.DecToBin(int64(h.Version), 2))
+	for _, field := range [][]byte{h.PublicKey, h.EphemeralKey, h.Nonce, h.Signature} {
+		buf.Write(converter.DecToBin(len(field), 4))
+		buf.Write(field)
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func readHello(r io.Reader) (*hello, error) {
+	h := &hello{}
+	version := make([]byte, 2)
+	if _, err := io.ReadFull(r, version); err != nil {
+		return nil, err
+	}
+	h.Version = uint16(converter.BinToDec(version))
+	for _, field := range []*[]byte{&h.PublicKey, &h.EphemeralKey, &h.Nonce, &h.Signature} {
+		size := make([]byte, 4)
+		if _, err := io.ReadFull(r, size); err != nil {
+			return nil, err
+		}
+		if converter.BinToDec(size) > maxFieldSize {
+			log.WithFields(log.Fields{"type": consts.ParameterExceeded, "size": converter.BinToDec(size)}).Error("hello field is too big")
+			return nil, ErrFrame
+		}
+		*field = make([]byte, converter.BinToDec(size))
+		if _, err := io.ReadFull(r, *field); err != nil {
+			return nil, err
+		}
+	}
+	return h, nil
+}
+
+func statusError(status byte) error {
+	switch status {
+	case statusOK:
+		return nil
+	case statusVersion:
+		return ErrVersion
+	case statusUnknownNode:
+		return ErrUnknownNode
+	}
+	return ErrSignature
+}
+
+// Dial makes the handshake on the client side of the connection. The request type has to be
+// sent by the caller. check verifies the key of the server.
+func Dial(conn net.Conn, identity *Identity, check KeyChecker) (*Conn, error) {
+	local, private, err := newHello(SecureVersion, identity)
+	if err != nil {
+		return nil, err
+	}
+	if err = local.sign(identity.PrivateKey, `client`, nil); err != nil {
+		return nil, err
+	}
+	if err = local.write(conn); err != nil {
+		return nil, err
+	}
+	status := make([]byte, 1)
+	if _, err = io.ReadFull(conn, status); err != nil {
+		return nil, err
+	}
+	if err = statusError(status[0]); err != nil {
+		return nil, err
+	}
+	remote, err := readHello(conn)
+	if err != nil {
+		return nil, err
+	}
+	if remote.Version < MinSecureVersion || remote.Version > SecureVersion {
+		return nil, ErrVersion
+	}
+	if !check(remote.PublicKey) {
+		return nil, ErrUnknownNode
+	}
+	if err = remote.check(`server`, local); err != nil {
+		return nil, err
+	}
+	return newConn(conn, remote, private, local, remote, true)
+}
+
+// Accept makes the handshake on the server side of the connection after the request type
+// consts.DATA_TYPE_SECURE has been read. check verifies the key of the client.
+func Accept(conn net.Conn, identity *Identity, check KeyChecker) (*Conn, error) {
+	remote, err := readHello(conn)
+	if err != nil {
+		return nil, err
+	}
+	refuse := func(status byte, err error) (*Conn, error) {
+		conn.Write([]byte{status})
+		return nil, err
+	}
+	if remote.Version < MinSecureVersion {
+		return refuse(statusVersion, ErrVersion)
+	}
+	if !check(remote.PublicKey) {
+		return refuse(statusUnknownNode, ErrUnknownNode)
+	}
+	if err = remote.check(`client`, nil); err != nil {
+		return refuse(statusSignature, err)
+	}
+	version := remote.Version
+	if version > SecureVersion {
+		version = SecureVersion
+	}
+	local, private, err := newHello(version, identity)
+	if err != nil {
+		return nil, err
+	}
+	if err = local.sign(identity.PrivateKey, `server`, remote); err != nil {
+		return nil, err
+	}
+	if _, err = conn.Write([]byte{statusOK}); err != nil {
+		return nil, err
+	}
+	if err = local.write(conn); err != nil {
+		return nil, err
+	}
+	return newConn(conn, remote, private, remote, local, false)
+}
+
+// Conn is the encrypted connection
+type Conn struct {
+	net.Conn
+	Version uint16
+	PeerKey []byte // the node key of the peer
+
+	reader, writer    cipher.AEAD
+	readSeq, writeSeq uint64
+	plain             []byte
+}
+
+func sessionCipher(shared []byte, direction string, client, server *hello) (cipher.AEAD, error) {
+	data := append(append([]byte{}, shared...), direction...)
+	data = append(append(data, client.Nonce...), server.Nonce...)
+	key, err := crypto.Hash(data)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func newConn(conn net.Conn, remote *hello, private []byte, client, server *hello, isClient bool) (*Conn, error) {
+	shared, err := crypto.GetSharedKey(private, remote.EphemeralKey)
+	if err != nil {
+		return nil, err
+	}
+	toServer, err := sessionCipher(shared, `client`, client, server)
+	if err != nil {
+		return nil, err
+	}
+	toClient, err := sessionCipher(shared, `server`, client, server)
+	if err != nil {
+		return nil, err
+	}
+	c := &Conn{Conn: conn, Version: server.Version, PeerKey: remote.PublicKey}
+	if isClient {
+		c.reader, c.writer = toClient, toServer
+	} else {
+		c.reader, c.writer = toServer, toClient
+	}
+	return c, nil
+}
+
+func frameNonce(seq uint64, size int) []byte {
+	nonce := make([]byte, size)
+	binary.BigEndian.PutUint64(nonce[size-8:], seq)
+	return nonce
+}
+
+// Write encrypts data and sends it by frames
+func (c *Conn) Write(data []byte) (int, error) {
+	var written int
+	for len(data) > 0 {
+		chunk := data
+		if len(chunk) > maxFrameSize {
+			chunk = chunk[:maxFrameSize]
+		}
+		frame := c.writer.Seal(nil, frameNonce(c.writeSeq, c.writer.NonceSize()), chunk, nil)
+		c.writeSeq++
+		if _, err := c.Conn.Write(append(converter.DecToBin(len(frame), 4), frame...)); err != nil {
+			return written, err
+		}
+		written += len(chunk)
+		data = data[len(chunk):]
+	}
+	return written, nil
+}
+
+// Read receives and decrypts the next frame if the previous one has been read
+func (c *Conn) Read(data []byte) (int, error) {
+	if len(c.plain) == 0 {
+		size := make([]byte, 4)
+		if _, err := io.ReadFull(c.Conn, size); err != nil {
+			return 0, err
+		}
+		frameSize := converter.BinToDec(size)
+		if frameSize > maxFrameSize+int64(c.reader.Overhead()) {
+			log.WithFields(log.Fields{"type": consts.ParameterExceeded, "size": frameSize}).Error("frame is too big")
+			return 0, ErrFrame
+		}
+		frame := make([]byte, frameSize)
+		if _, err := io.ReadFull(c.Conn, frame); err != nil {
+			return 0, err
+		}
+		plain, err := c.reader.Open(nil, frameNonce(c.readSeq, c.reader.NonceSize()), frame, nil)
+		if err != nil {
+			log.WithFields(log.Fields{"type": consts.CryptoError, "error": err}).Error("decrypting frame")
+			return 0, ErrFrame
+		}
+		c.readSeq++
+		c.plain = plain
+	}
+	n := copy(data, c.plain)
+	c.plain = c.plain[n:]
+	return n, nil
+}
@@ -0,0 +1,114 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package network
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/AplaProject/go-apla/packages/crypto"
+)
+
+func newIdentity(t *testing.T) *Identity {
+	private, public, err := crypto.GenHexKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, _ := hex.DecodeString(public)
+	return &Identity{PrivateKey: private, PublicKey: key}
+}
+
+func keyChecker(keys ...*Identity) KeyChecker {
+	return func(public []byte) bool {
+		for _, key := range keys {
+			if bytes.Equal(key.PublicKey, public) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+type handshake struct {
+	conn *Conn
+	err  error
+}
+
+func connect(client, server *Identity, clientCheck, serverCheck KeyChecker) (handshake, handshake) {
+	c, s := net.Pipe()
+	ch := make(chan handshake, 1)
+	go func() {
+		conn, err := Accept(s, server, serverCheck)
+		if err != nil {
+			s.Close()
+		}
+		ch <- handshake{conn, err}
+	}()
+	conn, err := Dial(c, client, clientCheck)
+	if err != nil {
+		c.Close()
+	}
+	return handshake{conn, err}, <-ch
+}
+
+func TestSecureChannel(t *testing.T) {
+	client, server := newIdentity(t), newIdentity(t)
+	cl, sv := connect(client, server, keyChecker(server), keyChecker(client))
+	if cl.err != nil || sv.err != nil {
+		t.Fatalf("handshake has failed %v %v", cl.err, sv.err)
+	}
+	if !bytes.Equal(sv.conn.PeerKey, client.PublicKey) || cl.conn.Version != SecureVersion {
+		t.Error("wrong peer of secure channel")
+	}
+	data := bytes.Repeat([]byte(`block`), maxFrameSize/2)
+	go func() {
+		cl.conn.Write(data)
+		cl.conn.Write([]byte{1, 2, 3, 4})
+	}()
+	received := make([]byte, len(data)+4)
+	if _, err := io.ReadFull(sv.conn, received); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(received[:len(data)], data) || !bytes.Equal(received[len(data):], []byte{1, 2, 3, 4}) {
+		t.Error("wrong data of secure channel")
+	}
+}
+
+func TestSecureChannelUnknownNode(t *testing.T) {
+	client, server, other := newIdentity(t), newIdentity(t), newIdentity(t)
+	cl, sv := connect(client, server, keyChecker(server), keyChecker(other))
+	if cl.err != ErrUnknownNode || sv.err != ErrUnknownNode {
+		t.Errorf("client must be refused %v %v", cl.err, sv.err)
+	}
+	cl, sv = connect(client, server, keyChecker(other), keyChecker(client))
+	if cl.err != ErrUnknownNode {
+		t.Errorf("server must be refused %v", cl.err)
+	}
+}
+
+func TestSecureChannelSignature(t *testing.T) {
+	client, server := newIdentity(t), newIdentity(t)
+	// the node key doesn't match the private key
+	fake := &Identity{PrivateKey: client.PrivateKey, PublicKey: server.PublicKey}
+	cl, sv := connect(fake, server, keyChecker(server), keyChecker(server))
+	if cl.err != ErrSignature || sv.err != ErrSignature {
+		t.Errorf("signature must be wrong %v %v", cl.err, sv.err)
+	}
+}
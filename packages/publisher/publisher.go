@@ -63,6 +63,19 @@ func Write(userID int64, data string) (bool, error) {
 	return publisher.Publish("client"+strconv.FormatInt(userID, 10), []byte(data))
 }
 
+// WriteEvent is publishing the event of the contract to the channel of the ecosystem
+func WriteEvent(ecosystemID int64, data []byte) (bool, error) {
+	if publisher == nil {
+		return false, fmt.Errorf("publisher not initialized")
+	}
+	return publisher.Publish(EventsChannel(ecosystemID), data)
+}
+
+// EventsChannel returns the name of the channel for the events of the ecosystem
+func EventsChannel(ecosystemID int64) string {
+	return "events" + strconv.FormatInt(ecosystemID, 10)
+}
+
 // GetStats returns Stats
 func GetStats() (gocent.Stats, error) {
 	if publisher == nil {
@@ -0,0 +1,95 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package ratelimit implements token buckets which are identified by the string keys
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// cleanupPeriod is the period of removing the full buckets
+const cleanupPeriod = time.Minute
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limiter keeps the token buckets with the same rate and size for the different keys
+type Limiter struct {
+	mutex   sync.Mutex
+	rate    float64 // tokens per second
+	burst   float64 // size of the bucket
+	buckets map[string]*bucket
+	cleaned time.Time
+	now     func() time.Time
+}
+
+// NewLimiter returns the limiter which allows rate requests per second with bursts of burst
+// requests. It returns nil if rate is not positive, the nil limiter allows everything.
+func NewLimiter(rate float64, burst int) *Limiter {
+	if rate <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &Limiter{rate: rate, burst: float64(burst), buckets: make(map[string]*bucket),
+		cleaned: time.Now(), now: time.Now}
+}
+
+// Allow takes the token from the bucket of the key. It returns false and the time after which
+// the token will be available if the bucket is empty.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	if l == nil {
+		return true, 0
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+	if now.Sub(l.cleaned) > cleanupPeriod {
+		l.cleanup(now)
+	}
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	} else {
+		b.tokens += now.Sub(b.last).Seconds() * l.rate
+		if b.tokens > l.burst {
+			b.tokens = l.burst
+		}
+		b.last = now
+	}
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// cleanup removes the buckets which have been refilled, they are the same as the new ones
+func (l *Limiter) cleanup(now time.Time) {
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+	l.cleaned = now
+}
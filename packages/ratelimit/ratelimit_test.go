@@ -0,0 +1,62 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	now := time.Now()
+	l := NewLimiter(2, 3)
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.Allow(`a`); !ok {
+			t.Errorf("request %d must be allowed", i)
+		}
+	}
+	ok, wait := l.Allow(`a`)
+	if ok || wait != 500*time.Millisecond {
+		t.Errorf("request must be rejected for 500ms, got %v %v", ok, wait)
+	}
+	if ok, _ = l.Allow(`b`); !ok {
+		t.Error("other key must be allowed")
+	}
+
+	now = now.Add(time.Second)
+	for i := 0; i < 2; i++ {
+		if ok, _ = l.Allow(`a`); !ok {
+			t.Errorf("request %d must be allowed after refill", i)
+		}
+	}
+	if ok, _ = l.Allow(`a`); ok {
+		t.Error("request must be rejected after refill")
+	}
+
+	now = now.Add(2 * cleanupPeriod)
+	l.Allow(`c`)
+	if len(l.buckets) != 1 {
+		t.Errorf("full buckets must be removed, got %d", len(l.buckets))
+	}
+
+	var off *Limiter
+	if ok, _ = off.Allow(`a`); !ok || NewLimiter(0, 10) != nil {
+		t.Error("limiter without rate must allow everything")
+	}
+}
@@ -25,6 +25,7 @@ import (
 	"github.com/AplaProject/go-apla/packages/model"
 	"github.com/AplaProject/go-apla/packages/smart"
 	"github.com/AplaProject/go-apla/packages/transaction"
+	"github.com/AplaProject/go-apla/packages/txstream"
 	"github.com/AplaProject/go-apla/packages/utils"
 
 	log "github.com/sirupsen/logrus"
@@ -66,8 +67,13 @@ func RollbackBlock(data []byte, deleteBlock bool) error {
 		}
 	}
 
-	err = dbTransaction.Commit()
-	return err
+	if err = dbTransaction.Commit(); err != nil {
+		return err
+	}
+	for _, t := range block.Transactions {
+		txstream.RolledBack(t.TxHash, block.Header.BlockID)
+	}
+	return nil
 }
 
 func rollbackBlock(dbTransaction *model.DbTransaction, block *block.Block) error {
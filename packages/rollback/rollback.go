@@ -18,6 +18,7 @@ package rollback
 
 import (
 	"bytes"
+	"errors"
 	"strconv"
 
 	"github.com/AplaProject/go-apla/packages/consts"
@@ -28,8 +29,37 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+// ErrSnapshotBlock is returned if the blocks have to be rolled back past the imported snapshot
+var ErrSnapshotBlock = errors.New("blocks can't be rolled back past the imported snapshot")
+
+// CheckBlockID returns ErrSnapshotBlock if the state can't be rolled back to blockID. The blockchain which
+// has been imported from the snapshot doesn't contain the blocks before the snapshot and their rollback data.
+func CheckBlockID(blockID int64) error {
+	last := &model.InfoBlock{}
+	if _, err := last.Get(); err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting info block")
+		return err
+	}
+	// the block blockID and the next block have to be in the blockchain
+	for id := blockID; id <= blockID+1 && id <= last.BlockID; id++ {
+		found, err := model.IsBlock(id)
+		if err != nil {
+			log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting block")
+			return err
+		}
+		if !found {
+			log.WithFields(log.Fields{"type": consts.NotFound, "block_id": id}).Error("rolling back past the imported snapshot")
+			return ErrSnapshotBlock
+		}
+	}
+	return nil
+}
+
 // ToBlockID rollbacks blocks till blockID
 func ToBlockID(blockID int64, dbTransaction *model.DbTransaction, logger *log.Entry) error {
+	if err := CheckBlockID(blockID); err != nil {
+		return err
+	}
 	_, err := model.MarkVerifiedAndNotUsedTransactionsUnverified()
 	if err != nil {
 		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("marking verified and not used transactions unverified")
@@ -0,0 +1,455 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package script
+
+import (
+	"fmt"
+	"sort"
+)
+
+const (
+	// CheckUnused reports the variables and the parameters which are never read
+	CheckUnused = `unused`
+	// CheckUnreachable reports the code after return, error, break or continue
+	CheckUnreachable = `unreachable`
+	// CheckConditionsDB reports the calls of database functions in conditions
+	CheckConditionsDB = `conditionsdb`
+	// CheckLoop reports while loops which can't be finished
+	CheckLoop = `loop`
+	// CheckContract reports the calls of contracts which don't exist
+	CheckContract = `contract`
+)
+
+// Diagnostic is the possible error which has been found in the source code
+type Diagnostic struct {
+	Line     uint32 `json:"line"`
+	Column   uint32 `json:"column"`
+	Check    string `json:"check"`
+	Contract string `json:"contract,omitempty"`
+	Func     string `json:"func,omitempty"`
+	Message  string `json:"message"`
+}
+
+// declaration is the lexem where the variable or the parameter has been declared
+type declaration struct {
+	lexem *Lexem
+	param bool
+}
+
+type analyzer struct {
+	vm    *VM
+	root  *Block
+	decls map[*Block]map[string]declaration
+	diags []Diagnostic
+}
+
+// Analyze compiles the source code and checks the compiled blocks for the possible errors.
+// The code is compiled in the extern mode so the calls of unknown contracts are reported
+// as diagnostics instead of compilation errors.
+func (vm *VM) Analyze(input []rune, owner *OwnerInfo) ([]Diagnostic, error) {
+	extern := *vm
+	extern.Extern = true
+	root, err := extern.CompileBlock(input, owner)
+	if err != nil {
+		return nil, err
+	}
+	lexems, err := lexParser(input)
+	if err != nil {
+		return nil, err
+	}
+	a := &analyzer{
+		vm:    vm,
+		root:  root,
+		decls: make(map[*Block]map[string]declaration),
+		diags: make([]Diagnostic, 0),
+	}
+	a.declarations(lexems)
+	a.children(root)
+	sort.SliceStable(a.diags, func(i, j int) bool {
+		if a.diags[i].Line != a.diags[j].Line {
+			return a.diags[i].Line < a.diags[j].Line
+		}
+		return a.diags[i].Column < a.diags[j].Column
+	})
+	return a.diags, nil
+}
+
+// declarations finds the positions of the declared variables and parameters. The blocks are
+// matched with lexems in the same order as they are created by the compiler.
+func (a *analyzer) declarations(lexems Lexems) {
+	var (
+		pending  *Block
+		vars     bool
+		headVars bool
+		parens   int
+	)
+	blocks := []*Block{a.root}
+	braces := make([]*Block, 0, 16)
+	next := make(map[*Block]int)
+	for _, lexem := range lexems {
+		top := blocks[len(blocks)-1]
+		switch lexem.Type {
+		case lexKeyword | (keyContract << 8), lexKeyword | (keyFunc << 8), lexKeyword | (keyIf << 8),
+			lexKeyword | (keyWhile << 8), lexKeyword | (keyElse << 8), lexKeyword | (keyFor << 8),
+			lexKeyword | (keyTry << 8), lexKeyword | (keyCatch << 8):
+			if next[top] < len(top.Children) {
+				pending = top.Children[next[top]]
+				next[top]++
+			}
+			headVars = lexem.Type == lexKeyword|(keyFor<<8) || lexem.Type == lexKeyword|(keyCatch<<8)
+		case lexKeyword | (keyIn << 8):
+			headVars = false
+		case lexKeyword | (keyVar << 8):
+			vars = true
+		case lexNewLine:
+			vars = false
+		case isLPar:
+			parens++
+		case isRPar:
+			parens--
+		case isLCurly:
+			vars, headVars = false, false
+			braces = append(braces, pending)
+			if pending != nil {
+				blocks = append(blocks, pending)
+				pending = nil
+			}
+		case isRCurly:
+			vars = false
+			if len(braces) > 0 {
+				if braces[len(braces)-1] != nil && len(blocks) > 1 {
+					blocks = blocks[:len(blocks)-1]
+				}
+				braces = braces[:len(braces)-1]
+			}
+		case lexIdent:
+			if vars {
+				a.declare(top, lexem, false)
+			} else if headVars && pending != nil {
+				a.declare(pending, lexem, false)
+			} else if pending != nil && pending.Type == ObjFunc && parens > 0 {
+				a.declare(pending, lexem, true)
+			}
+		}
+	}
+}
+
+func (a *analyzer) declare(block *Block, lexem *Lexem, param bool) {
+	if a.decls[block] == nil {
+		a.decls[block] = make(map[string]declaration)
+	}
+	name := lexem.Value.(string)
+	if _, ok := a.decls[block][name]; !ok {
+		a.decls[block][name] = declaration{lexem: lexem, param: param}
+	}
+}
+
+func (a *analyzer) report(check string, fblock *Block, line, column uint32, format string, args ...interface{}) {
+	contract, fname := blockNames(fblock)
+	a.diags = append(a.diags, Diagnostic{
+		Line:     line,
+		Column:   column,
+		Check:    check,
+		Contract: contract,
+		Func:     fname,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// children checks the contracts and the functions which are declared in the block
+func (a *analyzer) children(block *Block) {
+	for _, child := range block.Children {
+		switch child.Type {
+		case ObjContract:
+			a.contract(child)
+		case ObjFunc:
+			a.function(child)
+		default:
+			a.children(child)
+		}
+	}
+}
+
+func (a *analyzer) contract(cblock *Block) {
+	info := cblock.Info.(*ContractInfo)
+	names := make([]string, 0, len(info.Used))
+	for name := range info.Used {
+		if _, ok := a.root.Objects[name]; ok {
+			continue
+		}
+		if _, ok := a.vm.Objects[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		var line, column uint32
+		if cmd := findPush(cblock, name); cmd != nil {
+			line, column = cmd.Line, cmd.Column
+		}
+		a.report(CheckContract, cblock, line, column, `contract %s doesn't exist`, name)
+	}
+	a.children(cblock)
+	for _, child := range cblock.Children {
+		if _, fname := blockNames(child); child.Type == ObjFunc && fname == `conditions` {
+			a.conditions(child, child, make(map[*Block]bool))
+		}
+	}
+}
+
+func (a *analyzer) function(fblock *Block) {
+	reads := make(map[*ObjInfo]bool)
+	a.code(fblock, fblock, false, reads)
+	a.unused(fblock, fblock, reads)
+	a.children(fblock)
+}
+
+// code checks the byte-code of the block and collects the variables which are read
+func (a *analyzer) code(fblock, block *Block, loop bool, reads map[*ObjInfo]bool) {
+	var unreachable bool
+	for i, cmd := range block.Code {
+		switch cmd.Cmd {
+		case cmdVar:
+			reads[cmd.Value.(*VarInfo).Obj] = true
+		case cmdIndex, cmdSetIndex:
+			if obj := indexVar(cmd.Value.(*IndexInfo)); obj != nil {
+				reads[obj] = true
+			}
+		case cmdIf, cmdElse, cmdTry, cmdCatch:
+			a.code(fblock, cmd.Value.(*Block), loop, reads)
+		case cmdWhile:
+			a.code(fblock, cmd.Value.(*Block), true, reads)
+			a.loop(fblock, block.Code, i)
+		case cmdFor:
+			a.code(fblock, cmd.Value.(*Block), false, reads)
+		case cmdReturn, cmdError, cmdBreak, cmdContinue:
+			rest := block.Code[i+1:]
+			// the last continue of the loop is added by the compiler
+			if loop && len(rest) > 0 && rest[len(rest)-1].Cmd == cmdContinue {
+				rest = rest[:len(rest)-1]
+			}
+			if !unreachable && len(rest) > 0 {
+				unreachable = true
+				a.report(CheckUnreachable, fblock, rest[0].Line, rest[0].Column, `unreachable code`)
+			}
+		}
+	}
+}
+
+// unused reports the variables of the block and its nested blocks which are never read
+func (a *analyzer) unused(fblock, block *Block, reads map[*ObjInfo]bool) {
+	names := make([]string, 0, len(block.Objects))
+	for name, obj := range block.Objects {
+		if obj.Type == ObjVar && !reads[obj] && name != `_` {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		var line, column uint32
+		kind := `variable`
+		if decl, ok := a.decls[block][name]; ok {
+			line, column = decl.lexem.Line, decl.lexem.Column
+			if decl.param {
+				kind = `parameter`
+			}
+		}
+		a.report(CheckUnused, fblock, line, column, `%s %s is not used`, kind, name)
+	}
+	for _, child := range block.Children {
+		if child.Type != ObjFunc && child.Type != ObjContract {
+			a.unused(fblock, child, reads)
+		}
+	}
+}
+
+// loop reports the while loop if its condition doesn't depend on the body of the loop
+// and the body doesn't contain break, return or error
+func (a *analyzer) loop(fblock *Block, code ByteCodes, ind int) {
+	start := ind - 1
+	for start >= 0 && code[start].Cmd != cmdLabel {
+		start--
+	}
+	vars := make(map[*ObjInfo]bool)
+	extends := make(map[string]bool)
+	for _, cmd := range code[start+1 : ind] {
+		switch cmd.Cmd {
+		case cmdVar:
+			vars[cmd.Value.(*VarInfo).Obj] = true
+		case cmdExtend:
+			extends[cmd.Value.(string)] = true
+		case cmdIndex:
+			if info := cmd.Value.(*IndexInfo); len(info.Extend) > 0 {
+				extends[info.Extend] = true
+			} else if obj := indexVar(info); obj != nil {
+				vars[obj] = true
+			}
+		case cmdCall, cmdCallVari, cmdCallExtend, cmdFuncName:
+			// the result of the function can change
+			return
+		}
+	}
+	body := code[ind].Value.(*Block)
+	if exits(body, true) || modifies(body, vars, extends) {
+		return
+	}
+	a.report(CheckLoop, fblock, code[ind].Line, code[ind].Column, `while loop is unbounded`)
+}
+
+// conditions reports the calls of database functions from the conditions of the contract
+// including the calls in the called functions
+func (a *analyzer) conditions(fblock, block *Block, visited map[*Block]bool) {
+	for _, cmd := range block.Code {
+		switch cmd.Cmd {
+		case cmdCall, cmdCallVari:
+			obj := cmd.Value.(*ObjInfo)
+			switch obj.Type {
+			case ObjExtFunc:
+				name := obj.Value.(ExtFuncInfo).Name
+				if _, ok := a.vm.FuncCallsDB[name]; ok {
+					a.report(CheckConditionsDB, fblock, cmd.Line, cmd.Column,
+						`database function %s is called in conditions`, name)
+				}
+			case ObjFunc:
+				fn := obj.Value.(*Block)
+				if name := a.dbFunc(fn, visited); len(name) > 0 {
+					_, fname := blockNames(fn)
+					a.report(CheckConditionsDB, fblock, cmd.Line, cmd.Column,
+						`database function %s is called in conditions by %s`, name, fname)
+				}
+			}
+		case cmdIf, cmdElse, cmdWhile, cmdFor, cmdTry, cmdCatch:
+			a.conditions(fblock, cmd.Value.(*Block), visited)
+		}
+	}
+}
+
+// dbFunc returns the name of the database function which is called by the block
+func (a *analyzer) dbFunc(block *Block, visited map[*Block]bool) string {
+	if visited[block] {
+		return ``
+	}
+	visited[block] = true
+	for _, cmd := range block.Code {
+		switch cmd.Cmd {
+		case cmdCall, cmdCallVari:
+			obj := cmd.Value.(*ObjInfo)
+			if obj.Type == ObjExtFunc {
+				name := obj.Value.(ExtFuncInfo).Name
+				if _, ok := a.vm.FuncCallsDB[name]; ok {
+					return name
+				}
+			} else if obj.Type == ObjFunc {
+				if name := a.dbFunc(obj.Value.(*Block), visited); len(name) > 0 {
+					return name
+				}
+			}
+		case cmdIf, cmdElse, cmdWhile, cmdFor, cmdTry, cmdCatch:
+			if name := a.dbFunc(cmd.Value.(*Block), visited); len(name) > 0 {
+				return name
+			}
+		}
+	}
+	return ``
+}
+
+// indexVar returns the variable which is indexed
+func indexVar(info *IndexInfo) *ObjInfo {
+	if info.Owner == nil {
+		return nil
+	}
+	for _, obj := range info.Owner.Objects {
+		if obj.Type == ObjVar && obj.Value.(int) == info.VarOffset {
+			return obj
+		}
+	}
+	return nil
+}
+
+// exits returns true if the block contains return, error or break of the loop
+func exits(block *Block, loop bool) bool {
+	for _, cmd := range block.Code {
+		switch cmd.Cmd {
+		case cmdReturn, cmdError:
+			return true
+		case cmdBreak:
+			if loop {
+				return true
+			}
+		case cmdIf, cmdElse, cmdTry, cmdCatch:
+			if exits(cmd.Value.(*Block), loop) {
+				return true
+			}
+		case cmdWhile, cmdFor:
+			if exits(cmd.Value.(*Block), false) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// modifies returns true if the block assigns any of the variables. Any called function
+// can change extended variables.
+func modifies(block *Block, vars map[*ObjInfo]bool, extends map[string]bool) bool {
+	for _, cmd := range block.Code {
+		switch cmd.Cmd {
+		case cmdAssignVar:
+			for _, ivar := range cmd.Value.([]*VarInfo) {
+				if ivar.Obj.Type == ObjExtend {
+					if extends[ivar.Obj.Value.(string)] {
+						return true
+					}
+				} else if vars[ivar.Obj] {
+					return true
+				}
+			}
+		case cmdSetIndex:
+			if info := cmd.Value.(*IndexInfo); len(info.Extend) > 0 {
+				if extends[info.Extend] {
+					return true
+				}
+			} else if vars[indexVar(info)] {
+				return true
+			}
+		case cmdCall, cmdCallVari, cmdCallExtend:
+			if len(extends) > 0 {
+				return true
+			}
+		case cmdIf, cmdElse, cmdWhile, cmdFor, cmdTry, cmdCatch:
+			if modifies(cmd.Value.(*Block), vars, extends) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// findPush returns the command which pushes the value
+func findPush(block *Block, value interface{}) *ByteCode {
+	for _, cmd := range block.Code {
+		if cmd.Cmd == cmdPush && cmd.Value == value {
+			return cmd
+		}
+	}
+	for _, child := range block.Children {
+		if cmd := findPush(child, value); cmd != nil {
+			return cmd
+		}
+	}
+	return nil
+}
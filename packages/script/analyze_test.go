@@ -0,0 +1,90 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package script
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestAnalyze(t *testing.T) {
+	vm := NewVM()
+	vm.Extend(&ExtendData{Objects: map[string]interface{}{
+		"DBFind": func(table string) int64 { return 0 },
+	}})
+	vm.FuncCallsDB = map[string]struct{}{`DBFind`: {}}
+	if err := vm.Compile([]rune(`contract Exists {
+		action {}
+	}`), &OwnerInfo{StateID: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	diags, err := vm.Analyze([]rune(`func count(table string, unused int) int {
+	return DBFind(table)
+}
+contract Test {
+	conditions {
+		var i int
+		while i < 10 {
+			i = i + 1
+		}
+		if count("keys", 1) > 0 {
+			error "exists"
+		}
+	}
+	action {
+		var a, b int
+		while a < 1 {
+			b = 1
+		}
+		return
+		Exists()
+	}
+}
+contract Caller {
+	action {
+		Missing()
+		Exists()
+	}
+}`), &OwnerInfo{StateID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		`1:26 unused parameter unused is not used`,
+		`10:7 conditionsdb database function DBFind is called in conditions by count`,
+		`15:11 unused variable b is not used`,
+		`16:4 loop while loop is unbounded`,
+		`20:4 unreachable unreachable code`,
+		`25:4 contract contract @1Missing doesn't exist`,
+	}
+	if len(diags) != len(want) {
+		t.Fatalf(`wrong diagnostics %+v`, diags)
+	}
+	for i, diag := range diags {
+		if get := fmt.Sprintf(`%d:%d %s %s`, diag.Line, diag.Column, diag.Check, diag.Message); get != want[i] {
+			t.Errorf(`wrong diagnostic %s != %s`, get, want[i])
+		}
+	}
+	if diags[1].Contract != `@1Test` || diags[1].Func != `conditions` {
+		t.Errorf(`wrong function of diagnostic %+v`, diags[1])
+	}
+
+	if _, err = vm.Analyze([]rune(`func test {`), &OwnerInfo{StateID: 1}); err == nil {
+		t.Error(`compilation error must be returned`)
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package script
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/AplaProject/go-apla/packages/consts"
+	"github.com/AplaProject/go-apla/packages/crypto"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// BlockCache keeps the compiled blocks on the disk. Every entry is addressed by the hash of
+// the source code, the ecosystem and the version of the byte-code. The entry contains the hash
+// of the source and the ecosystem in the header so the collision or the stale entry is detected
+// and the source is compiled again.
+type BlockCache struct {
+	Dir string
+}
+
+// NewBlockCache returns the cache of the compiled blocks which are stored in dir
+func NewBlockCache(dir string) *BlockCache {
+	return &BlockCache{Dir: dir}
+}
+
+func cacheKey(input []rune, state uint32) ([]byte, error) {
+	return crypto.Hash([]byte(fmt.Sprintf(`%d:%d:%s`, BytecodeVersion, state, string(input))))
+}
+
+func (cache *BlockCache) path(key []byte) string {
+	name := fmt.Sprintf(`%x`, key)
+	return filepath.Join(cache.Dir, name[:2], name)
+}
+
+// Load returns the compiled block of the source if the cache has a valid entry
+func (cache *BlockCache) Load(vm *VM, input []rune, owner *OwnerInfo) (*Block, error) {
+	key, err := cacheKey(input, owner.StateID)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(cache.path(key))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < len(key)+4 || !bytes.Equal(data[:len(key)], key) ||
+		binary.BigEndian.Uint32(data[len(key):len(key)+4]) != owner.StateID {
+		return nil, errBytecodeFormat
+	}
+	return vm.DecodeBlock(data[len(key)+4:], owner)
+}
+
+// Store writes the compiled block of the source into the cache
+func (cache *BlockCache) Store(vm *VM, input []rune, root *Block) error {
+	state := root.Info.(uint32)
+	key, err := cacheKey(input, state)
+	if err != nil {
+		return err
+	}
+	data, err := vm.EncodeBlock(root)
+	if err != nil {
+		return err
+	}
+	path := cache.path(key)
+	if err = os.MkdirAll(filepath.Dir(path), 0775); err != nil {
+		log.WithFields(log.Fields{"type": consts.IOError, "error": err, "path": path}).Error("creating cache dir")
+		return err
+	}
+	var buf bytes.Buffer
+	buf.Write(key)
+	binary.Write(&buf, binary.BigEndian, state)
+	buf.Write(data)
+	// the entry is written into the temporary file and renamed, so an interrupted
+	// write never leaves a broken entry
+	tmp := path + `.tmp`
+	if err = ioutil.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+		log.WithFields(log.Fields{"type": consts.WritingFile, "error": err, "path": tmp}).Error("writing cache entry")
+		return err
+	}
+	if err = os.Rename(tmp, path); err != nil {
+		log.WithFields(log.Fields{"type": consts.IOError, "error": err, "path": path}).Error("renaming cache entry")
+		return err
+	}
+	return nil
+}
+
+// Remove deletes the entry of the source from the cache
+func (cache *BlockCache) Remove(input []rune, state uint32) error {
+	key, err := cacheKey(input, state)
+	if err != nil {
+		return err
+	}
+	if err = os.Remove(cache.path(key)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// CompileCached restores the byte-code of the source from the cache and loads it into the virtual
+// machine. If the cache doesn't have a valid entry then the source is compiled and stored into the cache.
+func (vm *VM) CompileCached(input []rune, owner *OwnerInfo, cache *BlockCache) error {
+	if cache == nil {
+		return vm.Compile(input, owner)
+	}
+	root, err := cache.Load(vm, input, owner)
+	if err == nil {
+		vm.FlushBlock(root)
+		return nil
+	}
+	if !os.IsNotExist(err) {
+		log.WithFields(log.Fields{"type": consts.VMError, "error": err, "state": owner.StateID}).Warning("stale byte-code in cache")
+		cache.Remove(input, owner.StateID)
+	}
+	root, err = vm.CompileBlock(input, owner)
+	if err != nil {
+		return err
+	}
+	if err = cache.Store(vm, input, root); err != nil {
+		log.WithFields(log.Fields{"type": consts.VMError, "error": err, "state": owner.StateID}).Warning("storing byte-code in cache")
+	}
+	vm.FlushBlock(root)
+	return nil
+}
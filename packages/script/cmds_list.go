@@ -40,6 +40,9 @@ const (
 	cmdFuncName              // set func name Func(...).Name(...)
 	cmdUnwrapArr             // unwrap array to stack
 	cmdError                 // error command
+	cmdFor                   // for loop over array or map
+	cmdTry                   // run block and catch its error
+	cmdCatch                 // run block if the error has been caught
 )
 
 // the commands for operations in expressions are listed below
@@ -89,6 +89,11 @@ const (
 	stateConstsAssign
 	stateConstsValue
 	stateFields
+	stateFor
+	stateForVars
+	stateTry
+	stateCatch
+	stateCatchVar
 	stateEval
 
 	// The list of state flags
@@ -117,6 +122,8 @@ const (
 	errVarType               // must be type
 	errAssign                // must be '='
 	errStrNum                // must be number or string
+	errMustIn                // must be 'in'
+	errMustCatch             // must be 'catch'
 )
 
 const (
@@ -147,11 +154,16 @@ const (
 	cfContinue
 	cfBreak
 	cfCmdError
+	cfFor
+	cfTry
+	cfCatch
 
 //	cfEval
 )
 
 var (
+	// loopType is the type of the variables of for loop and catch statement
+	loopType = reflect.TypeOf((*interface{})(nil)).Elem()
 	// Array of operations and their priority
 	opers = map[uint32]operPrior{
 		isOr: {cmdOr, 10}, isAnd: {cmdAnd, 15}, isEqEq: {cmdEqual, 20}, isNotEq: {cmdNotEq, 20},
@@ -184,6 +196,9 @@ var (
 		fContinue,
 		fBreak,
 		fCmdError,
+		fFor,
+		fTry,
+		fCatch,
 	}
 
 	// 'states' describes a finite machine with states on the base of which a bytecode will be generated
@@ -202,6 +217,8 @@ var (
 			lexKeyword | (keyBreak << 8):    {stateBody, cfBreak},
 			lexKeyword | (keyIf << 8):       {stateEval | statePush | stateToBlock | stateMustEval, cfIf},
 			lexKeyword | (keyWhile << 8):    {stateEval | statePush | stateToBlock | stateLabel | stateMustEval, cfWhile},
+			lexKeyword | (keyFor << 8):      {stateFor | statePush, 0},
+			lexKeyword | (keyTry << 8):      {stateTry, 0},
 			lexKeyword | (keyElse << 8):     {stateBlock | statePush, cfElse},
 			lexKeyword | (keyVar << 8):      {stateVar, 0},
 			lexKeyword | (keyTX << 8):       {stateTX, cfTX},
@@ -327,6 +344,29 @@ var (
 			isRCurly:   {stateToBody, 0},
 			0:          {errMustRCurly, cfError},
 		},
+		{ // stateFor
+			lexIdent: {stateForVars, cfFParam},
+			0:        {errMustName, cfError},
+		},
+		{ // stateForVars
+			isComma:                   {stateFor, 0},
+			lexKeyword | (keyIn << 8): {stateEval | stateToBlock | stateMustEval, cfFor},
+			0:                         {errMustIn, cfError},
+		},
+		{ // stateTry
+			lexNewLine:                   {stateTry, 0},
+			isLCurly:                     {stateBody | statePush, cfTry},
+			lexKeyword | (keyCatch << 8): {stateCatch | stateStay, 0},
+			0:                            {errMustCatch, cfError},
+		},
+		{ // stateCatch
+			lexKeyword | (keyCatch << 8): {stateCatchVar | statePush, 0},
+			0:                            {stateBody | stateStay, 0},
+		},
+		{ // stateCatchVar
+			lexIdent: {stateBlock, cfCatch},
+			0:        {errMustName, cfError},
+		},
 	}
 )
 
@@ -341,6 +381,8 @@ func fError(buf *[]*Block, state int, lexem *Lexem) error {
 		`must be type`,             // errVarType
 		`must be '='`,              // errAssign
 		`must be number or string`, // errStrNum
+		`must be 'in'`,             // errMustIn
+		`must be 'catch'`,          // errMustCatch
 	}
 	fmt.Printf("%s %x %v [Ln:%d Col:%d]\r\n", errors[state], lexem.Type, lexem.Value, lexem.Line, lexem.Column)
 	logger := lexem.GetLogger()
@@ -359,12 +401,12 @@ func fFuncResult(buf *[]*Block, state int, lexem *Lexem) error {
 }
 
 func fReturn(buf *[]*Block, state int, lexem *Lexem) error {
-	(*(*buf)[len(*buf)-1]).Code = append((*(*buf)[len(*buf)-1]).Code, &ByteCode{cmdReturn, 0})
+	(*(*buf)[len(*buf)-1]).Code = append((*(*buf)[len(*buf)-1]).Code, &ByteCode{cmdReturn, 0, lexem.Line, lexem.Column})
 	return nil
 }
 
 func fCmdError(buf *[]*Block, state int, lexem *Lexem) error {
-	(*(*buf)[len(*buf)-1]).Code = append((*(*buf)[len(*buf)-1]).Code, &ByteCode{cmdError, lexem.Value})
+	(*(*buf)[len(*buf)-1]).Code = append((*(*buf)[len(*buf)-1]).Code, &ByteCode{cmdError, lexem.Value, lexem.Line, lexem.Column})
 	return nil
 }
 
@@ -488,23 +530,89 @@ func fFNameParam(buf *[]*Block, state int, lexem *Lexem) error {
 }
 
 func fIf(buf *[]*Block, state int, lexem *Lexem) error {
-	(*(*buf)[len(*buf)-2]).Code = append((*(*buf)[len(*buf)-2]).Code, &ByteCode{cmdIf, (*buf)[len(*buf)-1]})
+	(*(*buf)[len(*buf)-2]).Code = append((*(*buf)[len(*buf)-2]).Code, &ByteCode{cmdIf, (*buf)[len(*buf)-1], lexem.Line, lexem.Column})
 	return nil
 }
 
 func fWhile(buf *[]*Block, state int, lexem *Lexem) error {
-	(*(*buf)[len(*buf)-2]).Code = append((*(*buf)[len(*buf)-2]).Code, &ByteCode{cmdWhile, (*buf)[len(*buf)-1]})
-	(*(*buf)[len(*buf)-2]).Code = append((*(*buf)[len(*buf)-2]).Code, &ByteCode{cmdContinue, 0})
+	(*(*buf)[len(*buf)-2]).Code = append((*(*buf)[len(*buf)-2]).Code, &ByteCode{cmdWhile, (*buf)[len(*buf)-1], lexem.Line, lexem.Column})
+	(*(*buf)[len(*buf)-2]).Code = append((*(*buf)[len(*buf)-2]).Code, &ByteCode{cmdContinue, 0, lexem.Line, lexem.Column})
+	return nil
+}
+
+// fFor moves the expression of the loop to the parent block and assigns the key and the value
+// at the beginning of every iteration
+func fFor(buf *[]*Block, state int, lexem *Lexem) error {
+	block := (*buf)[len(*buf)-1]
+	parent := (*buf)[len(*buf)-2]
+	logger := lexem.GetLogger()
+	if len(block.Vars) != 2 {
+		logger.WithFields(log.Fields{"type": consts.ParseError}).Error("for must have key and value variables")
+		return fmt.Errorf(`for must have key and value variables [Ln:%d Col:%d]`, lexem.Line, lexem.Column)
+	}
+	for _, cmd := range block.Code {
+		if cmd.Cmd == cmdVar && cmd.Value.(*VarInfo).Owner == block {
+			logger.WithFields(log.Fields{"type": consts.ParseError}).Error("loop variable in for expression")
+			return fmt.Errorf(`loop variable cannot be used in for expression [Ln:%d Col:%d]`, cmd.Line, cmd.Column)
+		}
+	}
+	parent.Code = append(parent.Code, block.Code...)
+	parent.Code = append(parent.Code, &ByteCode{cmdFor, block, lexem.Line, lexem.Column})
+	assignStack(block, lexem)
+	return nil
+}
+
+// fTry appends the block of try statement to the parent block
+func fTry(buf *[]*Block, state int, lexem *Lexem) error {
+	code := (*(*buf)[len(*buf)-2]).Code
+	if len(code) > 0 && code[len(code)-1].Cmd == cmdTry {
+		return fError(buf, errMustCatch, lexem)
+	}
+	(*(*buf)[len(*buf)-2]).Code = append(code, &ByteCode{cmdTry, (*buf)[len(*buf)-1], lexem.Line, lexem.Column})
+	return nil
+}
+
+// fCatch declares the variable of the error and appends the block of catch statement
+// to the parent block
+func fCatch(buf *[]*Block, state int, lexem *Lexem) error {
+	block := (*buf)[len(*buf)-1]
+	code := (*(*buf)[len(*buf)-2]).Code
+	if len(code) == 0 || code[len(code)-1].Cmd != cmdTry {
+		logger := lexem.GetLogger()
+		logger.WithFields(log.Fields{"type": consts.ParseError}).Error("there is not try before")
+		return fmt.Errorf(`there is not try before catch [Ln:%d Col:%d]`, lexem.Line, lexem.Column)
+	}
+	if err := fFparam(buf, state, lexem); err != nil {
+		return err
+	}
+	(*(*buf)[len(*buf)-2]).Code = append(code, &ByteCode{cmdCatch, block, lexem.Line, lexem.Column})
+	assignStack(block, lexem)
 	return nil
 }
 
+// assignStack makes the block assign the values from the stack to its variables at the beginning.
+// The variables can have values of any type.
+func assignStack(block *Block, lexem *Lexem) {
+	assign := make([]*VarInfo, 0, len(block.Vars))
+	for off := range block.Vars {
+		for _, obj := range block.Objects {
+			if obj.Value.(int) == off {
+				assign = append(assign, &VarInfo{Obj: obj, Owner: block})
+			}
+		}
+		block.Vars[off] = loopType
+	}
+	block.Code = ByteCodes{&ByteCode{cmdAssignVar, assign, lexem.Line, lexem.Column},
+		&ByteCode{cmdAssign, 0, lexem.Line, lexem.Column}}
+}
+
 func fContinue(buf *[]*Block, state int, lexem *Lexem) error {
-	(*(*buf)[len(*buf)-1]).Code = append((*(*buf)[len(*buf)-1]).Code, &ByteCode{cmdContinue, 0})
+	(*(*buf)[len(*buf)-1]).Code = append((*(*buf)[len(*buf)-1]).Code, &ByteCode{cmdContinue, 0, lexem.Line, lexem.Column})
 	return nil
 }
 
 func fBreak(buf *[]*Block, state int, lexem *Lexem) error {
-	(*(*buf)[len(*buf)-1]).Code = append((*(*buf)[len(*buf)-1]).Code, &ByteCode{cmdBreak, 0})
+	(*(*buf)[len(*buf)-1]).Code = append((*(*buf)[len(*buf)-1]).Code, &ByteCode{cmdBreak, 0, lexem.Line, lexem.Column})
 	return nil
 }
 
@@ -536,15 +644,15 @@ func fAssignVar(buf *[]*Block, state int, lexem *Lexem) error {
 	}
 	prev = append(prev, &ivar)
 	if len(prev) == 1 {
-		(*(*buf)[len(*buf)-1]).Code = append((*block).Code, &ByteCode{cmdAssignVar, prev})
+		(*(*buf)[len(*buf)-1]).Code = append((*block).Code, &ByteCode{cmdAssignVar, prev, lexem.Line, lexem.Column})
 	} else {
-		(*(*buf)[len(*buf)-1]).Code[len(block.Code)-1] = &ByteCode{cmdAssignVar, prev}
+		(*(*buf)[len(*buf)-1]).Code[len(block.Code)-1] = &ByteCode{cmdAssignVar, prev, lexem.Line, lexem.Column}
 	}
 	return nil
 }
 
 func fAssign(buf *[]*Block, state int, lexem *Lexem) error {
-	(*(*buf)[len(*buf)-1]).Code = append((*(*buf)[len(*buf)-1]).Code, &ByteCode{cmdAssign, 0})
+	(*(*buf)[len(*buf)-1]).Code = append((*(*buf)[len(*buf)-1]).Code, &ByteCode{cmdAssign, 0, lexem.Line, lexem.Column})
 	return nil
 }
 
@@ -621,7 +729,7 @@ func fElse(buf *[]*Block, state int, lexem *Lexem) error {
 		logger.WithFields(log.Fields{"type": consts.ParseError}).Error("there is not if before")
 		return fmt.Errorf(`there is not if before %v [Ln:%d Col:%d]`, lexem.Type, lexem.Line, lexem.Column)
 	}
-	(*(*buf)[len(*buf)-2]).Code = append(code, &ByteCode{cmdElse, (*buf)[len(*buf)-1]})
+	(*(*buf)[len(*buf)-2]).Code = append(code, &ByteCode{cmdElse, (*buf)[len(*buf)-1], lexem.Line, lexem.Column})
 	return nil
 }
 
@@ -669,6 +777,9 @@ func (vm *VM) CompileBlock(input []rune, owner *OwnerInfo) (*Block, error) {
 	if len(lexems) == 0 {
 		return root, nil
 	}
+	if lexems, err = vm.records(root, lexems); err != nil {
+		return nil, err
+	}
 	curState := 0
 	stack := make([]int, 0, 64)
 	blockstack := make([]*Block, 1, 64)
@@ -701,7 +812,7 @@ func (vm *VM) CompileBlock(input []rune, owner *OwnerInfo) (*Block, error) {
 		}
 		if nextState == stateEval {
 			if newState.NewState&stateLabel > 0 {
-				(*blockstack[len(blockstack)-1]).Code = append((*blockstack[len(blockstack)-1]).Code, &ByteCode{cmdLabel, 0})
+				(*blockstack[len(blockstack)-1]).Code = append((*blockstack[len(blockstack)-1]).Code, &ByteCode{cmdLabel, 0, lexem.Line, lexem.Column})
 			}
 			curlen := len((*blockstack[len(blockstack)-1]).Code)
 			if err := vm.compileEval(&lexems, &i, &blockstack); err != nil {
@@ -735,7 +846,7 @@ func (vm *VM) CompileBlock(input []rune, owner *OwnerInfo) (*Block, error) {
 				if len(prev.Code) > 0 && (*prev).Code[len((*prev).Code)-1].Cmd == cmdContinue {
 					(*prev).Code = (*prev).Code[:len((*prev).Code)-1]
 					prev = blockstack[len(blockstack)-1]
-					(*prev).Code = append((*prev).Code, &ByteCode{cmdContinue, 0})
+					(*prev).Code = append((*prev).Code, &ByteCode{cmdContinue, 0, lexem.Line, lexem.Column})
 				}
 			}
 			blockstack = blockstack[:len(blockstack)-1]
@@ -847,6 +958,7 @@ func (vm *VM) compileEval(lexems *Lexems, ind *int, block *[]*Block) error {
 	var indexInfo *IndexInfo
 
 	i := *ind
+	line, column := (*lexems)[i].Line, (*lexems)[i].Column
 	curBlock := (*block)[len(*block)-1]
 
 	buffer := make(ByteCodes, 0, 20)
@@ -874,9 +986,9 @@ main:
 			}
 			break main
 		case isLPar:
-			buffer = append(buffer, &ByteCode{cmdSys, uint16(0xff)})
+			buffer = append(buffer, &ByteCode{cmdSys, uint16(0xff), lexem.Line, lexem.Column})
 		case isLBrack:
-			buffer = append(buffer, &ByteCode{cmdSys, uint16(0xff)})
+			buffer = append(buffer, &ByteCode{cmdSys, uint16(0xff), lexem.Line, lexem.Column})
 		case isComma:
 			if len(parcount) > 0 {
 				parcount[len(parcount)-1]++
@@ -916,7 +1028,7 @@ main:
 				if prev := buffer[len(buffer)-1]; prev.Cmd == cmdCall || prev.Cmd == cmdCallVari {
 					if prev.Value.(*ObjInfo).Type == ObjFunc && prev.Value.(*ObjInfo).Value.(*Block).Info.(*FuncInfo).Names != nil {
 						if len(bytecode) == 0 || bytecode[len(bytecode)-1].Cmd != cmdFuncName {
-							bytecode = append(bytecode, &ByteCode{cmdPush, nil})
+							bytecode = append(bytecode, &ByteCode{cmdPush, nil, lexem.Line, lexem.Column})
 						}
 						if i < len(*lexems)-4 && (*lexems)[i+1].Type == isDot {
 							if (*lexems)[i+2].Type != lexIdent {
@@ -929,7 +1041,7 @@ main:
 								if i < len(*lexems)-5 && (*lexems)[i+3].Type == isLPar {
 									objInfo, _ := vm.findObj((*lexems)[i+2].Value.(string), block)
 									if objInfo != nil && objInfo.Type == ObjFunc || objInfo.Type == ObjExtFunc {
-										tail = &ByteCode{uint16(cmdCall), objInfo}
+										tail = &ByteCode{uint16(cmdCall), objInfo, lexem.Line, lexem.Column}
 									}
 								}
 								if tail == nil {
@@ -938,7 +1050,7 @@ main:
 								}
 							}
 							if tail == nil {
-								buffer = append(buffer, &ByteCode{cmdFuncName, FuncNameCmd{Name: (*lexems)[i+2].Value.(string)}})
+								buffer = append(buffer, &ByteCode{cmdFuncName, FuncNameCmd{Name: (*lexems)[i+2].Value.(string)}, lexem.Line, lexem.Column})
 								count := 0
 								if (*lexems)[i+3].Type != isRPar {
 									count++
@@ -967,7 +1079,7 @@ main:
 						}
 					}
 					if prev.Cmd == cmdCallVari {
-						bytecode = append(bytecode, &ByteCode{cmdPush, count})
+						bytecode = append(bytecode, &ByteCode{cmdPush, count, lexem.Line, lexem.Column})
 					}
 					buffer = buffer[:len(buffer)-1]
 					bytecode = append(bytecode, prev)
@@ -1016,7 +1128,7 @@ main:
 					oper.Cmd = cmdSign
 					oper.Priority = cmdUnary
 				}
-				byteOper := &ByteCode{oper.Cmd, oper.Priority}
+				byteOper := &ByteCode{oper.Cmd, oper.Priority, lexem.Line, lexem.Column}
 				for {
 					if len(buffer) == 0 {
 						buffer = append(buffer, byteOper)
@@ -1045,7 +1157,7 @@ main:
 				return fmt.Errorf(`unknown operator %d`, lexem.Value.(uint32))
 			}
 		case lexNumber, lexString:
-			cmd = &ByteCode{cmdPush, lexem.Value}
+			cmd = &ByteCode{cmdPush, lexem.Value, lexem.Line, lexem.Column}
 		case lexExtend:
 			if i < len(*lexems)-2 {
 				if (*lexems)[i+1].Type == isLPar {
@@ -1054,18 +1166,22 @@ main:
 						count++
 					}
 					parcount = append(parcount, count)
-					buffer = append(buffer, &ByteCode{cmdCallExtend, lexem.Value.(string)})
+					buffer = append(buffer, &ByteCode{cmdCallExtend, lexem.Value.(string), lexem.Line, lexem.Column})
 					call = true
 				}
 			}
 			if !call {
-				cmd = &ByteCode{cmdExtend, lexem.Value.(string)}
+				cmd = &ByteCode{cmdExtend, lexem.Value.(string), lexem.Line, lexem.Column}
 				if i < len(*lexems)-1 && (*lexems)[i+1].Type == isLBrack {
-					buffer = append(buffer, &ByteCode{cmdIndex, &IndexInfo{Extend: lexem.Value.(string)}})
+					buffer = append(buffer, &ByteCode{cmdIndex, &IndexInfo{Extend: lexem.Value.(string)}, lexem.Line, lexem.Column})
 				}
 			}
 		case lexIdent:
 			objInfo, tobj := vm.findObj(lexem.Value.(string), block)
+			if objInfo != nil && objInfo.Type == ObjRecord {
+				logger.WithFields(log.Fields{"lex_value": lexem.Value.(string), "type": consts.ParseError}).Error("record type is used as value")
+				return fmt.Errorf(eRecordValue, lexem.Value.(string))
+			}
 			if objInfo == nil && (!vm.Extern || i > *ind || i >= len(*lexems)-2 || (*lexems)[i+1].Type != isLPar) {
 				logger.WithFields(log.Fields{"lex_value": lexem.Value.(string), "type": consts.ParseError}).Error("unknown identifier")
 				return fmt.Errorf(`unknown identifier %s`, lexem.Value.(string))
@@ -1094,7 +1210,7 @@ main:
 					if (*lexems)[i+2].Type != isRPar {
 						count++
 					}
-					buffer = append(buffer, &ByteCode{cmdCall, objInfo})
+					buffer = append(buffer, &ByteCode{cmdCall, objInfo, lexem.Line, lexem.Column})
 					if isContract {
 						name := StateName((*block)[0].Info.(uint32), lexem.Value.(string))
 						for j := len(*block) - 1; j >= 0; j-- {
@@ -1109,17 +1225,17 @@ main:
 								topblock.Info.(*ContractInfo).Used[name] = true
 							}
 						}
-						bytecode = append(bytecode, &ByteCode{cmdPush, name})
+						bytecode = append(bytecode, &ByteCode{cmdPush, name, lexem.Line, lexem.Column})
 						if count == 0 {
 							count = 2
-							bytecode = append(bytecode, &ByteCode{cmdPush, ""})
-							bytecode = append(bytecode, &ByteCode{cmdPush, ""})
+							bytecode = append(bytecode, &ByteCode{cmdPush, "", lexem.Line, lexem.Column})
+							bytecode = append(bytecode, &ByteCode{cmdPush, "", lexem.Line, lexem.Column})
 						}
 						count++
 					}
 					if lexem.Value.(string) == `CallContract` {
 						count++
-						bytecode = append(bytecode, &ByteCode{cmdPush, (*block)[0].Info.(uint32)})
+						bytecode = append(bytecode, &ByteCode{cmdPush, (*block)[0].Info.(uint32), lexem.Line, lexem.Column})
 					}
 					parcount = append(parcount, count)
 					call = true
@@ -1129,16 +1245,16 @@ main:
 						logger.WithFields(log.Fields{"lex_value": lexem.Value.(string), "type": consts.ParseError}).Error("unknown variable")
 						return fmt.Errorf(`unknown variable %s`, lexem.Value.(string))
 					}
-					buffer = append(buffer, &ByteCode{cmdIndex, &IndexInfo{objInfo.Value.(int), tobj, ``}})
+					buffer = append(buffer, &ByteCode{cmdIndex, &IndexInfo{objInfo.Value.(int), tobj, ``}, lexem.Line, lexem.Column})
 				}
 			}
 			if !call {
-				cmd = &ByteCode{cmdVar, &VarInfo{objInfo, tobj}}
+				cmd = &ByteCode{cmdVar, &VarInfo{objInfo, tobj}, lexem.Line, lexem.Column}
 			}
 		}
 		if lexem.Type&0xff == lexKeyword {
 			if lexem.Value.(uint32) == keyTail {
-				cmd = &ByteCode{cmdUnwrapArr, 0}
+				cmd = &ByteCode{cmdUnwrapArr, 0, lexem.Line, lexem.Column}
 			}
 		}
 		if cmd != nil {
@@ -1154,7 +1270,7 @@ main:
 		bytecode = append(bytecode, buffer[i])
 	}
 	if setIndex {
-		bytecode = append(bytecode, &ByteCode{cmdSetIndex, indexInfo})
+		bytecode = append(bytecode, &ByteCode{cmdSetIndex, indexInfo, line, column})
 	}
 	curBlock.Code = append(curBlock.Code, bytecode...)
 	return nil
@@ -496,6 +496,44 @@ func TestVMCompile(t *testing.T) {
 			return Sprintf("%d", result)
 		}
 					`, `result`, `100`},
+		{`func first(list array) string {
+			for i, v in list {
+				if i == 1 {
+					return v
+				}
+			}
+			return ""
+		}
+		func forin string {
+			var my map
+			var ret string
+			my["b"] = 2
+			my["a"] = 1
+			my["c"] = 3
+			for key, value in my {
+				if key == "c" {
+					break
+				}
+				ret = ret + Sprintf("%s=%d;", key, value)
+			}
+			for i, v in GetArray() {
+				if i == 0 {
+					continue
+				}
+				ret = ret + Sprintf("%d:%v;", i, v)
+			}
+			return ret + first(GetArray())
+		}`, `forin`, `a=1;b=2;1:The second string;2:2000;The second string`},
+		{`func forstr string {
+			for i, v in "abc" {
+			}
+			return ""
+		}`, `forstr`, `type string doesn't support for loop`},
+		{`func fortwo string {
+			for i in GetArray() {
+			}
+			return ""
+		}`, `fortwo`, `for must have key and value variables [Ln:2 Col:11]`},
 	}
 	vm := NewVM()
 	vm.Extern = true
@@ -0,0 +1,249 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package script
+
+import (
+	"fmt"
+	"runtime/debug"
+
+	"github.com/AplaProject/go-apla/packages/consts"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// DebugStep executes one command of the byte-code
+	DebugStep = `step`
+	// DebugLine executes the byte-code until the next line of the source code including
+	// the lines of the called functions
+	DebugLine = `line`
+	// DebugContinue executes the byte-code until the next breakpoint
+	DebugContinue = `continue`
+	// DebugStop stops the execution
+	DebugStop = `stop`
+)
+
+var cmdNames = map[uint16]string{
+	cmdPush: `push`, cmdVar: `var`, cmdExtend: `extend`, cmdCallExtend: `callextend`,
+	cmdPushStr: `pushstr`, cmdCall: `call`, cmdCallVari: `callvari`, cmdReturn: `return`,
+	cmdIf: `if`, cmdElse: `else`, cmdAssignVar: `assignvar`, cmdAssign: `assign`,
+	cmdLabel: `label`, cmdContinue: `continue`, cmdWhile: `while`, cmdFor: `for`, cmdTry: `try`, cmdCatch: `catch`, cmdBreak: `break`,
+	cmdIndex: `index`, cmdSetIndex: `setindex`, cmdFuncName: `funcname`,
+	cmdUnwrapArr: `unwraparr`, cmdError: `error`, cmdNot: `not`, cmdSign: `sign`,
+	cmdAdd: `add`, cmdSub: `sub`, cmdMul: `mul`, cmdDiv: `div`, cmdAnd: `and`, cmdOr: `or`,
+	cmdEqual: `equal`, cmdNotEq: `noteq`, cmdLess: `less`, cmdNotLess: `notless`,
+	cmdGreat: `great`, cmdNotGreat: `notgreat`,
+}
+
+// Breakpoint is a place where the debugger pauses the execution. Empty Contract or Func matches
+// any contract or function. If Line is zero then the execution is paused at the beginning
+// of the function.
+type Breakpoint struct {
+	Contract string `json:"contract"`
+	Func     string `json:"func"`
+	Line     uint32 `json:"line"`
+}
+
+// DebugState is the state of the paused execution
+type DebugState struct {
+	Contract string                 `json:"contract,omitempty"`
+	Func     string                 `json:"func,omitempty"`
+	Line     uint32                 `json:"line,omitempty"`
+	Cmd      string                 `json:"cmd,omitempty"`
+	Stack    []interface{}          `json:"stack,omitempty"`
+	Vars     map[string]interface{} `json:"vars,omitempty"`
+	Extend   map[string]interface{} `json:"extend,omitempty"`
+	Finished bool                   `json:"finished"`
+	Result   interface{}            `json:"result,omitempty"`
+	Error    string                 `json:"error,omitempty"`
+}
+
+// Debugger executes the byte-code step by step. The code is run in a separate goroutine
+// which is paused before the commands and waits for the next command of the debugger.
+type Debugger struct {
+	Breakpoints []Breakpoint
+
+	mode     string
+	line     uint32
+	fblock   *Block
+	finished bool
+	commands chan string
+	states   chan *DebugState
+}
+
+// NewDebugger returns a new debugger with the specified breakpoints
+func NewDebugger(breakpoints []Breakpoint) *Debugger {
+	return &Debugger{
+		Breakpoints: breakpoints,
+		mode:        DebugContinue,
+		commands:    make(chan string),
+		states:      make(chan *DebugState),
+	}
+}
+
+// SetDebugger attaches the debugger to the runtime
+func (rt *RunTime) SetDebugger(d *Debugger) {
+	rt.debug = d
+}
+
+// Start runs the function in a separate goroutine and returns the state of the first pause
+// or the final state if the execution has not been paused
+func (d *Debugger) Start(run func() (interface{}, error)) *DebugState {
+	go func() {
+		state := &DebugState{Finished: true}
+		defer func() {
+			if r := recover(); r != nil {
+				log.WithFields(log.Fields{"type": consts.PanicRecoveredError, "error_info": r, "stack": string(debug.Stack())}).Error("debugger panic error")
+				state.Error = fmt.Sprint(r)
+			}
+			d.states <- state
+		}()
+		result, err := run()
+		state.Result = result
+		if err != nil {
+			state.Error = err.Error()
+		}
+	}()
+	return d.wait()
+}
+
+// Command continues the paused execution and returns the state of the next pause
+func (d *Debugger) Command(cmd string) (*DebugState, error) {
+	if d.finished {
+		return nil, errDebugFinished
+	}
+	switch cmd {
+	case DebugStep, DebugLine, DebugContinue, DebugStop:
+	default:
+		return nil, errDebugCommand
+	}
+	d.commands <- cmd
+	return d.wait(), nil
+}
+
+// Finished returns true if the execution has been finished
+func (d *Debugger) Finished() bool {
+	return d.finished
+}
+
+func (d *Debugger) wait() *DebugState {
+	state := <-d.states
+	d.finished = state.Finished
+	return state
+}
+
+// funcBlock returns the block of the function which is being executed
+func funcBlock(rt *RunTime) (int, *Block) {
+	for i := len(rt.blocks) - 1; i >= 0; i-- {
+		if rt.blocks[i].Block.Type == ObjFunc {
+			return i, rt.blocks[i].Block
+		}
+	}
+	return 0, nil
+}
+
+// blockNames returns the names of the contract and the function of the block
+func blockNames(fblock *Block) (contract, fname string) {
+	if fblock == nil || fblock.Parent == nil {
+		return
+	}
+	if fblock.Parent.Type == ObjContract {
+		contract = fblock.Parent.Info.(*ContractInfo).Name
+	}
+	for name, obj := range fblock.Parent.Objects {
+		if obj.Type == ObjFunc && obj.Value.(*Block) == fblock {
+			fname = name
+			break
+		}
+	}
+	return
+}
+
+func (bp *Breakpoint) match(contract, fname string, line uint32, entry, newLine bool) bool {
+	if len(bp.Contract) > 0 && bp.Contract != contract {
+		if _, name := ParseContract(contract); bp.Contract != name {
+			return false
+		}
+	}
+	if len(bp.Func) > 0 && bp.Func != fname {
+		return false
+	}
+	if bp.Line == 0 {
+		return entry
+	}
+	return newLine && bp.Line == line
+}
+
+// trace is called by RunCode before every command
+func (d *Debugger) trace(rt *RunTime, block *Block, ci int) error {
+	if d.mode == DebugStop {
+		return errDebugStopped
+	}
+	cmd := block.Code[ci]
+	off, fblock := funcBlock(rt)
+	newLine := cmd.Line != d.line || fblock != d.fblock
+	d.line, d.fblock = cmd.Line, fblock
+
+	contract, fname := blockNames(fblock)
+	var pause bool
+	switch d.mode {
+	case DebugStep:
+		pause = true
+	case DebugLine:
+		pause = newLine
+	default:
+		for i := range d.Breakpoints {
+			if d.Breakpoints[i].match(contract, fname, cmd.Line, block == fblock && ci == 0, newLine) {
+				pause = true
+				break
+			}
+		}
+	}
+	if !pause {
+		return nil
+	}
+	state := &DebugState{
+		Contract: contract,
+		Func:     fname,
+		Line:     cmd.Line,
+		Cmd:      cmdNames[cmd.Cmd],
+		Stack:    append([]interface{}{}, rt.stack...),
+		Vars:     make(map[string]interface{}),
+		Extend:   make(map[string]interface{}),
+	}
+	for _, item := range rt.blocks[off:] {
+		for name, obj := range item.Block.Objects {
+			if obj.Type == ObjVar && item.Offset+obj.Value.(int) < len(rt.vars) {
+				state.Vars[name] = rt.vars[item.Offset+obj.Value.(int)]
+			}
+		}
+	}
+	if rt.extend != nil {
+		for key, value := range *rt.extend {
+			if key == `sc` {
+				continue
+			}
+			state.Extend[key] = value
+		}
+	}
+	d.states <- state
+	d.mode = <-d.commands
+	if d.mode == DebugStop {
+		return errDebugStopped
+	}
+	return nil
+}
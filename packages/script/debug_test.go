@@ -0,0 +1,87 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package script
+
+import (
+	"testing"
+)
+
+func TestDebugger(t *testing.T) {
+	vm := NewVM()
+	err := vm.Compile([]rune(`func sum(a int) int {
+		var i, s int
+		while i < a {
+			i = i + 1
+			s = s + i
+		}
+		return s
+	}
+	func main int {
+		var total int
+		total = sum(3)
+		return total * 2
+	}`), &OwnerInfo{StateID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	run := func(d *Debugger) *DebugState {
+		return d.Start(func() (interface{}, error) {
+			rt := vm.RunInit(CostDefault)
+			rt.SetDebugger(d)
+			ret, err := rt.Run(vm.getObjByNameExt(`main`, 1).Value.(*Block), nil,
+				&map[string]interface{}{`rt_state`: uint32(1)})
+			if err != nil {
+				return nil, err
+			}
+			return ret[0], nil
+		})
+	}
+
+	d := NewDebugger([]Breakpoint{{Func: `sum`, Line: 5}})
+	state := run(d)
+	if state.Finished || state.Func != `sum` || state.Line != 5 {
+		t.Fatalf(`wrong breakpoint %+v`, state)
+	}
+	for i := int64(1); i <= 3; i++ {
+		if state.Vars[`i`] != i {
+			t.Errorf(`wrong variable i %v != %d`, state.Vars[`i`], i)
+		}
+		if state, err = d.Command(DebugContinue); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !state.Finished || state.Result != int64(12) {
+		t.Errorf(`wrong result %+v`, state)
+	}
+	if _, err = d.Command(DebugStep); err != errDebugFinished {
+		t.Errorf(`finished debugger must return error %v`, err)
+	}
+
+	d = NewDebugger([]Breakpoint{{Func: `main`}})
+	if state = run(d); state.Func != `main` || state.Line != 11 {
+		t.Fatalf(`wrong entry breakpoint %+v`, state)
+	}
+	if state, err = d.Command(DebugLine); err != nil || state.Func != `sum` || state.Line != 3 {
+		t.Fatalf(`wrong next line %+v %v`, state, err)
+	}
+	if state, err = d.Command(DebugStep); err != nil || state.Line != 3 || state.Cmd != `var` {
+		t.Fatalf(`wrong step %+v %v`, state, err)
+	}
+	if state, err = d.Command(DebugStop); err != nil || !state.Finished || state.Error != errDebugStopped.Error() {
+		t.Errorf(`wrong stop %+v %v`, state, err)
+	}
+}
@@ -27,6 +27,14 @@ const (
 	eWrongParams     = `function %s must have %d parameters`
 	eArrIndex        = `index of array cannot be type %s`
 	eMapIndex        = `index of map cannot be type %s`
+	eForType         = `type %s doesn't support for loop`
+	eRecordField     = `type %s doesn't have field %s`
+	eRecordFieldType = `type %s: field %s must be %s`
+	eTypeRecord      = `%s cannot be converted to %s`
+	eRecordValue     = `type %s cannot be used as a value`
+	eBytecodeType    = `unsupported type %s in byte-code`
+	eBytecodeValue   = `unsupported value %s in byte-code`
+	eBytecodeObject  = `unknown object %s in byte-code`
 )
 
 var (
@@ -38,3 +46,17 @@ var (
 	errMaxMapCount     = errors.New(`The maxumim length of map`)
 	errRecursion       = errors.New(`The contract can't call itself recursively`)
 )
+
+var (
+	errBytecodeFormat   = errors.New(`wrong format of byte-code`)
+	errBytecodeVersion  = errors.New(`unsupported version of byte-code`)
+	errBytecodeChecksum = errors.New(`wrong checksum of byte-code`)
+	errBytecodeExtern   = errors.New(`byte-code has been compiled in another mode`)
+	errBytecodeBlock    = errors.New(`unknown block in byte-code`)
+)
+
+var (
+	errDebugStopped  = errors.New(`debugging has been stopped`)
+	errDebugFinished = errors.New(`debugging has been finished`)
+	errDebugCommand  = errors.New(`unknown debugger command`)
+)
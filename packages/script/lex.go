@@ -107,6 +107,10 @@ const (
 	keyCond
 	keyTail
 	keyError
+	keyFor
+	keyIn
+	keyTry
+	keyCatch
 )
 
 const (
@@ -122,7 +126,8 @@ var (
 		msgInfo: keyInfo, `while`: keyWhile, `data`: keyTX, `settings`: keySettings, `nil`: keyNil,
 		`action`: keyAction, `conditions`: keyCond,
 		`true`: keyTrue, `false`: keyFalse, `break`: keyBreak, `continue`: keyContinue,
-		`var`: keyVar, `...`: keyTail}
+		`var`: keyVar, `...`: keyTail, `for`: keyFor, `in`: keyIn,
+		`try`: keyTry, `catch`: keyCatch}
 	// list of available types
 	// The list of types which save the corresponding 'reflect' type
 	types = map[string]reflect.Type{`bool`: reflect.TypeOf(true), `bytes`: reflect.TypeOf([]byte{}),
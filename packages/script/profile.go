@@ -0,0 +1,341 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package script
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// profileFrame is a function or an extended function in the call stack
+type profileFrame struct {
+	contract string
+	name     string
+	line     uint32
+	builtin  bool
+}
+
+func (frame profileFrame) funcName() string {
+	if len(frame.contract) > 0 && !frame.builtin {
+		return frame.contract + `.` + frame.name
+	}
+	return frame.name
+}
+
+type profileSample struct {
+	frames []profileFrame // the first frame is the root
+	fuel   int64
+	time   time.Duration
+}
+
+// Profiler attributes the fuel and the time of the execution to the call stacks. The fuel
+// and the time which have been spent since the previous command are added to the stack of
+// the previous command.
+type Profiler struct {
+	samples  map[string]*profileSample
+	current  []profileFrame
+	callers  [][]profileFrame
+	lastCost int64
+	lastTime time.Time
+	start    time.Time
+}
+
+// ProfileItem is the fuel and the time of the contract, the function, the builtin or the line
+type ProfileItem struct {
+	Name string `json:"name"`
+	Line uint32 `json:"line,omitempty"`
+	Fuel int64  `json:"fuel"`
+	Time int64  `json:"time_ns"`
+}
+
+// ProfileSummary contains the fuel and the time which have been spent by the own code of
+// contracts, functions, builtins and lines. Items are sorted by the fuel.
+type ProfileSummary struct {
+	Fuel      int64         `json:"fuel"`
+	Time      int64         `json:"time_ns"`
+	Contracts []ProfileItem `json:"contracts"`
+	Funcs     []ProfileItem `json:"funcs"`
+	Builtins  []ProfileItem `json:"builtins"`
+	Lines     []ProfileItem `json:"lines"`
+}
+
+// NewProfiler returns a new profiler
+func NewProfiler() *Profiler {
+	return &Profiler{
+		samples: make(map[string]*profileSample),
+		start:   time.Now(),
+	}
+}
+
+// SetProfiler attaches the profiler to the runtime
+func (rt *RunTime) SetProfiler(p *Profiler) {
+	rt.profiler = p
+}
+
+func (p *Profiler) add(now time.Time, cost int64) {
+	if p.current == nil {
+		return
+	}
+	keys := make([]string, len(p.current))
+	for i, frame := range p.current {
+		keys[i] = fmt.Sprintf(`%s:%d`, frame.funcName(), frame.line)
+	}
+	key := strings.Join(keys, `;`)
+	sample := p.samples[key]
+	if sample == nil {
+		sample = &profileSample{frames: p.current}
+		p.samples[key] = sample
+	}
+	sample.fuel += p.lastCost - cost
+	sample.time += now.Sub(p.lastTime)
+}
+
+// trace is called by RunCode before every command
+func (p *Profiler) trace(rt *RunTime, block *Block, ci int) {
+	now := time.Now()
+	p.add(now, rt.cost)
+
+	cmd := block.Code[ci]
+	rt.blocks[len(rt.blocks)-1].Line = cmd.Line
+	frames := make([]profileFrame, 0, 8)
+	if len(p.callers) > 0 {
+		frames = append(frames, p.callers[len(p.callers)-1]...)
+	}
+	for _, item := range rt.blocks {
+		if item.Block.Type == ObjFunc {
+			var frame profileFrame
+			frame.contract, frame.name = blockNames(item.Block)
+			frames = append(frames, frame)
+		}
+		if len(frames) > 0 {
+			frames[len(frames)-1].line = item.Line
+		}
+	}
+	if cmd.Cmd == cmdCall || cmd.Cmd == cmdCallVari {
+		if obj := cmd.Value.(*ObjInfo); obj.Type == ObjExtFunc {
+			frames = append(frames, profileFrame{name: obj.Value.(ExtFuncInfo).Name, builtin: true})
+		}
+	}
+	p.current = frames
+	p.lastCost = rt.cost
+	p.lastTime = now
+}
+
+// flush is called when the runtime has been finished
+func (p *Profiler) flush(rt *RunTime) {
+	now := time.Now()
+	p.add(now, rt.cost)
+	p.current = nil
+	// the spent fuel has been added, so the caller doesn't get it again after pop
+	p.lastCost = rt.cost
+	p.lastTime = now
+}
+
+// push is called before the call of the contract, the stack of the caller becomes the root
+// of the stacks of the contract
+func (p *Profiler) push() {
+	p.callers = append(p.callers, p.current)
+}
+
+// pop is called when the called contract has been finished
+func (p *Profiler) pop() {
+	p.current = p.callers[len(p.callers)-1]
+	p.callers = p.callers[:len(p.callers)-1]
+	p.lastTime = time.Now()
+}
+
+func profileItems(items map[profileFrame]*ProfileItem) []ProfileItem {
+	list := make([]ProfileItem, 0, len(items))
+	for _, item := range items {
+		list = append(list, *item)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Fuel != list[j].Fuel {
+			return list[i].Fuel > list[j].Fuel
+		}
+		if list[i].Name != list[j].Name {
+			return list[i].Name < list[j].Name
+		}
+		return list[i].Line < list[j].Line
+	})
+	return list
+}
+
+// Summary returns the fuel and the time which have been spent by the own code of contracts,
+// functions, builtins and lines
+func (p *Profiler) Summary() *ProfileSummary {
+	var summary ProfileSummary
+	contracts := make(map[profileFrame]*ProfileItem)
+	funcs := make(map[profileFrame]*ProfileItem)
+	builtins := make(map[profileFrame]*ProfileItem)
+	lines := make(map[profileFrame]*ProfileItem)
+	addItem := func(items map[profileFrame]*ProfileItem, key profileFrame, sample *profileSample) {
+		item := items[key]
+		if item == nil {
+			item = &ProfileItem{Name: key.funcName(), Line: key.line}
+			items[key] = item
+		}
+		item.Fuel += sample.fuel
+		item.Time += int64(sample.time)
+	}
+	for _, sample := range p.samples {
+		summary.Fuel += sample.fuel
+		summary.Time += int64(sample.time)
+		leaf := sample.frames[len(sample.frames)-1]
+		if leaf.builtin {
+			addItem(builtins, profileFrame{name: leaf.name, builtin: true}, sample)
+			if len(sample.frames) == 1 {
+				continue
+			}
+			leaf = sample.frames[len(sample.frames)-2]
+		}
+		if len(leaf.contract) > 0 {
+			addItem(contracts, profileFrame{name: leaf.contract, builtin: true}, sample)
+		}
+		addItem(funcs, profileFrame{contract: leaf.contract, name: leaf.name}, sample)
+		addItem(lines, leaf, sample)
+	}
+	summary.Contracts = profileItems(contracts)
+	summary.Funcs = profileItems(funcs)
+	summary.Builtins = profileItems(builtins)
+	summary.Lines = profileItems(lines)
+	return &summary
+}
+
+// protoBuffer writes the protocol buffers message
+type protoBuffer struct {
+	bytes.Buffer
+}
+
+func (pb *protoBuffer) varint(v uint64) {
+	var buf [binary.MaxVarintLen64]byte
+	pb.Write(buf[:binary.PutUvarint(buf[:], v)])
+}
+
+func (pb *protoBuffer) uint(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	pb.varint(uint64(field) << 3)
+	pb.varint(v)
+}
+
+func (pb *protoBuffer) bytes(field int, data []byte) {
+	pb.varint(uint64(field)<<3 | 2)
+	pb.varint(uint64(len(data)))
+	pb.Write(data)
+}
+
+func (pb *protoBuffer) packed(field int, values []uint64) {
+	var buf protoBuffer
+	for _, v := range values {
+		buf.varint(v)
+	}
+	pb.bytes(field, buf.Bytes())
+}
+
+// WritePprof writes the gzipped profile in the format of pprof tool. The samples have
+// the fuel and the wall time in nanoseconds.
+func (p *Profiler) WritePprof(w io.Writer) error {
+	var (
+		out     protoBuffer
+		strs    = map[string]uint64{``: 0}
+		strList = []string{``}
+		funcs   = make(map[string]uint64)
+		locs    = make(map[string]uint64)
+	)
+	str := func(s string) uint64 {
+		if id, ok := strs[s]; ok {
+			return id
+		}
+		strs[s] = uint64(len(strList))
+		strList = append(strList, s)
+		return strs[s]
+	}
+	valueType := func(field int, vtype, unit string) {
+		var buf protoBuffer
+		buf.uint(1, str(vtype))
+		buf.uint(2, str(unit))
+		out.bytes(field, buf.Bytes())
+	}
+	valueType(1, `fuel`, `count`)
+	valueType(1, `time`, `nanoseconds`)
+
+	var body protoBuffer
+	keys := make([]string, 0, len(p.samples))
+	for key := range p.samples {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		sample := p.samples[key]
+		ids := make([]uint64, 0, len(sample.frames))
+		for i := len(sample.frames) - 1; i >= 0; i-- {
+			frame := sample.frames[i]
+			name := frame.funcName()
+			fid, ok := funcs[name]
+			if !ok {
+				fid = uint64(len(funcs) + 1)
+				funcs[name] = fid
+				var buf protoBuffer
+				buf.uint(1, fid)
+				buf.uint(2, str(name))
+				buf.uint(3, str(name))
+				buf.uint(4, str(frame.contract))
+				body.bytes(5, buf.Bytes())
+			}
+			lkey := fmt.Sprintf(`%s:%d`, name, frame.line)
+			lid, ok := locs[lkey]
+			if !ok {
+				lid = uint64(len(locs) + 1)
+				locs[lkey] = lid
+				var line, buf protoBuffer
+				line.uint(1, fid)
+				line.uint(2, uint64(frame.line))
+				buf.uint(1, lid)
+				buf.bytes(4, line.Bytes())
+				body.bytes(4, buf.Bytes())
+			}
+			ids = append(ids, lid)
+		}
+		var buf protoBuffer
+		buf.packed(1, ids)
+		buf.packed(2, []uint64{uint64(sample.fuel), uint64(sample.time)})
+		out.bytes(2, buf.Bytes())
+	}
+	out.Write(body.Bytes())
+	out.uint(9, uint64(p.start.UnixNano()))
+	out.uint(10, uint64(time.Since(p.start)))
+	valueType(11, `fuel`, `count`)
+	out.uint(12, 1)
+	// the string table is the last because all strings must be collected
+	for _, s := range strList {
+		out.bytes(6, []byte(s))
+	}
+
+	zw := gzip.NewWriter(w)
+	if _, err := zw.Write(out.Bytes()); err != nil {
+		return err
+	}
+	return zw.Close()
+}
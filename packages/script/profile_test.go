@@ -0,0 +1,88 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package script
+
+import (
+	"testing"
+)
+
+func TestProfilerNested(t *testing.T) {
+	vm := NewVM()
+	err := vm.Compile([]rune(`contract Inner {
+		action {
+			var i int
+			while i < 10 {
+				i = i + 1
+			}
+		}
+	}
+	contract Outer {
+		action {
+			var i int
+			while i < 5 {
+				i = i + 1
+			}
+			Inner()
+			i = 0
+			while i < 5 {
+				i = i + 1
+			}
+		}
+	}`), &OwnerInfo{StateID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	run := func(name string) (*Profiler, int64) {
+		p := NewProfiler()
+		rt := vm.RunInit(CostDefault)
+		rt.SetProfiler(p)
+		rt.extend = &map[string]interface{}{`rt_state`: uint32(1), `sc`: nil}
+		if _, err := ExContract(rt, 1, name, nil); err != nil {
+			t.Fatal(err)
+		}
+		return p, CostDefault - rt.cost
+	}
+	funcFuel := func(summary *ProfileSummary, name string) int64 {
+		for _, item := range summary.Funcs {
+			if item.Name == name {
+				return item.Fuel
+			}
+		}
+		return 0
+	}
+
+	p, inner := run(`Inner`)
+	innerAction := funcFuel(p.Summary(), `@1Inner.action`)
+	if innerAction == 0 {
+		t.Fatalf(`wrong profile of inner contract %+v`, p.Summary().Funcs)
+	}
+
+	// the price of the call of the first contract is spent before the profiler gets the stack
+	overhead := inner - innerAction
+
+	p, total := run(`Outer`)
+	summary := p.Summary()
+	if summary.Fuel != total-overhead {
+		t.Errorf(`wrong total fuel %d != %d`, summary.Fuel, total-overhead)
+	}
+	if fuel := funcFuel(summary, `@1Inner.action`); fuel != innerAction {
+		t.Errorf(`wrong fuel of nested contract %d != %d`, fuel, innerAction)
+	}
+	if fuel := funcFuel(summary, `@1Outer.action`); fuel != total-overhead-innerAction {
+		t.Errorf(`wrong fuel of parent contract %d != %d`, fuel, total-overhead-innerAction)
+	}
+}
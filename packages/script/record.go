@@ -0,0 +1,325 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package script
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+
+	"github.com/AplaProject/go-apla/packages/consts"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// The record types are declared at the top level of the source code
+//
+//  type Person {
+//      name string
+//      age int
+//  }
+//
+// The type is a pointer to the structure which is created by reflect.StructOf. The names of
+// the fields are stored in json tags so the records are encoded by JSONEncode as objects.
+// The types with the same name and fields are identical, so recompiling the source doesn't
+// change the type. The names of record types can be used everywhere where the types are
+// expected: variables, parameters and results of functions, data fields of contracts.
+
+const (
+	keywordType = `type`
+	recordTag   = `record`
+)
+
+// RecordName returns the full name of the record type or an empty string if the type isn't a record
+func RecordName(itype reflect.Type) string {
+	if itype == nil || itype.Kind() != reflect.Ptr || itype.Elem().Kind() != reflect.Struct ||
+		itype.Elem().NumField() == 0 {
+		return ``
+	}
+	return itype.Elem().Field(0).Tag.Get(recordTag)
+}
+
+// typeName returns the name of the type which is used in the source code
+func typeName(itype reflect.Type) string {
+	if name := RecordName(itype); len(name) > 0 {
+		return name
+	}
+	if name, ok := typeNames[itype]; ok {
+		return name
+	}
+	return itype.String()
+}
+
+func newRecordType(name string, fields []string, ftypes []reflect.Type) reflect.Type {
+	list := make([]reflect.StructField, len(fields))
+	for i, field := range fields {
+		list[i] = reflect.StructField{Name: fmt.Sprintf(`F%d`, i), Type: ftypes[i],
+			Tag: reflect.StructTag(fmt.Sprintf(`json:"%s" %s:"%s"`, field, recordTag, name))}
+	}
+	return reflect.PtrTo(reflect.StructOf(list))
+}
+
+// recordField returns the index of the field of the record type or -1 if the field doesn't exist
+func recordField(itype reflect.Type, field string) int {
+	stype := itype.Elem()
+	for i := 0; i < stype.NumField(); i++ {
+		if stype.Field(i).Tag.Get(`json`) == field {
+			return i
+		}
+	}
+	return -1
+}
+
+// newRecord creates a record with the initialized fields
+func newRecord(itype reflect.Type) interface{} {
+	rec := reflect.New(itype.Elem())
+	for i := 0; i < rec.Elem().NumField(); i++ {
+		rec.Elem().Field(i).Set(reflect.ValueOf(zeroValue(rec.Elem().Field(i).Type())))
+	}
+	return rec.Interface()
+}
+
+// zeroValue returns the initial value of the variable or the field of the specified type
+func zeroValue(itype reflect.Type) interface{} {
+	switch {
+	case itype == reflect.TypeOf(map[string]interface{}{}):
+		return make(map[string]interface{})
+	case itype == reflect.TypeOf([]interface{}{}):
+		return make([]interface{}, 0)
+	case len(RecordName(itype)) > 0:
+		return newRecord(itype)
+	}
+	return reflect.New(itype).Elem().Interface()
+}
+
+// getRecordField returns the value of the field of the record
+func getRecordField(rec interface{}, field string) (interface{}, error) {
+	rv := reflect.ValueOf(rec)
+	ind := recordField(rv.Type(), field)
+	if ind < 0 {
+		log.WithFields(log.Fields{"type": consts.VMError, "field": field}).Error("unknown field of record")
+		return nil, fmt.Errorf(eRecordField, RecordName(rv.Type()), field)
+	}
+	return rv.Elem().Field(ind).Interface(), nil
+}
+
+// setRecordField assigns the value to the field of the record if the value has the type of the field
+func setRecordField(rec interface{}, field string, value interface{}) error {
+	rv := reflect.ValueOf(rec)
+	ind := recordField(rv.Type(), field)
+	if ind < 0 {
+		log.WithFields(log.Fields{"type": consts.VMError, "field": field}).Error("unknown field of record")
+		return fmt.Errorf(eRecordField, RecordName(rv.Type()), field)
+	}
+	ftype := rv.Elem().Field(ind).Type()
+	val, err := recordValue(ftype, value)
+	if err != nil {
+		if len(RecordName(ftype)) > 0 {
+			return err
+		}
+		log.WithFields(log.Fields{"type": consts.VMError, "field": field}).Error("wrong type of record field")
+		return fmt.Errorf(eRecordFieldType, RecordName(rv.Type()), field, typeName(ftype))
+	}
+	rv.Elem().Field(ind).Set(reflect.ValueOf(val))
+	return nil
+}
+
+// recordValue converts the value to the type of the record field
+func recordValue(itype reflect.Type, value interface{}) (interface{}, error) {
+	if value == nil {
+		return zeroValue(itype), nil
+	}
+	vtype := reflect.TypeOf(value)
+	switch {
+	case vtype == itype:
+		return value, nil
+	case itype.String() == Decimal:
+		return ValueToDecimal(value)
+	case itype.Kind() == reflect.Float64 && vtype.Kind() == reflect.Int64:
+		return float64(value.(int64)), nil
+	case itype.Kind() == reflect.Int64 && vtype.Kind() == reflect.Float64:
+		// the numbers are decoded from JSON as float64
+		if val := value.(float64); val == math.Trunc(val) {
+			return int64(val), nil
+		}
+	case len(RecordName(itype)) > 0:
+		return ToRecord(itype, value)
+	}
+	return nil, fmt.Errorf(eTypeRecord, vtype.String(), typeName(itype))
+}
+
+// ToRecord converts the map to the record of the specified type. The map must contain only
+// the fields of the record and their values must have the types of the fields.
+func ToRecord(itype reflect.Type, value interface{}) (interface{}, error) {
+	if reflect.TypeOf(value) == itype {
+		return value, nil
+	}
+	var rec interface{}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		rec = newRecord(itype)
+		for key, item := range v {
+			if err := setRecordField(rec, key, item); err != nil {
+				return nil, err
+			}
+		}
+	case map[string]string:
+		rec = newRecord(itype)
+		for key, item := range v {
+			if err := setRecordField(rec, key, item); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		log.WithFields(log.Fields{"type": consts.ConversionError, "record": RecordName(itype)}).Error("converting to record")
+		return nil, fmt.Errorf(eTypeRecord, reflect.TypeOf(value).String(), RecordName(itype))
+	}
+	return rec, nil
+}
+
+// DecodeRecord converts JSON object to the record of the specified type
+func DecodeRecord(itype reflect.Type, input string) (interface{}, error) {
+	var value map[string]interface{}
+	if err := json.Unmarshal([]byte(input), &value); err != nil {
+		log.WithFields(log.Fields{"type": consts.JSONUnmarshallError, "error": err}).Error("unmarshalling record")
+		return nil, err
+	}
+	return ToRecord(itype, value)
+}
+
+// isTypePlace returns true if the type is expected after the list of lexems. These are
+// the declarations of variables, parameters and fields `name Type`, the results of functions
+// `func name(...) Type` and `func name(...) int, Type`.
+func isTypePlace(lexems Lexems) bool {
+	if len(lexems) == 0 {
+		return false
+	}
+	switch lexems[len(lexems)-1].Type {
+	case lexIdent, isRPar:
+		return true
+	case isComma:
+		return len(lexems) > 1 && lexems[len(lexems)-2].Type == lexType
+	}
+	return false
+}
+
+// records parses the declarations of record types and adds them to the root block. The names of
+// record types are replaced with type lexems. The declarations are removed from the list of lexems.
+func (vm *VM) records(root *Block, lexems Lexems) (Lexems, error) {
+	state := root.Info.(uint32)
+	local := make(map[string]reflect.Type)
+	getType := func(lexem *Lexem) reflect.Type {
+		if lexem.Type == lexType {
+			return lexem.Value.(reflect.Type)
+		}
+		if lexem.Type != lexIdent {
+			return nil
+		}
+		name := lexem.Value.(string)
+		if itype, ok := local[name]; ok {
+			return itype
+		}
+		if obj := vm.getObjByName(StateName(state, name)); obj != nil && obj.Type == ObjRecord {
+			return obj.Value.(reflect.Type)
+		}
+		return nil
+	}
+	var level int
+	out := make(Lexems, 0, len(lexems))
+	for i := 0; i < len(lexems); i++ {
+		lexem := lexems[i]
+		switch lexem.Type {
+		case isLCurly:
+			level++
+		case isRCurly:
+			level--
+		case lexIdent:
+			if level == 0 && lexem.Value.(string) == keywordType && i+2 < len(lexems) &&
+				lexems[i+1].Type == lexIdent && lexems[i+2].Type == isLCurly {
+				end, err := vm.record(root, lexems, i, getType)
+				if err != nil {
+					return nil, err
+				}
+				name := lexems[i+1].Value.(string)
+				local[name] = root.Objects[StateName(state, name)].Value.(reflect.Type)
+				i = end
+				continue
+			}
+			if isTypePlace(out) {
+				if itype := getType(lexem); itype != nil {
+					lexem = &Lexem{Type: lexType, Value: itype, Line: lexem.Line, Column: lexem.Column}
+				}
+			}
+		}
+		out = append(out, lexem)
+	}
+	return out, nil
+}
+
+// record parses the declaration of the record type which starts from the lexem with ind index.
+// It returns the index of the closing curly bracket.
+func (vm *VM) record(root *Block, lexems Lexems, ind int, getType func(*Lexem) reflect.Type) (int, error) {
+	var (
+		fields []string
+		ftypes []reflect.Type
+	)
+	name := StateName(root.Info.(uint32), lexems[ind+1].Value.(string))
+	if _, ok := root.Objects[name]; ok {
+		return 0, recordError(lexems[ind+1], `type %s has been already declared`, name)
+	}
+	i := ind + 3
+	for ; i < len(lexems) && lexems[i].Type != isRCurly; i++ {
+		lexem := lexems[i]
+		if lexem.Type == lexNewLine || lexem.Type == isComma {
+			continue
+		}
+		if lexem.Type != lexIdent || i+1 >= len(lexems) {
+			return 0, recordError(lexem, `must be the name of field`)
+		}
+		field := lexem.Value.(string)
+		for _, item := range fields {
+			if item == field {
+				return 0, recordError(lexem, `field %s has been already declared`, field)
+			}
+		}
+		i++
+		ftype := getType(lexems[i])
+		if ftype == nil {
+			return 0, recordError(lexems[i], `unknown type of field %s`, field)
+		}
+		fields = append(fields, field)
+		ftypes = append(ftypes, ftype)
+	}
+	if i >= len(lexems) {
+		return 0, recordError(lexems[len(lexems)-1], `must be '}'`)
+	}
+	if len(fields) == 0 {
+		return 0, recordError(lexems[ind+1], `type %s must have fields`, name)
+	}
+	if root.Objects == nil {
+		root.Objects = make(map[string]*ObjInfo)
+	}
+	root.Objects[name] = &ObjInfo{Type: ObjRecord, Value: newRecordType(name, fields, ftypes)}
+	return i, nil
+}
+
+func recordError(lexem *Lexem, format string, params ...interface{}) error {
+	err := fmt.Sprintf(format, params...)
+	lexem.GetLogger().WithFields(log.Fields{"type": consts.ParseError, "error": err}).Error("parsing record type")
+	return fmt.Errorf(`%s [Ln:%d Col:%d]`, err, lexem.Line, lexem.Column)
+}
@@ -0,0 +1,119 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package script
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestRecord(t *testing.T) {
+	vm := NewVM()
+	vm.Extend(&ExtendData{Objects: map[string]interface{}{
+		"Sprintf": fmt.Sprintf,
+		"JSONEncode": func(v interface{}) string {
+			out, _ := json.Marshal(v)
+			return string(out)
+		},
+		"JSONDecode": func(input string) interface{} {
+			var ret interface{}
+			json.Unmarshal([]byte(input), &ret)
+			return ret
+		},
+	}})
+	if err := vm.Compile([]rune(`type Address {
+	city string
+}
+type Person {
+	name string
+	age int
+	balance money
+	home Address
+}`), &OwnerInfo{StateID: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := vm.Compile([]rune(`func older(p Person, years int) Person {
+	p["age"] = p["age"] + years
+	return p
+}
+func test string {
+	var p, q Person
+	var home Address
+	p["name"] = "Bob"
+	home["city"] = "Paris"
+	p["home"] = home
+	p = older(p, 30)
+	q = JSONDecode("{\"name\":\"Ann\",\"age\":20,\"home\":{\"city\":\"Rome\"}}")
+	home = q["home"]
+	return Sprintf("%s %d %s", JSONEncode(p), q["age"], home["city"])
+}
+func wrongtype string {
+	var p Person
+	p["age"] = "20"
+	return ""
+}
+func wrongfield string {
+	var p Person
+	return p["phone"]
+}
+func wrongdecode string {
+	var p Person
+	p = JSONDecode("{\"name\":\"Ann\",\"age\":20.5}")
+	return ""
+}
+func wrongparam string {
+	var m map
+	return older(m, 1)
+}`), &OwnerInfo{StateID: 1}); err != nil {
+		t.Fatal(err)
+	}
+	for _, item := range []struct {
+		Func   string
+		Output string
+	}{
+		{`test`, `{"name":"Bob","age":30,"balance":"0","home":{"city":"Paris"}} 20 Rome`},
+		{`wrongtype`, `type @1Person: field age must be int`},
+		{`wrongfield`, `type @1Person doesn't have field phone`},
+		{`wrongdecode`, `type @1Person: field age must be int`},
+		{`wrongparam`, `parameter 1 has wrong type`},
+	} {
+		out, err := vm.Call(item.Func, nil, &map[string]interface{}{`rt_state`: uint32(1)})
+		if err != nil {
+			if err.Error() != item.Output {
+				t.Errorf(`%s: %v`, item.Func, err)
+			}
+		} else if out[0].(string) != item.Output {
+			t.Errorf(`%s: %s != %s`, item.Func, out[0], item.Output)
+		}
+	}
+
+	for _, src := range []string{`type Empty {
+	}`, `type Wrong {
+		field unknown
+	}`, `type Twice {
+		a int
+		a string
+	}`, `func test {
+		var a int
+		a = @1Person
+	}`} {
+		if err := vm.Compile([]rune(src), &OwnerInfo{StateID: 1}); err == nil {
+			t.Errorf(`%s must not be compiled`, src)
+		}
+	}
+}
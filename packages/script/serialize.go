@@ -0,0 +1,1015 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package script
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+
+	"github.com/AplaProject/go-apla/packages/consts"
+	"github.com/AplaProject/go-apla/packages/crypto"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// The binary format of the compiled Block tree is described in this file. The tree is written
+// as a flat list of blocks in pre-order, so every pointer between blocks becomes an index.
+// Objects of the blocks are written before the byte-code, thus the byte-code can refer to the
+// objects of any block. The objects which are not a part of the tree (extended functions,
+// contracts and functions compiled earlier) are written by their names and are resolved
+// in the virtual machine during decoding.
+//
+//  magic [4]byte | version uint16 | extern byte | blocks | code of blocks | crc64 uint64
+
+const (
+	// BytecodeVersion is the version of the binary format of compiled blocks. It must be
+	// increased every time when the layout of Block, ByteCode or the compiler is changed.
+	BytecodeVersion = 6
+
+	bytecodeMagic = `AVMB`
+)
+
+const (
+	// Tags of the values in the byte-code
+	valNil = iota
+	valInt
+	valInt64
+	valUint16
+	valUint32
+	valFloat64
+	valBool
+	valString
+	valBlock
+	valObj
+	valVar
+	valVars
+	valFuncName
+	valIndex
+	valType
+)
+
+const (
+	// Tags of Block.Info
+	infoNil = iota
+	infoState
+	infoContract
+	infoFunc
+)
+
+const (
+	// Kinds of references to ObjInfo
+	refNil = iota
+	refLocal
+	refExternal
+	refInline
+)
+
+// loopTypeName is the name of the type of for loop variables in byte-code
+const loopTypeName = `interface`
+
+var typeNames = make(map[reflect.Type]string)
+
+func init() {
+	for name, itype := range types {
+		typeNames[itype] = name
+	}
+	typeNames[loopType] = loopTypeName
+}
+
+type objRef struct {
+	block int
+	name  string
+}
+
+type blockEncoder struct {
+	buf      bytes.Buffer
+	blocks   map[*Block]int
+	list     []*Block
+	objects  map[*ObjInfo]objRef
+	external map[*ObjInfo]string
+}
+
+func (enc *blockEncoder) uint(v uint64) {
+	var tmp [binary.MaxVarintLen64]byte
+	enc.buf.Write(tmp[:binary.PutUvarint(tmp[:], v)])
+}
+
+func (enc *blockEncoder) int(v int64) {
+	var tmp [binary.MaxVarintLen64]byte
+	enc.buf.Write(tmp[:binary.PutVarint(tmp[:], v)])
+}
+
+func (enc *blockEncoder) bool(v bool) {
+	if v {
+		enc.buf.WriteByte(1)
+	} else {
+		enc.buf.WriteByte(0)
+	}
+}
+
+func (enc *blockEncoder) string(v string) {
+	enc.uint(uint64(len(v)))
+	enc.buf.WriteString(v)
+}
+
+func usedKeys(list map[string]bool) []string {
+	keys := make([]string, 0, len(list))
+	for key := range list {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (enc *blockEncoder) itype(v reflect.Type) error {
+	if v == nil {
+		enc.string(``)
+		return nil
+	}
+	if name := RecordName(v); len(name) > 0 {
+		// the record type is written with its fields
+		enc.string(name)
+		stype := v.Elem()
+		enc.uint(uint64(stype.NumField()))
+		for i := 0; i < stype.NumField(); i++ {
+			enc.string(stype.Field(i).Tag.Get(`json`))
+			if err := enc.itype(stype.Field(i).Type); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	name, ok := typeNames[v]
+	if !ok {
+		return fmt.Errorf(eBytecodeType, v.String())
+	}
+	enc.string(name)
+	return nil
+}
+
+func (enc *blockEncoder) types(list []reflect.Type) error {
+	enc.uint(uint64(len(list)))
+	for _, item := range list {
+		if err := enc.itype(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (enc *blockEncoder) blockRef(block *Block) error {
+	if block == nil {
+		enc.uint(0)
+		return nil
+	}
+	ind, ok := enc.blocks[block]
+	if !ok {
+		return errBytecodeBlock
+	}
+	enc.uint(uint64(ind) + 1)
+	return nil
+}
+
+func (enc *blockEncoder) objRef(obj *ObjInfo) error {
+	if obj == nil {
+		enc.buf.WriteByte(refNil)
+		return nil
+	}
+	if ref, ok := enc.objects[obj]; ok {
+		enc.buf.WriteByte(refLocal)
+		enc.uint(uint64(ref.block))
+		enc.string(ref.name)
+		return nil
+	}
+	if name, ok := enc.external[obj]; ok {
+		enc.buf.WriteByte(refExternal)
+		enc.string(name)
+		enc.int(int64(obj.Type))
+		return nil
+	}
+	if obj.Type == ObjExtFunc {
+		return fmt.Errorf(eBytecodeObject, obj.Value.(ExtFuncInfo).Name)
+	}
+	enc.buf.WriteByte(refInline)
+	enc.int(int64(obj.Type))
+	return enc.value(obj.Value)
+}
+
+func (enc *blockEncoder) varInfo(v *VarInfo) error {
+	if err := enc.objRef(v.Obj); err != nil {
+		return err
+	}
+	return enc.blockRef(v.Owner)
+}
+
+func (enc *blockEncoder) value(v interface{}) error {
+	switch val := v.(type) {
+	case nil:
+		enc.buf.WriteByte(valNil)
+	case int:
+		enc.buf.WriteByte(valInt)
+		enc.int(int64(val))
+	case int64:
+		enc.buf.WriteByte(valInt64)
+		enc.int(val)
+	case uint16:
+		enc.buf.WriteByte(valUint16)
+		enc.uint(uint64(val))
+	case uint32:
+		enc.buf.WriteByte(valUint32)
+		enc.uint(uint64(val))
+	case float64:
+		enc.buf.WriteByte(valFloat64)
+		enc.uint(math.Float64bits(val))
+	case bool:
+		enc.buf.WriteByte(valBool)
+		enc.bool(val)
+	case string:
+		enc.buf.WriteByte(valString)
+		enc.string(val)
+	case *Block:
+		enc.buf.WriteByte(valBlock)
+		return enc.blockRef(val)
+	case *ObjInfo:
+		enc.buf.WriteByte(valObj)
+		return enc.objRef(val)
+	case *VarInfo:
+		enc.buf.WriteByte(valVar)
+		return enc.varInfo(val)
+	case []*VarInfo:
+		enc.buf.WriteByte(valVars)
+		enc.uint(uint64(len(val)))
+		for _, item := range val {
+			if err := enc.varInfo(item); err != nil {
+				return err
+			}
+		}
+	case FuncNameCmd:
+		enc.buf.WriteByte(valFuncName)
+		enc.string(val.Name)
+		enc.int(int64(val.Count))
+	case *IndexInfo:
+		enc.buf.WriteByte(valIndex)
+		enc.int(int64(val.VarOffset))
+		enc.string(val.Extend)
+		return enc.blockRef(val.Owner)
+	case reflect.Type:
+		enc.buf.WriteByte(valType)
+		return enc.itype(val)
+	default:
+		return fmt.Errorf(eBytecodeValue, reflect.TypeOf(v).String())
+	}
+	return nil
+}
+
+func (enc *blockEncoder) contractInfo(info *ContractInfo) error {
+	enc.uint(uint64(info.ID))
+	enc.string(info.Name)
+	enc.bool(info.Owner != nil)
+	used := usedKeys(info.Used)
+	enc.bool(info.Used != nil)
+	enc.uint(uint64(len(used)))
+	for _, key := range used {
+		enc.string(key)
+		enc.bool(info.Used[key])
+	}
+	enc.bool(info.Tx != nil)
+	if info.Tx != nil {
+		enc.uint(uint64(len(*info.Tx)))
+		for _, field := range *info.Tx {
+			enc.string(field.Name)
+			if err := enc.itype(field.Type); err != nil {
+				return err
+			}
+			enc.string(field.Tags)
+		}
+	}
+	enc.bool(info.Settings != nil)
+	if info.Settings != nil {
+		keys := make([]string, 0, len(info.Settings))
+		for key := range info.Settings {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		enc.uint(uint64(len(keys)))
+		for _, key := range keys {
+			enc.string(key)
+			if err := enc.value(info.Settings[key]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (enc *blockEncoder) funcInfo(info *FuncInfo) error {
+	if err := enc.types(info.Params); err != nil {
+		return err
+	}
+	if err := enc.types(info.Results); err != nil {
+		return err
+	}
+	enc.bool(info.Names != nil)
+	if info.Names != nil {
+		keys := make([]string, 0, len(*info.Names))
+		for key := range *info.Names {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		enc.uint(uint64(len(keys)))
+		for _, key := range keys {
+			name := (*info.Names)[key]
+			enc.string(key)
+			if err := enc.types(name.Params); err != nil {
+				return err
+			}
+			enc.uint(uint64(len(name.Offset)))
+			for _, off := range name.Offset {
+				enc.int(int64(off))
+			}
+			enc.bool(name.Variadic)
+		}
+	}
+	enc.bool(info.Variadic)
+	enc.uint(uint64(info.ID))
+	return nil
+}
+
+func (enc *blockEncoder) collect(block *Block) {
+	enc.blocks[block] = len(enc.list)
+	enc.list = append(enc.list, block)
+	for _, child := range block.Children {
+		enc.collect(child)
+	}
+}
+
+func (enc *blockEncoder) block(block *Block) error {
+	enc.int(int64(block.Type))
+	switch info := block.Info.(type) {
+	case nil:
+		enc.buf.WriteByte(infoNil)
+	case uint32:
+		enc.buf.WriteByte(infoState)
+		enc.uint(uint64(info))
+	case *ContractInfo:
+		enc.buf.WriteByte(infoContract)
+		if err := enc.contractInfo(info); err != nil {
+			return err
+		}
+	case *FuncInfo:
+		enc.buf.WriteByte(infoFunc)
+		if err := enc.funcInfo(info); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf(eBytecodeValue, reflect.TypeOf(block.Info).String())
+	}
+	enc.bool(block.Owner != nil)
+	if err := enc.types(block.Vars); err != nil {
+		return err
+	}
+	enc.bool(block.Objects != nil)
+	keys := make([]string, 0, len(block.Objects))
+	for key := range block.Objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	enc.uint(uint64(len(keys)))
+	for _, key := range keys {
+		obj := block.Objects[key]
+		enc.string(key)
+		enc.int(int64(obj.Type))
+		if err := enc.value(obj.Value); err != nil {
+			return err
+		}
+	}
+	enc.uint(uint64(len(block.Children)))
+	for _, child := range block.Children {
+		if err := enc.blockRef(child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (enc *blockEncoder) code(block *Block) error {
+	enc.uint(uint64(len(block.Code)))
+	for _, cmd := range block.Code {
+		enc.uint(uint64(cmd.Cmd))
+		enc.uint(uint64(cmd.Line))
+		enc.uint(uint64(cmd.Column))
+		if err := enc.value(cmd.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EncodeBlock serializes the compiled Block which has been returned by CompileBlock.
+// It must be called before FlushBlock because FlushBlock modifies the tree.
+func (vm *VM) EncodeBlock(root *Block) ([]byte, error) {
+	enc := blockEncoder{
+		blocks:   make(map[*Block]int),
+		objects:  make(map[*ObjInfo]objRef),
+		external: make(map[*ObjInfo]string),
+	}
+	enc.collect(root)
+	for i, block := range enc.list {
+		for key, obj := range block.Objects {
+			enc.objects[obj] = objRef{block: i, name: key}
+		}
+	}
+	for key, obj := range vm.Objects {
+		enc.external[obj] = key
+	}
+	enc.buf.WriteString(bytecodeMagic)
+	binary.Write(&enc.buf, binary.BigEndian, uint16(BytecodeVersion))
+	enc.bool(vm.Extern)
+	enc.uint(uint64(len(enc.list)))
+	for _, block := range enc.list {
+		if err := enc.block(block); err != nil {
+			log.WithFields(log.Fields{"type": consts.MarshallingError, "error": err}).Error("encoding block")
+			return nil, err
+		}
+	}
+	for _, block := range enc.list {
+		if err := enc.code(block); err != nil {
+			log.WithFields(log.Fields{"type": consts.MarshallingError, "error": err}).Error("encoding byte-code")
+			return nil, err
+		}
+	}
+	crc, err := crypto.CalcChecksum(enc.buf.Bytes())
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.CryptoError, "error": err}).Error("calculating byte-code checksum")
+		return nil, err
+	}
+	binary.Write(&enc.buf, binary.BigEndian, crc)
+	return enc.buf.Bytes(), nil
+}
+
+type blockDecoder struct {
+	vm     *VM
+	data   *bytes.Reader
+	owner  *OwnerInfo
+	blocks []*Block
+}
+
+func (dec *blockDecoder) uint() (uint64, error) {
+	v, err := binary.ReadUvarint(dec.data)
+	if err != nil {
+		return 0, errBytecodeFormat
+	}
+	return v, nil
+}
+
+func (dec *blockDecoder) count() (int, error) {
+	v, err := dec.uint()
+	if err != nil {
+		return 0, err
+	}
+	if v > uint64(dec.data.Len()) {
+		return 0, errBytecodeFormat
+	}
+	return int(v), nil
+}
+
+func (dec *blockDecoder) int() (int64, error) {
+	v, err := binary.ReadVarint(dec.data)
+	if err != nil {
+		return 0, errBytecodeFormat
+	}
+	return v, nil
+}
+
+func (dec *blockDecoder) byte() (byte, error) {
+	v, err := dec.data.ReadByte()
+	if err != nil {
+		return 0, errBytecodeFormat
+	}
+	return v, nil
+}
+
+func (dec *blockDecoder) bool() (bool, error) {
+	v, err := dec.byte()
+	return v != 0, err
+}
+
+func (dec *blockDecoder) string() (string, error) {
+	size, err := dec.count()
+	if err != nil {
+		return ``, err
+	}
+	buf := make([]byte, size)
+	if _, err = io.ReadFull(dec.data, buf); err != nil {
+		return ``, errBytecodeFormat
+	}
+	return string(buf), nil
+}
+
+func (dec *blockDecoder) itype() (reflect.Type, error) {
+	name, err := dec.string()
+	if err != nil || len(name) == 0 {
+		return nil, err
+	}
+	if name == loopTypeName {
+		return loopType, nil
+	}
+	if name[0] == '@' {
+		count, err := dec.count()
+		if err != nil || count == 0 {
+			return nil, errBytecodeFormat
+		}
+		fields := make([]string, count)
+		ftypes := make([]reflect.Type, count)
+		for i := range fields {
+			if fields[i], err = dec.string(); err != nil {
+				return nil, err
+			}
+			if ftypes[i], err = dec.itype(); err != nil {
+				return nil, err
+			}
+			if ftypes[i] == nil {
+				return nil, errBytecodeFormat
+			}
+		}
+		return newRecordType(name, fields, ftypes), nil
+	}
+	itype, ok := types[name]
+	if !ok {
+		return nil, fmt.Errorf(eBytecodeType, name)
+	}
+	return itype, nil
+}
+
+func (dec *blockDecoder) types() ([]reflect.Type, error) {
+	count, err := dec.count()
+	if err != nil || count == 0 {
+		return nil, err
+	}
+	list := make([]reflect.Type, count)
+	for i := range list {
+		if list[i], err = dec.itype(); err != nil {
+			return nil, err
+		}
+	}
+	return list, nil
+}
+
+func (dec *blockDecoder) blockRef() (*Block, error) {
+	ind, err := dec.uint()
+	if err != nil || ind == 0 {
+		return nil, err
+	}
+	if ind > uint64(len(dec.blocks)) {
+		return nil, errBytecodeBlock
+	}
+	return dec.blocks[ind-1], nil
+}
+
+func (dec *blockDecoder) objRef() (*ObjInfo, error) {
+	kind, err := dec.byte()
+	if err != nil {
+		return nil, err
+	}
+	switch kind {
+	case refNil:
+		return nil, nil
+	case refLocal:
+		ind, err := dec.uint()
+		if err != nil {
+			return nil, err
+		}
+		name, err := dec.string()
+		if err != nil {
+			return nil, err
+		}
+		if ind >= uint64(len(dec.blocks)) {
+			return nil, errBytecodeBlock
+		}
+		obj, ok := dec.blocks[ind].Objects[name]
+		if !ok {
+			return nil, fmt.Errorf(eBytecodeObject, name)
+		}
+		return obj, nil
+	case refExternal:
+		name, err := dec.string()
+		if err != nil {
+			return nil, err
+		}
+		itype, err := dec.int()
+		if err != nil {
+			return nil, err
+		}
+		obj, ok := dec.vm.Objects[name]
+		if !ok || obj.Type != int(itype) {
+			return nil, fmt.Errorf(eBytecodeObject, name)
+		}
+		return obj, nil
+	case refInline:
+		itype, err := dec.int()
+		if err != nil {
+			return nil, err
+		}
+		value, err := dec.value()
+		if err != nil {
+			return nil, err
+		}
+		return &ObjInfo{Type: int(itype), Value: value}, nil
+	}
+	return nil, errBytecodeFormat
+}
+
+func (dec *blockDecoder) varInfo() (*VarInfo, error) {
+	obj, err := dec.objRef()
+	if err != nil {
+		return nil, err
+	}
+	owner, err := dec.blockRef()
+	if err != nil {
+		return nil, err
+	}
+	return &VarInfo{Obj: obj, Owner: owner}, nil
+}
+
+func (dec *blockDecoder) value() (interface{}, error) {
+	tag, err := dec.byte()
+	if err != nil {
+		return nil, err
+	}
+	switch tag {
+	case valNil:
+		return nil, nil
+	case valInt:
+		v, err := dec.int()
+		return int(v), err
+	case valInt64:
+		return dec.int()
+	case valUint16:
+		v, err := dec.uint()
+		return uint16(v), err
+	case valUint32:
+		v, err := dec.uint()
+		return uint32(v), err
+	case valFloat64:
+		v, err := dec.uint()
+		return math.Float64frombits(v), err
+	case valBool:
+		return dec.bool()
+	case valString:
+		return dec.string()
+	case valBlock:
+		return dec.blockRef()
+	case valObj:
+		return dec.objRef()
+	case valVar:
+		return dec.varInfo()
+	case valVars:
+		count, err := dec.count()
+		if err != nil {
+			return nil, err
+		}
+		list := make([]*VarInfo, count)
+		for i := range list {
+			if list[i], err = dec.varInfo(); err != nil {
+				return nil, err
+			}
+		}
+		return list, nil
+	case valFuncName:
+		name, err := dec.string()
+		if err != nil {
+			return nil, err
+		}
+		count, err := dec.int()
+		return FuncNameCmd{Name: name, Count: int(count)}, err
+	case valType:
+		return dec.itype()
+	case valIndex:
+		off, err := dec.int()
+		if err != nil {
+			return nil, err
+		}
+		extend, err := dec.string()
+		if err != nil {
+			return nil, err
+		}
+		owner, err := dec.blockRef()
+		return &IndexInfo{VarOffset: int(off), Owner: owner, Extend: extend}, err
+	}
+	return nil, errBytecodeFormat
+}
+
+func (dec *blockDecoder) contractInfo() (*ContractInfo, error) {
+	var (
+		info ContractInfo
+		v    uint64
+		ok   bool
+		err  error
+	)
+	if v, err = dec.uint(); err != nil {
+		return nil, err
+	}
+	info.ID = uint32(v)
+	if info.Name, err = dec.string(); err != nil {
+		return nil, err
+	}
+	if ok, err = dec.bool(); err != nil {
+		return nil, err
+	} else if ok {
+		info.Owner = dec.owner
+	}
+	if ok, err = dec.bool(); err != nil {
+		return nil, err
+	} else if ok {
+		info.Used = make(map[string]bool)
+	}
+	count, err := dec.count()
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < count; i++ {
+		key, err := dec.string()
+		if err != nil {
+			return nil, err
+		}
+		if info.Used[key], err = dec.bool(); err != nil {
+			return nil, err
+		}
+	}
+	if ok, err = dec.bool(); err != nil {
+		return nil, err
+	} else if ok {
+		count, err := dec.count()
+		if err != nil {
+			return nil, err
+		}
+		tx := make([]*FieldInfo, count)
+		for i := range tx {
+			var field FieldInfo
+			if field.Name, err = dec.string(); err != nil {
+				return nil, err
+			}
+			if field.Type, err = dec.itype(); err != nil {
+				return nil, err
+			}
+			if field.Tags, err = dec.string(); err != nil {
+				return nil, err
+			}
+			tx[i] = &field
+		}
+		info.Tx = &tx
+	}
+	if ok, err = dec.bool(); err != nil {
+		return nil, err
+	} else if ok {
+		count, err := dec.count()
+		if err != nil {
+			return nil, err
+		}
+		info.Settings = make(map[string]interface{})
+		for i := 0; i < count; i++ {
+			key, err := dec.string()
+			if err != nil {
+				return nil, err
+			}
+			if info.Settings[key], err = dec.value(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &info, nil
+}
+
+func (dec *blockDecoder) funcInfo() (*FuncInfo, error) {
+	var (
+		info FuncInfo
+		ok   bool
+		err  error
+	)
+	if info.Params, err = dec.types(); err != nil {
+		return nil, err
+	}
+	if info.Results, err = dec.types(); err != nil {
+		return nil, err
+	}
+	if ok, err = dec.bool(); err != nil {
+		return nil, err
+	} else if ok {
+		count, err := dec.count()
+		if err != nil {
+			return nil, err
+		}
+		names := make(map[string]FuncName)
+		for i := 0; i < count; i++ {
+			var name FuncName
+			key, err := dec.string()
+			if err != nil {
+				return nil, err
+			}
+			if name.Params, err = dec.types(); err != nil {
+				return nil, err
+			}
+			offcount, err := dec.count()
+			if err != nil {
+				return nil, err
+			}
+			for j := 0; j < offcount; j++ {
+				off, err := dec.int()
+				if err != nil {
+					return nil, err
+				}
+				name.Offset = append(name.Offset, int(off))
+			}
+			if name.Variadic, err = dec.bool(); err != nil {
+				return nil, err
+			}
+			names[key] = name
+		}
+		info.Names = &names
+	}
+	if info.Variadic, err = dec.bool(); err != nil {
+		return nil, err
+	}
+	id, err := dec.uint()
+	info.ID = uint32(id)
+	return &info, err
+}
+
+func (dec *blockDecoder) block(block *Block) error {
+	itype, err := dec.int()
+	if err != nil {
+		return err
+	}
+	block.Type = int(itype)
+	tag, err := dec.byte()
+	if err != nil {
+		return err
+	}
+	switch tag {
+	case infoNil:
+	case infoState:
+		state, err := dec.uint()
+		if err != nil {
+			return err
+		}
+		block.Info = uint32(state)
+	case infoContract:
+		if block.Info, err = dec.contractInfo(); err != nil {
+			return err
+		}
+	case infoFunc:
+		if block.Info, err = dec.funcInfo(); err != nil {
+			return err
+		}
+	default:
+		return errBytecodeFormat
+	}
+	if ok, err := dec.bool(); err != nil {
+		return err
+	} else if ok {
+		block.Owner = dec.owner
+	}
+	if block.Vars, err = dec.types(); err != nil {
+		return err
+	}
+	if ok, err := dec.bool(); err != nil {
+		return err
+	} else if ok {
+		block.Objects = make(map[string]*ObjInfo)
+	}
+	count, err := dec.count()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < count; i++ {
+		key, err := dec.string()
+		if err != nil {
+			return err
+		}
+		otype, err := dec.int()
+		if err != nil {
+			return err
+		}
+		value, err := dec.value()
+		if err != nil {
+			return err
+		}
+		block.Objects[key] = &ObjInfo{Type: int(otype), Value: value}
+	}
+	if count, err = dec.count(); err != nil {
+		return err
+	}
+	for i := 0; i < count; i++ {
+		child, err := dec.blockRef()
+		if err != nil {
+			return err
+		}
+		if child == nil {
+			return errBytecodeBlock
+		}
+		child.Parent = block
+		block.Children = append(block.Children, child)
+	}
+	return nil
+}
+
+func (dec *blockDecoder) code(block *Block) error {
+	count, err := dec.count()
+	if err != nil || count == 0 {
+		return err
+	}
+	block.Code = make(ByteCodes, count)
+	for i := range block.Code {
+		cmd, err := dec.uint()
+		if err != nil {
+			return err
+		}
+		line, err := dec.uint()
+		if err != nil {
+			return err
+		}
+		column, err := dec.uint()
+		if err != nil {
+			return err
+		}
+		value, err := dec.value()
+		if err != nil {
+			return err
+		}
+		block.Code[i] = &ByteCode{uint16(cmd), value, uint32(line), uint32(column)}
+	}
+	return nil
+}
+
+// DecodeBlock restores the Block which has been serialized by EncodeBlock. The objects
+// outside of the block are resolved in the virtual machine, so it must be decoded in the
+// same virtual machine and in the same order as it was compiled. The owner replaces
+// the owner of the block which has been encoded.
+func (vm *VM) DecodeBlock(data []byte, owner *OwnerInfo) (*Block, error) {
+	var version uint16
+
+	if len(data) < len(bytecodeMagic)+10 || string(data[:len(bytecodeMagic)]) != bytecodeMagic {
+		return nil, errBytecodeFormat
+	}
+	payload := data[:len(data)-8]
+	crc, err := crypto.CalcChecksum(payload)
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.CryptoError, "error": err}).Error("calculating byte-code checksum")
+		return nil, err
+	}
+	if crc != binary.BigEndian.Uint64(data[len(data)-8:]) {
+		return nil, errBytecodeChecksum
+	}
+	dec := blockDecoder{vm: vm, owner: owner,
+		data: bytes.NewReader(payload[len(bytecodeMagic):])}
+	binary.Read(dec.data, binary.BigEndian, &version)
+	if version != BytecodeVersion {
+		return nil, errBytecodeVersion
+	}
+	if extern, err := dec.bool(); err != nil {
+		return nil, err
+	} else if extern != vm.Extern {
+		return nil, errBytecodeExtern
+	}
+	count, err := dec.count()
+	if err != nil || count == 0 {
+		return nil, errBytecodeFormat
+	}
+	dec.blocks = make([]*Block, count)
+	for i := range dec.blocks {
+		dec.blocks[i] = &Block{}
+	}
+	for _, block := range dec.blocks {
+		if err = dec.block(block); err != nil {
+			return nil, err
+		}
+	}
+	for _, block := range dec.blocks {
+		if err = dec.code(block); err != nil {
+			return nil, err
+		}
+	}
+	if dec.data.Len() != 0 {
+		return nil, errBytecodeFormat
+	}
+	return dec.blocks[0], nil
+}
@@ -0,0 +1,179 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package script
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+var serializeSources = []TestVM{
+	{`contract sets {
+		settings {
+			val = 1.56
+			rate = 100000000000
+			name="Name parameter"
+		}
+		data {
+			Name string "optional"
+		}
+		action {
+			$result = Settings("@1sets","name")
+		}
+	}
+	func result() string {
+		var par map
+		return CallContract("@1sets", par) + "=" + Sprintf("%v", Settings("@1sets","val"))
+	}`, `result`, `Name parameter=1.56`},
+	{`func proc(par string) string {
+		return par + "proc"
+	}
+	func names(a int).Tail(b string, c ...) string {
+		return Sprintf("%d%s%v", a, b, c)
+	}
+	func loop string {
+		var i int
+		var my map
+		var list array
+		while true {
+			i = i + 1
+			if i == 5 {
+				continue
+			}
+			if i > 10 {
+				break
+			}
+			list[i] = i
+		}
+		my["key"] = proc("my")
+		$ext = my["key"]
+		return Sprintf("%d %s %s %d %s", i, my["key"], $ext, Len(list), names(1).Tail("b", 2, 3))
+	}`, `loop`, `11 myproc myproc 11 1b[2 3]`},
+	{`func keys string {
+		var my map
+		var ret string
+		my["b"] = 2
+		my["a"] = 1
+		for key, value in my {
+			ret = ret + Sprintf("%s%d", key, value)
+		}
+		return ret
+	}`, `keys`, `a1b2`},
+	{`type Point {
+		x int
+		y int
+	}
+	func point string {
+		var p Point
+		p["x"] = 2
+		return Sprintf("%d %d", p["x"], p["y"])
+	}`, `point`, `2 0`},
+}
+
+func serializeVM() *VM {
+	vm := NewVM()
+	vm.Extern = true
+	vm.Extend(&ExtendData{map[string]interface{}{"Sprintf": fmt.Sprintf,
+		"Len": lenArray}, nil})
+	return vm
+}
+
+func TestEncodeBlock(t *testing.T) {
+	vm := serializeVM()
+	for _, item := range serializeSources {
+		owner := &OwnerInfo{StateID: 1, Active: true, TableID: 1}
+		root, err := vm.CompileBlock([]rune(item.Input), owner)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := vm.EncodeBlock(root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		restored, err := vm.DecodeBlock(data, owner)
+		if err != nil {
+			t.Fatal(err)
+		}
+		again, err := vm.EncodeBlock(restored)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(again) != string(data) {
+			t.Errorf(`different encoding of restored block %s`, item.Func)
+		}
+		vm.FlushBlock(restored)
+		out, err := vm.Call(item.Func, nil, &map[string]interface{}{`rt_state`: uint32(1)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out[0].(string) != item.Output {
+			t.Errorf(`wrong result %s != %s`, out[0], item.Output)
+		}
+	}
+
+	root, err := vm.CompileBlock([]rune(`func test string { return "test" }`), &OwnerInfo{StateID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := vm.EncodeBlock(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[len(data)-9]++
+	if _, err = vm.DecodeBlock(data, &OwnerInfo{StateID: 1}); err != errBytecodeChecksum {
+		t.Errorf(`wrong checksum must be detected %v`, err)
+	}
+	if _, err = NewVM().DecodeBlock(data, &OwnerInfo{StateID: 1}); err == nil {
+		t.Error(`mismatched vm must be detected`)
+	}
+}
+
+func TestBlockCache(t *testing.T) {
+	dir, err := ioutil.TempDir(``, `cache`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cache := NewBlockCache(dir)
+	for i := 0; i < 2; i++ {
+		vm := serializeVM()
+		for _, item := range serializeSources {
+			owner := &OwnerInfo{StateID: 1}
+			if i > 0 {
+				if _, err = cache.Load(vm, []rune(item.Input), owner); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err = vm.CompileCached([]rune(item.Input), owner, cache); err != nil {
+				t.Fatal(err)
+			}
+			out, err := vm.Call(item.Func, nil, &map[string]interface{}{`rt_state`: uint32(1)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out[0].(string) != item.Output {
+				t.Errorf(`wrong result %s != %s`, out[0], item.Output)
+			}
+		}
+	}
+	if _, err = cache.Load(serializeVM(), []rune(serializeSources[0].Input), &OwnerInfo{StateID: 2}); !os.IsNotExist(err) {
+		t.Errorf(`entry of another ecosystem must not be found %v`, err)
+	}
+}
@@ -22,8 +22,10 @@ import (
 	"fmt"
 	"reflect"
 	"runtime/debug"
+	"sort"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 	"unsafe"
 
 	"github.com/AplaProject/go-apla/packages/consts"
@@ -74,15 +76,48 @@ var sysVars = map[string]struct{}{
 
 var ErrMemoryLimit = errors.New("Memory limit exceeded")
 
+// ErrInTry is returned by the functions which change the state of the node in memory if they are
+// called inside try block. These changes can't be rolled back so the error isn't caught.
+var ErrInTry = errors.New("The function can't be called inside try block")
+
 // VMError represents error of VM
 type VMError struct {
-	Type  string `json:"type"`
-	Error string `json:"error"`
+	Type  string   `json:"type"`
+	Error string   `json:"error"`
+	Trace []string `json:"trace,omitempty"`
+}
+
+// TraceItem is the position in the function where the error has occurred
+type TraceItem struct {
+	Contract string `json:"contract,omitempty"`
+	Func     string `json:"func"`
+	Line     uint32 `json:"line"`
+	Column   uint32 `json:"column"`
+}
+
+func (item TraceItem) String() string {
+	name := item.Func
+	if len(item.Contract) > 0 {
+		name = item.Contract + `.` + name
+	}
+	return fmt.Sprintf(`%s [Ln:%d Col:%d]`, name, item.Line, item.Column)
+}
+
+// RuntimeError is returned by Run. It contains the stack trace of the called functions
+// and contracts, the first item is the function where the error has occurred.
+type RuntimeError struct {
+	Err   error
+	Trace []TraceItem
+}
+
+func (e *RuntimeError) Error() string {
+	return e.Err.Error()
 }
 
 type blockStack struct {
 	Block  *Block
 	Offset int
+	Line   uint32 // the current line, it is used by the profiler
 }
 
 // RunTime is needed for the execution of the byte-code
@@ -98,6 +133,10 @@ type RunTime struct {
 	callDepth uint16
 	mem       int64
 	memVars   map[interface{}]int64
+	debug     *Debugger
+	profiler  *Profiler
+	errPos    *ByteCode
+	trace     []TraceItem
 }
 
 func isSysVar(name string) bool {
@@ -175,6 +214,11 @@ func (rt *RunTime) callFunc(cmd uint16, obj *ObjInfo) (err error) {
 					log.WithFields(log.Fields{"type": consts.VMError}).Error(eTypeParam)
 					return fmt.Errorf(eTypeParam, i+1)
 				}
+			default:
+				if len(RecordName(v)) > 0 && reflect.TypeOf(rt.stack[len(rt.stack)-in+i]) != v {
+					log.WithFields(log.Fields{"type": consts.VMError, "record": RecordName(v)}).Error("wrong type of record parameter")
+					return fmt.Errorf(eTypeParam, i+1)
+				}
 			}
 		}
 		if obj.Value.(*Block).Info.(*FuncInfo).Names != nil {
@@ -252,6 +296,9 @@ func (rt *RunTime) callFunc(cmd uint16, obj *ObjInfo) (err error) {
 					}
 
 					rt.cost -= cost
+					if counter, ok := (*rt.extend)["sc"].(FuelCounter); ok {
+						counter.AddFuel(finfo.Name, cost)
+					}
 					continue
 				}
 			}
@@ -329,6 +376,14 @@ func calcMem(v interface{}) (mem int64) {
 			mem += calcMem(k.Interface())
 			mem += calcMem(rv.MapIndex(k).Interface())
 		}
+	case reflect.Ptr:
+		if len(RecordName(rv.Type())) == 0 || rv.IsNil() {
+			mem = int64(unsafe.Sizeof(v))
+			break
+		}
+		for i := 0; i < rv.Elem().NumField(); i++ {
+			mem += calcMem(rv.Elem().Field(i).Interface())
+		}
 	default:
 		mem = int64(unsafe.Sizeof(v))
 	}
@@ -466,10 +521,195 @@ func SetVMError(eType string, eText interface{}) error {
 	return fmt.Errorf(string(out))
 }
 
+// SetVMErrorTrace converts the error to VMError and adds the stack trace if it is RuntimeError
+func SetVMErrorTrace(err error) error {
+	var verr VMError
+	eText := err.Error()
+	if !strings.HasPrefix(eText, `{`) || json.Unmarshal([]byte(eText), &verr) != nil {
+		verr = VMError{Type: `panic`, Error: eText}
+	}
+	if rerr, ok := err.(*RuntimeError); ok {
+		for _, item := range rerr.Trace {
+			verr.Trace = append(verr.Trace, item.String())
+		}
+	}
+	out, jerr := json.Marshal(&verr)
+	if jerr != nil {
+		log.WithFields(log.Fields{"type": consts.JSONMarshallError, "error": jerr}).Error("marshalling VMError")
+		out = []byte(`{"type": "panic", "error": "marshalling VMError"}`)
+	}
+	return errors.New(string(out))
+}
+
+// TrimVMError shortens the text of the error to size bytes. If the text is VMError then the outer
+// entries of the trace are removed first and the result is still valid JSON.
+func TrimVMError(eText string, size int) string {
+	if len(eText) <= size {
+		return eText
+	}
+	var verr VMError
+	if !strings.HasPrefix(eText, `{`) || json.Unmarshal([]byte(eText), &verr) != nil {
+		cut := size
+		for cut > 0 && !utf8.RuneStart(eText[cut]) {
+			cut--
+		}
+		return eText[:cut]
+	}
+	for {
+		out, err := json.Marshal(&verr)
+		if err != nil {
+			log.WithFields(log.Fields{"type": consts.JSONMarshallError, "error": err}).Error("marshalling VMError")
+			return `{"type": "panic", "error": "marshalling VMError"}`
+		}
+		over := len(out) - size
+		switch {
+		case over <= 0:
+			return string(out)
+		case len(verr.Trace) > 0:
+			verr.Trace = verr.Trace[:len(verr.Trace)-1]
+		case len(verr.Error) > 0:
+			// the escaped characters are longer in JSON, so the text is checked again
+			cut := len(verr.Error) - over
+			if cut < 0 {
+				cut = 0
+			}
+			for cut > 0 && !utf8.RuneStart(verr.Error[cut]) {
+				cut--
+			}
+			verr.Error = verr.Error[:cut]
+		default:
+			return string(out)
+		}
+	}
+}
+
+// runFor executes the body of for loop for every item of the array or the map. The keys of
+// the map are iterated in the sorted order. Every iteration costs the same as a command.
+func (rt *RunTime) runFor(body *Block, value interface{}) (status int, err error) {
+	var keys []reflect.Value
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Invalid:
+		return statusNormal, nil
+	case reflect.Slice, reflect.Array:
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return 0, fmt.Errorf(eForType, rv.Type().String())
+		}
+		keys = rv.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+	default:
+		rt.vm.logger.WithFields(log.Fields{"type": consts.VMError, "vm_type": rv.Type().String()}).Error("type does not support for loop")
+		return 0, fmt.Errorf(eForType, rv.Type().String())
+	}
+	count := rv.Len()
+	size := len(rt.stack)
+	for i := 0; i < count; i++ {
+		rt.cost--
+		if rt.cost <= 0 {
+			rt.vm.logger.WithFields(log.Fields{"type": consts.VMError}).Warn("paid CPU resource is over")
+			return 0, fmt.Errorf(`paid CPU resource is over`)
+		}
+		if keys == nil {
+			rt.stack = append(rt.stack, int64(i), rv.Index(i).Interface())
+		} else {
+			rt.stack = append(rt.stack, keys[i].String(), rv.MapIndex(keys[i]).Interface())
+		}
+		status, err = rt.RunCode(body)
+		if err != nil || status == statusReturn {
+			return
+		}
+		rt.stack = rt.stack[:size]
+		if status == statusBreak {
+			break
+		}
+	}
+	return statusNormal, nil
+}
+
+// runTry executes the block of try statement. If the block fails then the changes of the database
+// are rolled back to the savepoint and the error is returned as caught. The errors of the exceeded
+// limits and ErrInTry are not caught.
+func (rt *RunTime) runTry(block *Block) (status int, caught error, err error) {
+	var (
+		point Savepointer
+		id    int
+	)
+	if point, _ = (*rt.extend)["sc"].(Savepointer); point != nil {
+		if id, err = point.Savepoint(); err != nil {
+			return
+		}
+	}
+	extend := make(map[string]interface{}, len(*rt.extend))
+	for key, item := range *rt.extend {
+		extend[key] = item
+	}
+	blocks, vars, stack := len(rt.blocks), len(rt.vars), len(rt.stack)
+	if status, err = rt.RunCode(block); err == nil || rt.cost <= 0 || !isCatchable(err) {
+		if point != nil {
+			if errPoint := point.ReleaseSavepoint(id); err == nil {
+				err = errPoint
+			}
+		}
+		return
+	}
+	caught = err
+	if point != nil {
+		if err = point.RollbackSavepoint(id); err != nil {
+			return
+		}
+	}
+	for key := range *rt.extend {
+		delete(*rt.extend, key)
+	}
+	for key, item := range extend {
+		(*rt.extend)[key] = item
+	}
+	rt.blocks, rt.vars, rt.stack = rt.blocks[:blocks], rt.vars[:vars], rt.stack[:stack]
+	rt.errPos, rt.trace = nil, nil
+	return statusNormal, caught, nil
+}
+
+// isCatchable returns false if the error can't be caught by try statement
+func isCatchable(err error) bool {
+	if rerr, ok := err.(*RuntimeError); ok {
+		err = rerr.Err
+	}
+	return err != ErrMemoryLimit && err != ErrInTry
+}
+
+// errorText returns the text of the error which is assigned to the variable of catch statement
+func errorText(err error) string {
+	if rerr, ok := err.(*RuntimeError); ok {
+		err = rerr.Err
+	}
+	var verr VMError
+	eText := err.Error()
+	if strings.HasPrefix(eText, `{`) && json.Unmarshal([]byte(eText), &verr) == nil {
+		return verr.Error
+	}
+	return eText
+}
+
+// addTrace is called when RunCode returns the error. The position of the error is added to
+// the trace when the function is finished.
+func (rt *RunTime) addTrace(block *Block, cmd *ByteCode) {
+	if rt.errPos == nil {
+		rt.errPos = cmd
+	}
+	if block.Type != ObjFunc {
+		return
+	}
+	contract, fname := blockNames(block)
+	rt.trace = append(rt.trace, TraceItem{Contract: contract, Func: fname,
+		Line: rt.errPos.Line, Column: rt.errPos.Column})
+	rt.errPos = nil
+}
+
 // RunCode executes Block
 func (rt *RunTime) RunCode(block *Block) (status int, err error) {
 	top := make([]interface{}, 8)
-	rt.blocks = append(rt.blocks, &blockStack{block, len(rt.vars)})
+	rt.blocks = append(rt.blocks, &blockStack{Block: block, Offset: len(rt.vars)})
 	var namemap map[string][]interface{}
 	if block.Type == ObjFunc && block.Info.(*FuncInfo).Names != nil {
 		if rt.stack[len(rt.stack)-1] != nil {
@@ -490,6 +730,8 @@ func (rt *RunTime) RunCode(block *Block) (status int, err error) {
 				value = make(map[string]interface{})
 			} else if vpar == reflect.TypeOf([]interface{}{}) {
 				value = make([]interface{}, 0, len(rt.vars)+1)
+			} else if len(RecordName(vpar)) > 0 {
+				value = newRecord(vpar)
 			}
 		}
 		rt.addVar(value)
@@ -515,11 +757,22 @@ func (rt *RunTime) RunCode(block *Block) (status int, err error) {
 	}
 	var (
 		assign []*VarInfo
+		caught error
 		tmpInt int64
 		tmpDec decimal.Decimal
 	)
 	labels := make([]int, 0)
+	var current *ByteCode
+	defer func() {
+		if err != nil && current != nil {
+			rt.addTrace(block, current)
+		}
+	}()
 	for ci := 0; ci < len(block.Code); ci++ {
+		current = block.Code[ci]
+		if rt.profiler != nil {
+			rt.profiler.trace(rt, block, ci)
+		}
 		rt.cost--
 		if rt.cost <= 0 {
 			rt.vm.logger.WithFields(log.Fields{"type": consts.VMError}).Warn("paid CPU resource is over")
@@ -531,6 +784,11 @@ func (rt *RunTime) RunCode(block *Block) (status int, err error) {
 			return 0, ErrMemoryLimit
 		}
 
+		if rt.debug != nil {
+			if err = rt.debug.trace(rt, block, ci); err != nil {
+				return 0, err
+			}
+		}
 		cmd := block.Code[ci]
 		var bin interface{}
 		size := len(rt.stack)
@@ -571,6 +829,22 @@ func (rt *RunTime) RunCode(block *Block) (status int, err error) {
 					break
 				}
 			}
+		case cmdFor:
+			val := rt.stack[len(rt.stack)-1]
+			rt.stack = rt.stack[:len(rt.stack)-1]
+			status, err = rt.runFor(cmd.Value.(*Block), val)
+		case cmdTry:
+			status, caught, err = rt.runTry(cmd.Value.(*Block))
+		case cmdCatch:
+			if caught != nil {
+				size := len(rt.stack)
+				rt.stack = append(rt.stack, errorText(caught))
+				caught = nil
+				status, err = rt.RunCode(cmd.Value.(*Block))
+				if err == nil && status != statusReturn {
+					rt.stack = rt.stack[:size]
+				}
+			}
 		case cmdLabel:
 			labels = append(labels, ci)
 		case cmdContinue:
@@ -596,13 +870,20 @@ func (rt *RunTime) RunCode(block *Block) (status int, err error) {
 					for i = len(rt.blocks) - 1; i >= 0; i-- {
 						if item.Owner == rt.blocks[i].Block {
 							k := rt.blocks[i].Offset + item.Obj.Value.(int)
-							switch rt.blocks[i].Block.Vars[item.Obj.Value.(int)].String() {
-							case Decimal:
+							vtype := rt.blocks[i].Block.Vars[item.Obj.Value.(int)]
+							switch {
+							case vtype.String() == Decimal:
 								v, err := ValueToDecimal(rt.stack[len(rt.stack)-count+ivar])
 								if err != nil {
 									return 0, err
 								}
 								rt.setVar(k, v)
+							case len(RecordName(vtype)) > 0:
+								v, err := ToRecord(vtype, rt.stack[len(rt.stack)-count+ivar])
+								if err != nil {
+									return 0, err
+								}
+								rt.setVar(k, v)
 							default:
 								rt.setVar(k, rt.stack[len(rt.stack)-count+ivar])
 							}
@@ -651,9 +932,11 @@ func (rt *RunTime) RunCode(block *Block) (status int, err error) {
 						rt.vm.logger.WithFields(log.Fields{"type": consts.VMError}).Warning("paid CPU resource is over")
 						return 0, fmt.Errorf(`paid CPU resource is over`)
 					} else if cost == -1 {
-						rt.cost -= CostCall
-					} else {
-						rt.cost -= cost
+						cost = CostCall
+					}
+					rt.cost -= cost
+					if counter, ok := (*rt.extend)["sc"].(FuelCounter); ok {
+						counter.AddFuel(finfo.Name, cost)
 					}
 				}
 			} else {
@@ -721,6 +1004,18 @@ func (rt *RunTime) RunCode(block *Block) (status int, err error) {
 					rt.stack[size-2] = nil
 				}
 				rt.stack = rt.stack[:size-1]
+			case reflect.Ptr:
+				if len(RecordName(rv.Type())) == 0 {
+					err = fmt.Errorf(`Type %s doesn't support indexing`, rv.Type().String())
+					break
+				}
+				if reflect.TypeOf(rt.stack[size-1]).String() != `string` {
+					err = fmt.Errorf(eMapIndex, reflect.TypeOf(rt.stack[size-1]).String())
+					break
+				}
+				if rt.stack[size-2], err = getRecordField(rt.stack[size-2], rt.stack[size-1].(string)); err == nil {
+					rt.stack = rt.stack[:size-1]
+				}
 			default:
 				itype := reflect.TypeOf(rt.stack[size-2]).String()
 				rt.vm.logger.WithFields(log.Fields{"type": consts.VMError, "vm_type": itype}).Error("type does not support indexing")
@@ -779,6 +1074,14 @@ func (rt *RunTime) RunCode(block *Block) (status int, err error) {
 					slice[ind] = rt.stack[size-1].(map[string]string)
 				}
 				rt.stack = rt.stack[:size-2]
+			case len(RecordName(reflect.TypeOf(rt.stack[size-3]))) > 0:
+				if reflect.TypeOf(rt.stack[size-2]).String() != `string` {
+					err = fmt.Errorf(eMapIndex, reflect.TypeOf(rt.stack[size-2]).String())
+					break
+				}
+				if err = setRecordField(rt.stack[size-3], rt.stack[size-2].(string), rt.stack[size-1]); err == nil {
+					rt.stack = rt.stack[:size-2]
+				}
 			default:
 				rt.vm.logger.WithFields(log.Fields{"type": consts.VMError, "vm_type": itype}).Error("type does not support indexing")
 				err = fmt.Errorf(`Type %s doesn't support indexing`, itype)
@@ -1154,11 +1457,20 @@ func (rt *RunTime) Run(block *Block, params []interface{}, extend *map[string]in
 	}()
 	info := block.Info.(*FuncInfo)
 	rt.extend = extend
+	rt.errPos, rt.trace = nil, nil
+	if rt.profiler != nil {
+		defer rt.profiler.flush(rt)
+	}
 	if _, err = rt.RunCode(block); err == nil {
 		off := len(rt.stack) - len(info.Results)
 		for i := 0; i < len(info.Results); i++ {
 			ret = append(ret, rt.stack[off+i])
 		}
+	} else if rerr, ok := err.(*RuntimeError); ok {
+		// the error of the called contract already has the trace
+		err = &RuntimeError{Err: rerr.Err, Trace: append(rerr.Trace, rt.trace...)}
+	} else {
+		err = &RuntimeError{Err: err, Trace: rt.trace}
 	}
 	return
 }
@@ -25,3 +25,134 @@ func TestCalcMem(t *testing.T) {
 		assert.Equal(t, v.mem, calcMem(v.v))
 	}
 }
+
+func TestRuntimeTrace(t *testing.T) {
+	vm := NewVM()
+	err := vm.Compile([]rune(`func inner(a int) int {
+	if a > 1 {
+		error "too big"
+	}
+	return a
+}
+func outer int {
+	var b int
+	b = inner(2)
+	return b
+}`), &OwnerInfo{StateID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rt := vm.RunInit(CostDefault)
+	_, err = rt.Run(vm.getObjByNameExt(`outer`, 1).Value.(*Block), nil,
+		&map[string]interface{}{`rt_state`: uint32(1)})
+	rerr, ok := err.(*RuntimeError)
+	if !ok {
+		t.Fatalf(`wrong runtime error %v`, err)
+	}
+	assert.Equal(t, `{"type":"error","error":"too big"}`, rerr.Error())
+	assert.Equal(t, []TraceItem{{Func: `inner`, Line: 3, Column: 4}, {Func: `outer`, Line: 9, Column: 7}}, rerr.Trace)
+	assert.Equal(t, `{"type":"error","error":"too big","trace":["inner [Ln:3 Col:4]","outer [Ln:9 Col:7]"]}`,
+		SetVMErrorTrace(err).Error())
+}
+
+func TestTrimVMError(t *testing.T) {
+	eText := `{"type":"error","error":"too big","trace":["inner [Ln:3 Col:4]","outer [Ln:9 Col:7]"]}`
+	assert.Equal(t, eText, TrimVMError(eText, len(eText)))
+	assert.Equal(t, `{"type":"error","error":"too big","trace":["inner [Ln:3 Col:4]"]}`,
+		TrimVMError(eText, len(eText)-1))
+	assert.Equal(t, `{"type":"error","error":"too b"}`, TrimVMError(eText, 32))
+	assert.Equal(t, `{"type":"error","error":"\"ab"}`, TrimVMError(`{"type":"error","error":"\"abc"}`, 31))
+	assert.Equal(t, `{"type":"error","error":"ф"}`, TrimVMError(`{"type":"error","error":"фы"}`, 29))
+	assert.Equal(t, `plain`, TrimVMError(`plain text`, 5))
+	assert.Equal(t, `ab`, TrimVMError(`abфы`, 3))
+}
+
+type testSavepoints struct {
+	calls []string
+}
+
+func (p *testSavepoints) Savepoint() (int, error) {
+	p.calls = append(p.calls, `savepoint`)
+	return len(p.calls), nil
+}
+
+func (p *testSavepoints) RollbackSavepoint(id int) error {
+	p.calls = append(p.calls, `rollback`)
+	return nil
+}
+
+func (p *testSavepoints) ReleaseSavepoint(id int) error {
+	p.calls = append(p.calls, `release`)
+	return nil
+}
+
+func TestTryCatch(t *testing.T) {
+	vm := NewVM()
+	err := vm.Compile([]rune(`func div(a, b int) int {
+	return a / b
+}
+func test string {
+	var ret string
+	try {
+		ret = "a"
+		div(1, 0)
+		ret = "b"
+	} catch err {
+		ret = ret + err
+	}
+	try {
+		error "failed"
+	} catch err {
+		ret = ret + " " + err
+	}
+	try {
+		ret = ret + " ok"
+	} catch err {
+		ret = "never"
+	}
+	return ret
+}`), &OwnerInfo{StateID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	points := &testSavepoints{}
+	rt := vm.RunInit(CostDefault)
+	ret, err := rt.Run(vm.getObjByNameExt(`test`, 1).Value.(*Block), nil,
+		&map[string]interface{}{`rt_state`: uint32(1), `sc`: points})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []interface{}{`adivided by zero failed ok`}, ret)
+	assert.Equal(t, []string{`savepoint`, `rollback`, `savepoint`, `rollback`, `savepoint`, `release`}, points.calls)
+
+	for _, src := range []string{`func a { try {} }`, `func a { var b int
+	catch err {} }`} {
+		if err = vm.Compile([]rune(src), &OwnerInfo{StateID: 1}); err == nil {
+			t.Errorf(`%s must not be compiled`, src)
+		}
+	}
+}
+
+func TestTryUncatchable(t *testing.T) {
+	vm := NewVM()
+	vm.Extend(&ExtendData{Objects: map[string]interface{}{
+		"Flush": func() error { return ErrInTry },
+	}})
+	err := vm.Compile([]rune(`func test string {
+	try {
+		Flush()
+	} catch err {
+		return "caught"
+	}
+	return "ok"
+}`), &OwnerInfo{StateID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	points := &testSavepoints{}
+	rt := vm.RunInit(CostDefault)
+	_, err = rt.Run(vm.getObjByNameExt(`test`, 1).Value.(*Block), nil,
+		&map[string]interface{}{`rt_state`: uint32(1), `sc`: points})
+	assert.False(t, isCatchable(err))
+	assert.Equal(t, []string{`savepoint`, `release`}, points.calls)
+}
@@ -30,8 +30,10 @@ import (
 
 // ByteCode stores a command and an additional parameter.
 type ByteCode struct {
-	Cmd   uint16
-	Value interface{}
+	Cmd    uint16
+	Value  interface{}
+	Line   uint32 // Line of the source code
+	Column uint32 // Column of the source code
 }
 
 // ByteCodes is the slice of ByteCode items
@@ -55,6 +57,8 @@ const (
 	ObjVar
 	// ObjExtend is an extended variable. $myvar
 	ObjExtend
+	// ObjRecord is a record type. type Person {...}
+	ObjRecord
 
 	// CostCall is the cost of the function calling
 	CostCall = 50
@@ -195,6 +199,18 @@ type Stacker interface {
 	AppendStack(contract string) error
 }
 
+// FuelCounter represents interface for counting the fuel spent by extended functions
+type FuelCounter interface {
+	AddFuel(name string, fuel int64)
+}
+
+// Savepointer represents interface for rolling back the changes of the failed try block
+type Savepointer interface {
+	Savepoint() (int, error)
+	RollbackSavepoint(id int) error
+	ReleaseSavepoint(id int) error
+}
+
 // ParseContract gets a state identifier and the name of the contract from the full name like @[id]name
 func ParseContract(in string) (id uint64, name string) {
 	var err error
@@ -302,9 +318,15 @@ func ExecContract(rt *RunTime, name, txs string, params ...interface{}) (interfa
 			return nil, err
 		}
 	}
+	if rt.profiler != nil {
+		rt.profiler.push()
+		defer rt.profiler.pop()
+	}
 	for _, method := range []string{`init`, `conditions`, `action`} {
 		if block, ok := (*cblock).Objects[method]; ok && block.Type == ObjFunc {
 			rtemp := rt.vm.RunInit(rt.cost)
+			rtemp.debug = rt.debug
+			rtemp.profiler = rt.profiler
 			(*rt.extend)[`parent`] = parent
 			_, err := rtemp.Run(block.Value.(*Block), nil, rt.extend)
 			rt.cost = rtemp.cost
@@ -23,6 +23,11 @@ const (
 	eContractLoop  = `There is loop in %s contract`
 	eContractExist = `Contract %s already exists`
 	eLatin         = `Name %s must only contain latin, digit and '_', '-' characters`
+	eWhere         = `Where condition %s is not valid JSON object`
+	eWhereColumn   = `Column %s is not valid in where condition`
+	eWhereGroup    = `Operator $%s must have the list of objects`
+	eWhereOperator = `Unknown operator %s in where condition`
+	eWhereValue    = `Value of %s is not valid in where condition`
 )
 
 var (
@@ -92,6 +92,20 @@ type SmartContract struct {
 	TxHash        []byte
 	PublicKeys    [][]byte
 	DbTransaction *model.DbTransaction
+	DryRun        bool             // The contract is run without the signature and the payment
+	Debugger      *script.Debugger // The debugger of the contract, it is nil usually
+	Profiler      *script.Profiler // The profiler of the contract, it is nil usually
+	FuelStat      map[string]int64 // The fuel spent by extended functions, it is filled if it is not nil
+	RowChanges    []RowChange      // Inserted and updated rows, they are stored in DryRun mode only
+	Events        []model.Event    // The events which have been emitted by the contract
+	savepoints    []trySavepoint
+}
+
+// trySavepoint is the state of the contract which is restored if try block fails
+type trySavepoint struct {
+	stack   int
+	changes int
+	events  int
 }
 
 // AppendStack adds an element to the stack of contract call or removes the top element when name is empty
@@ -111,6 +125,79 @@ func (sc *SmartContract) AppendStack(contract string) error {
 	return nil
 }
 
+// Savepoint creates the savepoint of the database transaction for try block. The identifiers
+// are negative so they don't intersect with the savepoints of the transactions in the block.
+func (sc *SmartContract) Savepoint() (int, error) {
+	sc.savepoints = append(sc.savepoints, trySavepoint{stack: len(sc.TxContract.StackCont),
+		changes: len(sc.RowChanges), events: len(sc.Events)})
+	id := -len(sc.savepoints)
+	if sc.DbTransaction != nil {
+		if err := sc.DbTransaction.Savepoint(id); err != nil {
+			log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("creating savepoint of try block")
+			sc.savepoints = sc.savepoints[:len(sc.savepoints)-1]
+			return 0, err
+		}
+	}
+	return id, nil
+}
+
+// RollbackSavepoint rollbacks the changes of the failed try block
+func (sc *SmartContract) RollbackSavepoint(id int) error {
+	point := sc.savepoints[-id-1]
+	sc.savepoints = sc.savepoints[:-id-1]
+	sc.TxContract.StackCont = sc.TxContract.StackCont[:point.stack]
+	(*sc.TxContract.Extend)["stack"] = sc.TxContract.StackCont
+	if len(sc.RowChanges) > point.changes {
+		sc.RowChanges = sc.RowChanges[:point.changes]
+	}
+	if len(sc.Events) > point.events {
+		sc.Events = sc.Events[:point.events]
+	}
+	if sc.DbTransaction == nil {
+		return nil
+	}
+	if err := sc.DbTransaction.RollbackSavepoint(id); err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("rolling back savepoint of try block")
+		return err
+	}
+	return sc.releaseSavepoint(id)
+}
+
+// ReleaseSavepoint releases the savepoint of the successful try block
+func (sc *SmartContract) ReleaseSavepoint(id int) error {
+	sc.savepoints = sc.savepoints[:-id-1]
+	if sc.DbTransaction == nil {
+		return nil
+	}
+	return sc.releaseSavepoint(id)
+}
+
+func (sc *SmartContract) releaseSavepoint(id int) error {
+	if err := sc.DbTransaction.ReleaseSavepoint(id); err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("releasing savepoint of try block")
+		return err
+	}
+	return nil
+}
+
+// checkNotInTry returns script.ErrInTry if the contract is running inside try block. It is called
+// by the functions which change the virtual machine, the system parameters or the languages in memory,
+// because these changes aren't rolled back to the savepoint.
+func (sc *SmartContract) checkNotInTry(name string) error {
+	if len(sc.savepoints) > 0 {
+		log.WithFields(log.Fields{"type": consts.IncorrectCallingContract, "func_name": name}).Error("calling function inside try block")
+		return script.ErrInTry
+	}
+	return nil
+}
+
+// AddFuel adds the fuel spent by the extended function to the statistics
+func (sc *SmartContract) AddFuel(name string, fuel int64) {
+	if sc.FuelStat != nil {
+		sc.FuelStat[name] += fuel
+	}
+}
+
 var (
 	funcCallsDB = map[string]struct{}{
 		"DBInsert":    {},
@@ -302,6 +389,7 @@ func EmbedFuncs(vm *script.VM, vt script.VMType) {
 		f["UpdateNodesBan"] = UpdateNodesBan
 		f["DBSelectMetrics"] = DBSelectMetrics
 		f["DBCollectMetrics"] = DBCollectMetrics
+		f["EmitEvent"] = EmitEvent
 		ExtendCost(getCostP)
 		FuncCallsDB(funcCallsDBP)
 	}
@@ -954,6 +1042,9 @@ func FlushContract(sc *SmartContract, iroot interface{}, id int64, active bool)
 		log.WithFields(log.Fields{"type": consts.IncorrectCallingContract}).Error("FlushContract can be only called from NewContract or EditContract")
 		return fmt.Errorf(`FlushContract can be only called from NewContract or EditContract`)
 	}
+	if err := sc.checkNotInTry(`FlushContract`); err != nil {
+		return err
+	}
 	root := iroot.(*script.Block)
 	if id != 0 {
 		if len(root.Children) != 1 || root.Children[0].Type != script.ObjContract {
@@ -966,6 +1057,10 @@ func FlushContract(sc *SmartContract, iroot interface{}, id int64, active bool)
 			root.Children[i].Info.(*script.ContractInfo).Owner.Active = active
 		}
 	}
+	if sc.DryRun {
+		// the virtual machine can't be rolled back
+		return nil
+	}
 	VMFlushBlock(sc.VM, root)
 	return nil
 }
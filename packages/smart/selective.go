@@ -35,6 +35,15 @@ var (
 	errUpdNotExistRecord = errors.New(`Update for not existing record`)
 )
 
+// RowChange is the row which has been inserted or updated by the contract
+type RowChange struct {
+	Table  string            `json:"table"`
+	ID     string            `json:"id"`
+	Insert bool              `json:"insert"`
+	Values map[string]string `json:"values"`
+	Prev   map[string]string `json:"prev,omitempty"`
+}
+
 func (sc *SmartContract) selectiveLoggingAndUpd(fields []string, ivalues []interface{},
 	table string, whereFields, whereValues []string, generalRollback bool, exists bool) (int64, string, error) {
 	queryCoster := querycost.GetQueryCoster(querycost.FormulaQueryCosterType)
@@ -284,6 +293,21 @@ func (sc *SmartContract) selectiveLoggingAndUpd(fields []string, ivalues []inter
 	if err != nil {
 		return 0, tableID, err
 	}
+	if sc.DryRun {
+		change := RowChange{Table: table, ID: tableID, Insert: len(rollbackInfoStr) == 0,
+			Values: make(map[string]string)}
+		for i, field := range fields {
+			if converter.IsByteColumn(table, field) {
+				change.Values[field] = hex.EncodeToString([]byte(values[i]))
+			} else {
+				change.Values[field] = values[i]
+			}
+		}
+		if !change.Insert {
+			json.Unmarshal([]byte(rollbackInfoStr), &change.Prev)
+		}
+		sc.RowChanges = append(sc.RowChanges, change)
+	}
 
 	if generalRollback {
 		rollbackTx := &model.RollbackTx{
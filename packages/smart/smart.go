@@ -131,6 +131,13 @@ func vmCompile(vm *script.VM, src string, owner *script.OwnerInfo) error {
 	return vm.Compile([]rune(src), owner)
 }
 
+func vmCompileCached(vm *script.VM, src string, owner *script.OwnerInfo) error {
+	if len(conf.Config.ContractsCacheDir) == 0 {
+		return vmCompile(vm, src, owner)
+	}
+	return vm.CompileCached([]rune(src), owner, script.NewBlockCache(conf.Config.ContractsCacheDir))
+}
+
 // VMCompileBlock is compiling block
 func VMCompileBlock(vm *script.VM, src string, owner *script.OwnerInfo) (*script.Block, error) {
 	return vm.CompileBlock([]rune(src), owner)
@@ -159,6 +166,14 @@ func VMRun(vm *script.VM, block *script.Block, params []interface{}, extend *map
 		cost = ecost.(int64)
 	}
 	rt := vm.RunInit(cost)
+	if sc, ok := (*extend)[`sc`].(*SmartContract); ok {
+		if sc.Debugger != nil {
+			rt.SetDebugger(sc.Debugger)
+		}
+		if sc.Profiler != nil {
+			rt.SetProfiler(sc.Profiler)
+		}
+	}
 	ret, err = rt.Run(block, params, extend)
 	if err != nil {
 		log.WithFields(log.Fields{"type": consts.VMError, "error": err}).Error("running block in smart vm")
@@ -293,6 +308,12 @@ func SetContractWallet(sc *SmartContract, tblid, state int64, wallet int64) erro
 		log.WithFields(log.Fields{"type": consts.IncorrectCallingContract}).Error("SetContractWallet can be only called from @1EditContract")
 		return fmt.Errorf(`SetContractWallet can be only called from @1EditContract`)
 	}
+	if err := sc.checkNotInTry(`SetContractWallet`); err != nil {
+		return err
+	}
+	if sc.DryRun {
+		return nil
+	}
 	for i, item := range smartVM.Block.Children {
 		if item != nil && item.Type == script.ObjContract {
 			cinfo := item.Info.(*script.ContractInfo)
@@ -456,7 +477,7 @@ func LoadContract(transaction *model.DbTransaction, prefix string) (err error) {
 			WalletID: converter.StrToInt64(item[`wallet_id`]),
 			TokenID:  converter.StrToInt64(item[`token_id`]),
 		}
-		if err = Compile(item[`value`], &owner); err != nil {
+		if err = vmCompileCached(smartVM, item[`value`], &owner); err != nil {
 			log.WithFields(log.Fields{"type": consts.EvalError, "names": names, "error": err}).Error("Load Contract")
 		} else {
 			log.WithFields(log.Fields{"contract_name": names, "contract_id": item["id"], "contract_active": item["active"]}).Info("OK Loading Contract")
@@ -503,7 +524,7 @@ func LoadVDEContracts(transaction *model.DbTransaction, prefix string) (err erro
 			TokenID:  0,
 		}
 
-		if err = vmCompile(vm, item[`value`], &owner); err != nil {
+		if err = vmCompileCached(vm, item[`value`], &owner); err != nil {
 			log.WithFields(log.Fields{"names": names, "error": err}).Error("Load VDE Contract")
 		} else {
 			log.WithFields(log.Fields{"names": names, "contract_id": item["id"]}).Info("OK Load VDE Contract")
@@ -754,6 +775,21 @@ func (sc *SmartContract) AccessColumns(table string, columns *[]string, update b
 	return nil
 }
 
+// AccessWhereColumns checks that all columns which are used in the conditions can be read
+func (sc *SmartContract) AccessWhereColumns(table string, columns []string) error {
+	if len(columns) == 0 {
+		return nil
+	}
+	list := append([]string{}, columns...)
+	if err := sc.AccessColumns(table, &list, false); err != nil {
+		return err
+	}
+	if len(list) != len(columns) {
+		return errAccessDenied
+	}
+	return nil
+}
+
 // AccessRights checks the access right by executing the condition value
 func (sc *SmartContract) AccessRights(condition string, iscondition bool) error {
 	sp := &model.StateParameter{}
@@ -831,7 +867,9 @@ func (sc *SmartContract) CallContract(flags int) (string, error) {
 
 	retError := func(err error) (string, error) {
 		eText := err.Error()
-		if !strings.HasPrefix(eText, `{`) {
+		if _, ok := err.(*script.RuntimeError); ok {
+			err = script.SetVMErrorTrace(err)
+		} else if !strings.HasPrefix(eText, `{`) {
 			err = script.SetVMError(`panic`, eText)
 		}
 		return ``, err
@@ -840,7 +878,7 @@ func (sc *SmartContract) CallContract(flags int) (string, error) {
 	methods := []string{`init`, `conditions`, `action`, `rollback`}
 	sc.AppendStack(sc.TxContract.Name)
 	sc.VM = GetVM()
-	if (flags&CallRollback) == 0 && (flags&CallAction) != 0 {
+	if (flags&CallRollback) == 0 && (flags&CallAction) != 0 && !sc.DryRun {
 		if !sc.VDE {
 			toID = sc.BlockData.KeyID
 			fromID = sc.TxSmart.KeyID
@@ -1030,7 +1068,7 @@ func (sc *SmartContract) CallContract(flags int) (string, error) {
 		}
 	}
 
-	if (flags&CallRollback) == 0 && (flags&CallAction) != 0 && sc.TxSmart.EcosystemID > 0 && !sc.VDE && !conf.Config.IsPrivateBlockchain() {
+	if (flags&CallRollback) == 0 && (flags&CallAction) != 0 && !sc.DryRun && sc.TxSmart.EcosystemID > 0 && !sc.VDE && !conf.Config.IsPrivateBlockchain() {
 		apl := sc.TxUsedCost.Mul(fuelRate)
 
 		wltAmount, ierr := decimal.NewFromString(payWallet.Amount)
@@ -1045,7 +1083,7 @@ func (sc *SmartContract) CallContract(flags int) (string, error) {
 		commission := apl.Mul(decimal.New(syspar.SysInt64(`commission_size`), 0)).Div(decimal.New(100, 0)).Floor()
 		walletTable := model.KeyTableName(sc.TxSmart.TokenEcosystem)
 		historyTable := model.HistoryTableName(sc.TxSmart.TokenEcosystem)
-		comment := fmt.Sprintf("Commission for execution of %s contract", sc.TxContract.Name)
+		comment := fmt.Sprintf(model.CommissionComment, sc.TxContract.Name)
 		fromIDString := converter.Int64ToStr(fromID)
 
 		payCommission := func(toID string, sum decimal.Decimal) error {
@@ -45,6 +45,7 @@ var (
 		"DBUpdateSysParam": {},
 		"DBUpdateExt":      {},
 		"DBSelect":         {},
+		"EmitEvent":        {},
 	}
 
 	extendCostSysParams = map[string]string{
@@ -214,15 +215,29 @@ func UpdateSysParam(sc *SmartContract, name, value, conditions string) (int64, e
 	if err != nil {
 		return 0, err
 	}
-	err = syspar.SysUpdate(sc.DbTransaction)
-	if err != nil {
-		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("updating syspar")
+	if err = sc.updateSysParams(); err != nil {
 		return 0, err
 	}
-	sc.SysUpdate = true
 	return 0, nil
 }
 
+// updateSysParams reloads the system parameters. They are shared by the whole node, so
+// they aren't reloaded in the dry run mode
+func (sc *SmartContract) updateSysParams() error {
+	if err := sc.checkNotInTry(`UpdateSysParam`); err != nil {
+		return err
+	}
+	if sc.DryRun {
+		return nil
+	}
+	if err := syspar.SysUpdate(sc.DbTransaction); err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("updating syspar")
+		return err
+	}
+	sc.SysUpdate = true
+	return nil
+}
+
 // DBUpdateExt updates the record in the specified table. You can specify 'where' query in params and then the values for this query
 func DBUpdateExt(sc *SmartContract, tblname string, column string, value interface{},
 	params string, val ...interface{}) (qcost int64, err error) {
@@ -342,12 +357,17 @@ func CreateLanguage(sc *SmartContract, name, trans string, appID int64) (id int6
 		log.WithFields(log.Fields{"type": consts.IncorrectCallingContract}).Error("CreateLanguage can be only called from @1NewLang, @1NewLangJoint, @1Import")
 		return 0, fmt.Errorf(`CreateLanguage can be only called from @1NewLang, @1NewLangJoint, @1Import`)
 	}
+	if err := sc.checkNotInTry(`CreateLanguage`); err != nil {
+		return 0, err
+	}
 	idStr := converter.Int64ToStr(sc.TxSmart.EcosystemID)
 	if _, id, err = DBInsert(sc, `@`+idStr+"_languages", "name,res,app_id", name, trans, appID); err != nil {
 		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("inserting new language")
 		return 0, err
 	}
-	language.UpdateLang(int(sc.TxSmart.EcosystemID), int(appID), name, trans, sc.VDE)
+	if !sc.DryRun {
+		language.UpdateLang(int(sc.TxSmart.EcosystemID), int(appID), name, trans, sc.VDE)
+	}
 	return id, nil
 }
 
@@ -357,12 +377,17 @@ func EditLanguage(sc *SmartContract, id int64, name, trans string, appID int64)
 		log.WithFields(log.Fields{"type": consts.IncorrectCallingContract}).Error("EditLanguage can be only called from @1EditLang, @1EditLangJoint and @1Import")
 		return fmt.Errorf(`EditLanguage can be only called from @1EditLang, @1EditLangJoint and @1Import`)
 	}
+	if err := sc.checkNotInTry(`EditLanguage`); err != nil {
+		return err
+	}
 	idStr := converter.Int64ToStr(sc.TxSmart.EcosystemID)
 	if _, err := DBUpdate(sc, `@`+idStr+"_languages", id, "name,res,app_id", name, trans, appID); err != nil {
 		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("inserting new language")
 		return err
 	}
-	language.UpdateLang(int(sc.TxSmart.EcosystemID), int(appID), name, trans, sc.VDE)
+	if !sc.DryRun {
+		language.UpdateLang(int(sc.TxSmart.EcosystemID), int(appID), name, trans, sc.VDE)
+	}
 	return nil
 }
 
@@ -422,6 +447,9 @@ func CreateEcosystem(sc *SmartContract, wallet int64, name string) (int64, error
 		log.WithFields(log.Fields{"type": consts.IncorrectCallingContract}).Error("CreateEcosystem can be only called from @1NewEcosystem")
 		return 0, fmt.Errorf(`CreateEcosystem can be only called from @1NewEcosystem`)
 	}
+	if err := sc.checkNotInTry(`CreateEcosystem`); err != nil {
+		return 0, err
+	}
 
 	var sp model.StateParameter
 	sp.SetTablePrefix(`1`)
@@ -442,14 +470,22 @@ func CreateEcosystem(sc *SmartContract, wallet int64, name string) (int64, error
 		return 0, err
 	}
 
-	if err = model.ExecSchemaEcosystem(sc.DbTransaction, int(id), wallet, name, converter.StrToInt64(sp.Value)); err != nil {
+	var blockVersion int
+	if sc.BlockData != nil {
+		blockVersion = sc.BlockData.Version
+	}
+	if err = model.ExecSchemaEcosystem(sc.DbTransaction, int(id), wallet, name, converter.StrToInt64(sp.Value),
+		blockVersion); err != nil {
 		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("executing ecosystem schema")
 		return 0, err
 	}
 
 	idStr := converter.Int64ToStr(id)
-	if err := LoadContract(sc.DbTransaction, idStr); err != nil {
-		return 0, err
+	if !sc.DryRun {
+		// the virtual machine can't be rolled back
+		if err := LoadContract(sc.DbTransaction, idStr); err != nil {
+			return 0, err
+		}
 	}
 
 	sc.Rollback = false
@@ -576,6 +612,7 @@ func RollbackEcosystem(sc *SmartContract) error {
 		`applications`,
 		`binaries`,
 		`app_params`,
+		`events`,
 	}
 
 	if rollbackTx.TableID == "1" {
@@ -693,7 +730,12 @@ func Activate(sc *SmartContract, tblid int64, state int64) error {
 		log.WithFields(log.Fields{"type": consts.IncorrectCallingContract}).Error("ActivateContract can be only called from @1ActivateContract or @1DeactivateContract")
 		return fmt.Errorf(`ActivateContract can be only called from @1ActivateContract or @1DeactivateContract`)
 	}
-	ActivateContract(tblid, state, true)
+	if err := sc.checkNotInTry(`Activate`); err != nil {
+		return err
+	}
+	if !sc.DryRun {
+		ActivateContract(tblid, state, true)
+	}
 	return nil
 }
 
@@ -703,7 +745,12 @@ func Deactivate(sc *SmartContract, tblid int64, state int64) error {
 		log.WithFields(log.Fields{"type": consts.IncorrectCallingContract}).Error("DeactivateContract can be only called from @1ActivateContract or @1DeactivateContract")
 		return fmt.Errorf(`DeactivateContract can be only called from @1ActivateContract or @1DeactivateContract`)
 	}
-	ActivateContract(tblid, state, false)
+	if err := sc.checkNotInTry(`Deactivate`); err != nil {
+		return err
+	}
+	if !sc.DryRun {
+		ActivateContract(tblid, state, false)
+	}
 	return nil
 }
 
@@ -759,6 +806,9 @@ func RollbackContract(sc *SmartContract, name string) error {
 		log.WithFields(log.Fields{"type": consts.IncorrectCallingContract, "error": errAccessRollbackContract}).Error("Check contract access")
 		return errAccessRollbackContract
 	}
+	if err := sc.checkNotInTry(`RollbackContract`); err != nil {
+		return err
+	}
 
 	if c := VMGetContract(sc.VM, name, uint32(sc.TxSmart.EcosystemID)); c != nil {
 		id := c.Block.Info.(*script.ContractInfo).ID
@@ -870,7 +920,7 @@ func JSONEncode(input interface{}) (string, error) {
 		rv = rv.Elem()
 	}
 
-	if rv.Kind() == reflect.Struct {
+	if rv.Kind() == reflect.Struct && len(script.RecordName(reflect.TypeOf(input))) == 0 {
 		return "", fmt.Errorf("Type %T doesn't support json marshalling", input)
 	}
 
@@ -886,3 +936,42 @@ func JSONEncode(input interface{}) (string, error) {
 func Append(slice []interface{}, val interface{}) []interface{} {
 	return append(slice, val)
 }
+
+// EmitEvent stores the event of the contract in the events table of the ecosystem. The events
+// are rolled back with the block and they are sent to the subscribers when the block is committed.
+func EmitEvent(sc *SmartContract, name string, params map[string]interface{}) (int64, error) {
+	if len(name) == 0 || len(name) > 255 {
+		log.WithFields(log.Fields{"type": consts.InvalidObject, "name": name}).Error("wrong name of event")
+		return 0, fmt.Errorf(`Name of event must be from 1 to 255 characters`)
+	}
+	if params == nil {
+		params = make(map[string]interface{})
+	}
+	data, err := json.Marshal(params)
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.JSONMarshallError, "error": err}).Error("marshalling event data")
+		return 0, err
+	}
+	var contract string
+	for i := len(sc.TxContract.StackCont) - 1; i >= 0; i-- {
+		if item := sc.TxContract.StackCont[i].(string); strings.HasPrefix(item, `@`) {
+			contract = item
+			break
+		}
+	}
+	event := model.Event{Contract: contract, Name: name, Data: string(data), KeyID: sc.TxSmart.KeyID,
+		TxHash: sc.TxHash}
+	event.SetTablePrefix(sc.TxSmart.EcosystemID)
+	if sc.BlockData != nil {
+		event.BlockID = sc.BlockData.BlockID
+	}
+	qcost, id, err := sc.selectiveLoggingAndUpd([]string{`contract`, `name`, `data`, `key_id`, `block_id`,
+		`txhash`}, []interface{}{event.Contract, event.Name, event.Data, event.KeyID, event.BlockID,
+		event.TxHash}, event.TableName(), nil, nil, !sc.VDE && sc.Rollback, false)
+	if err != nil {
+		return 0, err
+	}
+	event.ID = converter.StrToInt64(id)
+	sc.Events = append(sc.Events, event)
+	return qcost, nil
+}
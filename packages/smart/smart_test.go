@@ -21,6 +21,7 @@ import (
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/AplaProject/go-apla/packages/conf/syspar"
 	"github.com/AplaProject/go-apla/packages/script"
 )
 
@@ -93,3 +94,19 @@ func TestCheckAppend(t *testing.T) {
 	_, err := Run(cfunc, nil, &map[string]interface{}{})
 	require.NoError(t, err)
 }
+
+func TestDryRunSysParams(t *testing.T) {
+	before := syspar.SysString(syspar.GapsBetweenBlocks)
+	sc := &SmartContract{DryRun: true}
+	// the database isn't connected, so the parameters can't be reloaded from it
+	require.NoError(t, sc.updateSysParams())
+	require.False(t, sc.SysUpdate)
+	require.Equal(t, before, syspar.SysString(syspar.GapsBetweenBlocks))
+}
+
+func TestCheckNotInTry(t *testing.T) {
+	sc := &SmartContract{}
+	require.NoError(t, sc.checkNotInTry(`FlushContract`))
+	sc.savepoints = append(sc.savepoints, trySavepoint{})
+	require.Equal(t, script.ErrInTry, sc.checkNotInTry(`FlushContract`))
+}
@@ -0,0 +1,238 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package smart
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/AplaProject/go-apla/packages/consts"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// The conditions of selecting rows can be specified as JSON object. The keys of the object are
+// the names of columns and the values are either the values of columns or the objects with
+// the operators. All conditions of the object must be true.
+//
+//  {"name": "John", "amount": {"$gt": 100, "$lte": 500}, "$or": [{"id": 1}, {"info->city": "Paris"}]}
+//
+// The operators are $eq, $neq, $gt, $gte, $lt, $lte, $like, $begin, $in, $nin for the columns
+// and $and, $or with the list of objects for the groups of conditions.
+
+var (
+	regWhereColumn = regexp.MustCompile(`^[a-z_][a-z0-9_]*(->[a-z0-9_]+)*$`)
+
+	whereOperators = map[string]string{
+		`$eq`:  `=`,
+		`$neq`: `!=`,
+		`$gt`:  `>`,
+		`$gte`: `>=`,
+		`$lt`:  `<`,
+		`$lte`: `<=`,
+	}
+)
+
+// IsWhereJSON returns true if the condition is specified as JSON object
+func IsWhereJSON(where string) bool {
+	return strings.HasPrefix(strings.TrimSpace(where), `{`)
+}
+
+// GetWhere converts JSON conditions to the condition of SQL query. It returns the condition
+// and the list of the columns which are used in it.
+func GetWhere(input string) (string, []string, error) {
+	var where map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewBufferString(input))
+	decoder.UseNumber()
+	if err := decoder.Decode(&where); err != nil {
+		log.WithFields(log.Fields{"type": consts.JSONUnmarshallError, "error": err, "where": input}).Error("unmarshalling where")
+		return ``, nil, fmt.Errorf(eWhere, input)
+	}
+	columns := make(map[string]bool)
+	cond, err := whereObject(where, columns)
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.InvalidObject, "error": err, "where": input}).Error("parsing where")
+		return ``, nil, err
+	}
+	list := make([]string, 0, len(columns))
+	for column := range columns {
+		list = append(list, column)
+	}
+	sort.Strings(list)
+	return cond, list, nil
+}
+
+func whereObject(where map[string]interface{}, columns map[string]bool) (string, error) {
+	keys := make([]string, 0, len(where))
+	for key := range where {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	conds := make([]string, 0, len(keys))
+	for _, key := range keys {
+		var (
+			cond string
+			err  error
+		)
+		switch strings.ToLower(key) {
+		case `$and`:
+			cond, err = whereGroup(where[key], ` and `, columns)
+		case `$or`:
+			cond, err = whereGroup(where[key], ` or `, columns)
+		default:
+			cond, err = whereColumn(key, where[key], columns)
+		}
+		if err != nil {
+			return ``, err
+		}
+		conds = append(conds, cond)
+	}
+	if len(conds) == 0 {
+		return `true`, nil
+	}
+	return strings.Join(conds, ` and `), nil
+}
+
+func whereGroup(value interface{}, oper string, columns map[string]bool) (string, error) {
+	items, ok := value.([]interface{})
+	if !ok || len(items) == 0 {
+		return ``, fmt.Errorf(eWhereGroup, strings.TrimSpace(oper))
+	}
+	conds := make([]string, 0, len(items))
+	for _, item := range items {
+		obj, ok := item.(map[string]interface{})
+		if !ok {
+			return ``, fmt.Errorf(eWhereGroup, strings.TrimSpace(oper))
+		}
+		cond, err := whereObject(obj, columns)
+		if err != nil {
+			return ``, err
+		}
+		conds = append(conds, `(`+cond+`)`)
+	}
+	return `(` + strings.Join(conds, oper) + `)`, nil
+}
+
+func whereColumn(name string, value interface{}, columns map[string]bool) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if !regWhereColumn.MatchString(name) {
+		return ``, fmt.Errorf(eWhereColumn, name)
+	}
+	path := strings.Split(name, `->`)
+	columns[path[0]] = true
+	column := `"` + path[0] + `"`
+	switch len(path) {
+	case 1:
+	case 2:
+		column += `::jsonb->>'` + path[1] + `'`
+	default:
+		column += `::jsonb#>>'{` + strings.Join(path[1:], `,`) + `}'`
+	}
+	opers, ok := value.(map[string]interface{})
+	if !ok {
+		return whereCompare(column, `$eq`, value)
+	}
+	keys := make([]string, 0, len(opers))
+	for key := range opers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	conds := make([]string, 0, len(keys))
+	for _, key := range keys {
+		cond, err := whereCompare(column, strings.ToLower(key), opers[key])
+		if err != nil {
+			return ``, err
+		}
+		conds = append(conds, cond)
+	}
+	if len(conds) == 0 {
+		return ``, fmt.Errorf(eWhereColumn, name)
+	}
+	return strings.Join(conds, ` and `), nil
+}
+
+func whereCompare(column, oper string, value interface{}) (string, error) {
+	switch oper {
+	case `$in`, `$nin`:
+		items, ok := value.([]interface{})
+		if !ok {
+			return ``, fmt.Errorf(eWhereValue, oper)
+		}
+		if len(items) == 0 {
+			if oper == `$in` {
+				return `false`, nil
+			}
+			return `true`, nil
+		}
+		list := make([]string, len(items))
+		for i, item := range items {
+			val, err := whereValue(item)
+			if err != nil {
+				return ``, err
+			}
+			list[i] = val
+		}
+		if oper == `$nin` {
+			return column + ` not in (` + strings.Join(list, `,`) + `)`, nil
+		}
+		return column + ` in (` + strings.Join(list, `,`) + `)`, nil
+	case `$like`, `$begin`:
+		val, ok := value.(string)
+		if !ok {
+			return ``, fmt.Errorf(eWhereValue, oper)
+		}
+		val = escapeSingleQuotes(val)
+		if oper == `$like` {
+			return column + ` like '%` + val + `%'`, nil
+		}
+		return column + ` like '` + val + `%'`, nil
+	}
+	sqlOper, ok := whereOperators[oper]
+	if !ok {
+		return ``, fmt.Errorf(eWhereOperator, oper)
+	}
+	if value == nil {
+		switch oper {
+		case `$eq`:
+			return column + ` is null`, nil
+		case `$neq`:
+			return column + ` is not null`, nil
+		}
+		return ``, fmt.Errorf(eWhereValue, oper)
+	}
+	val, err := whereValue(value)
+	if err != nil {
+		return ``, err
+	}
+	return column + ` ` + sqlOper + ` ` + val, nil
+}
+
+func whereValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return `'` + escapeSingleQuotes(v) + `'`, nil
+	case json.Number:
+		return `'` + escapeSingleQuotes(v.String()) + `'`, nil
+	case bool:
+		return fmt.Sprintf(`'%t'`, v), nil
+	}
+	return ``, fmt.Errorf(eWhereValue, fmt.Sprint(value))
+}
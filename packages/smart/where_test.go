@@ -0,0 +1,58 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package smart
+
+import (
+	"strings"
+	"testing"
+)
+
+type TestWhere struct {
+	Input   string
+	Output  string
+	Columns string
+}
+
+func TestGetWhere(t *testing.T) {
+	test := []TestWhere{
+		{`{"name": "John"}`, `"name" = 'John'`, `name`},
+		{`{"amount": {"$gt": 100, "$lte": 500.5}, "Name": "O'Neil"}`,
+			`"name" = 'O''Neil' and "amount" > '100' and "amount" <= '500.5'`, `amount,name`},
+		{`{"$or": [{"id": 1}, {"info->city": {"$begin": "Par"}}], "deleted": {"$neq": null}}`,
+			`(("id" = '1') or ("info"::jsonb->>'city' like 'Par%')) and "deleted" is not null`, `deleted,id,info`},
+		{`{"id": {"$in": [1, 2, 3]}, "doc->a->b": {"$nin": []}, "title": {"$like": "x"}}`,
+			`true and "id" in ('1','2','3') and "title" like '%x%'`, `doc,id,title`},
+		{`{}`, `true`, ``},
+		{`{"a;drop": 1}`, `Column a;drop is not valid in where condition`, ``},
+		{`{"id": {"$regexp": "1"}}`, `Unknown operator $regexp in where condition`, ``},
+		{`{"$or": {"id": 1}}`, `Operator $or must have the list of objects`, ``},
+		{`{"id": {"$gt": null}}`, `Value of $gt is not valid in where condition`, ``},
+		{`[1]`, `Where condition [1] is not valid JSON object`, ``},
+	}
+	for _, item := range test {
+		out, columns, err := GetWhere(item.Input)
+		if err != nil {
+			out = err.Error()
+		}
+		if out != item.Output {
+			t.Errorf("wrong where %s: %s != %s", item.Input, out, item.Output)
+		}
+		if strings.Join(columns, `,`) != item.Columns {
+			t.Errorf("wrong columns %s: %v", item.Input, columns)
+		}
+	}
+}
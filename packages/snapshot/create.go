@@ -0,0 +1,199 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/AplaProject/go-apla/packages/conf"
+	"github.com/AplaProject/go-apla/packages/consts"
+	"github.com/AplaProject/go-apla/packages/converter"
+	"github.com/AplaProject/go-apla/packages/model"
+	"github.com/AplaProject/go-apla/packages/script"
+	"github.com/AplaProject/go-apla/packages/smart"
+	"github.com/AplaProject/go-apla/packages/utils"
+	"github.com/AplaProject/go-apla/packages/utils/tx"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const newSnapshotContract = "NewSnapshot"
+
+// creating is 1 while the snapshot is being created
+var creating int32
+
+// Create starts creating the snapshot of the block which has just been committed. The state is read
+// by the separate transaction in the background, so the next blocks can be played. If commit is true
+// then the hash of the snapshot is sent to the blockchain.
+func Create(blockID int64, commit bool) {
+	logger := log.WithFields(log.Fields{"block_id": blockID})
+	if !atomic.CompareAndSwapInt32(&creating, 0, 1) {
+		logger.WithFields(log.Fields{"type": consts.DuplicateObject}).Warning("previous snapshot is still being created")
+		return
+	}
+	transaction, err := model.StartReadOnlyTransaction()
+	if err != nil {
+		atomic.StoreInt32(&creating, 0)
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("starting read only transaction")
+		return
+	}
+	go func() {
+		defer atomic.StoreInt32(&creating, 0)
+		defer transaction.Rollback()
+
+		st := time.Now()
+		m, err := create(transaction, blockID)
+		if err != nil {
+			logger.WithFields(log.Fields{"type": consts.IOError, "error": err}).Error("creating snapshot")
+			return
+		}
+		logger.WithFields(log.Fields{"chunks": len(m.Chunks), "duration": time.Since(st).String()}).Info("snapshot has been created")
+		removeOld()
+		if commit {
+			if err = commitHash(m); err != nil {
+				logger.WithFields(log.Fields{"type": consts.ContractError, "error": err}).Error("committing hash of snapshot")
+			}
+		}
+	}()
+}
+
+func create(transaction *model.DbTransaction, blockID int64) (*Manifest, error) {
+	block := &model.Block{}
+	found, err := block.Get(blockID)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("block %d has not been found", blockID)
+	}
+	privateKey, publicKey, err := utils.GetNodeKeys()
+	if err != nil {
+		return nil, err
+	}
+	nodeKey, err := hex.DecodeString(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := Dir(blockID)
+	tmpDir := dir + ".tmp"
+	if err = os.RemoveAll(tmpDir); err != nil {
+		return nil, err
+	}
+	if err = os.MkdirAll(tmpDir, 0755); err != nil {
+		return nil, err
+	}
+	w := &chunkWriter{size: ChunkSize, save: func(index int, data []byte) error {
+		return ioutil.WriteFile(filepath.Join(tmpDir, chunkFile(index)), data, 0644)
+	}}
+	if err = dump(transaction, block.Data, w); err != nil {
+		os.RemoveAll(tmpDir)
+		return nil, err
+	}
+
+	m := &Manifest{BlockID: blockID, BlockHash: block.Hash, Chunks: w.hashes}
+	if err = m.Sign(privateKey, nodeKey); err != nil {
+		os.RemoveAll(tmpDir)
+		return nil, err
+	}
+	data, err := m.Marshal()
+	if err != nil {
+		os.RemoveAll(tmpDir)
+		return nil, err
+	}
+	if err = ioutil.WriteFile(filepath.Join(tmpDir, manifestFile), data, 0644); err != nil {
+		os.RemoveAll(tmpDir)
+		return nil, err
+	}
+	if err = os.RemoveAll(dir); err != nil {
+		return nil, err
+	}
+	return m, os.Rename(tmpDir, dir)
+}
+
+// dump writes the block and all tables of the state
+func dump(transaction *model.DbTransaction, blockData []byte, w *chunkWriter) error {
+	if err := w.write(&record{Block: blockData}); err != nil {
+		return err
+	}
+	tables, err := model.GetStateTables(transaction)
+	if err != nil {
+		return err
+	}
+	for _, table := range tables {
+		schema, err := model.GetTableSchema(transaction, table)
+		if err != nil {
+			return err
+		}
+		if err = w.write(&record{Table: schema}); err != nil {
+			return err
+		}
+		err = model.ReadTableRows(transaction, schema, func(values []interface{}) error {
+			return w.write(&record{Values: values})
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return w.flush()
+}
+
+// commitHash sends the transaction of NewSnapshot signed by the node key
+func commitHash(m *Manifest) error {
+	privateKey, publicKey, err := utils.GetNodeKeys()
+	if err != nil {
+		return err
+	}
+	hash, err := m.Hash()
+	if err != nil {
+		return err
+	}
+	hexHash := hex.EncodeToString(hash)
+
+	contract := smart.VMGetContract(smart.GetVM(), newSnapshotContract, 1)
+	if contract == nil {
+		return fmt.Errorf("contract %s has not been found", newSnapshotContract)
+	}
+	info := contract.Block.Info.(*script.ContractInfo)
+
+	params := make([]byte, 0)
+	converter.EncodeLenInt64(&params, m.BlockID)
+	params = append(append(params, converter.EncodeLength(int64(len(hexHash)))...), []byte(hexHash)...)
+
+	return tx.BuildTransaction(tx.SmartContract{
+		Header: tx.Header{
+			Type:        int(info.ID),
+			Time:        time.Now().Unix(),
+			EcosystemID: 1,
+			KeyID:       conf.Config.KeyID,
+		},
+		SignedBy: smart.PubToID(publicKey),
+		Data:     params,
+	},
+		privateKey,
+		publicKey,
+		strconv.FormatInt(m.BlockID, 10),
+		hexHash,
+	)
+}
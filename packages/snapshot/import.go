@@ -0,0 +1,151 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+
+	"github.com/AplaProject/go-apla/packages/consts"
+	"github.com/AplaProject/go-apla/packages/converter"
+	"github.com/AplaProject/go-apla/packages/model"
+	"github.com/AplaProject/go-apla/packages/utils"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// importBatchSize is the number of rows which are inserted by one query
+const importBatchSize = 1000
+
+// ChunkReader returns the chunk of the snapshot
+type ChunkReader func(index int) ([]byte, error)
+
+// Import replaces the state by the snapshot. The chunks are checked by the manifest, the manifest has to be
+// checked by the caller. The block of the snapshot becomes the last block of the blockchain.
+func Import(m *Manifest, read ChunkReader) error {
+	transaction, err := model.StartTransaction()
+	if err != nil {
+		return err
+	}
+	if err = importChunks(transaction, m, read); err != nil {
+		transaction.Rollback()
+		return err
+	}
+	return transaction.Commit()
+}
+
+func importChunks(transaction *model.DbTransaction, m *Manifest, read ChunkReader) error {
+	var (
+		blockData []byte
+		schema    *model.TableSchema
+		rows      [][]interface{}
+	)
+	insert := func() error {
+		err := model.InsertSnapshotRows(transaction, schema, rows)
+		rows = rows[:0]
+		return err
+	}
+	for i := range m.Chunks {
+		data, err := read(i)
+		if err != nil {
+			return err
+		}
+		if err = m.CheckChunk(i, data); err != nil {
+			return err
+		}
+		err = readRecords(data, func(r *record) error {
+			switch {
+			case r.Block != nil:
+				if blockData != nil || schema != nil {
+					return ErrFormat
+				}
+				blockData = r.Block
+			case r.Table != nil:
+				if blockData == nil || !model.IsStateTable(r.Table.Name) {
+					return ErrFormat
+				}
+				if schema != nil {
+					if err := insert(); err != nil {
+						return err
+					}
+				}
+				schema = r.Table
+				log.WithFields(log.Fields{"table": schema.Name}).Debug("importing table of snapshot")
+				return model.CreateSnapshotTable(transaction, schema)
+			default:
+				if schema == nil {
+					return ErrFormat
+				}
+				rows = append(rows, r.Values)
+				if len(rows) >= importBatchSize {
+					return insert()
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			log.WithFields(log.Fields{"type": consts.DBError, "error": err, "chunk": i}).Error("importing chunk of snapshot")
+			return err
+		}
+	}
+	if blockData == nil {
+		return ErrFormat
+	}
+	if schema != nil {
+		if err := insert(); err != nil {
+			return err
+		}
+	}
+	return setLastBlock(transaction, m, blockData)
+}
+
+// setLastBlock writes the block of the snapshot to the blockchain and the info block
+func setLastBlock(transaction *model.DbTransaction, m *Manifest, blockData []byte) error {
+	header, err := utils.ParseBlockHeader(bytes.NewBuffer(blockData), false)
+	if err != nil {
+		return err
+	}
+	if header.BlockID != m.BlockID {
+		return ErrFormat
+	}
+	b := &model.Block{
+		ID:           header.BlockID,
+		Hash:         m.BlockHash,
+		Data:         blockData,
+		EcosystemID:  header.EcosystemID,
+		KeyID:        header.KeyID,
+		NodePosition: header.NodePosition,
+		Time:         header.Time,
+	}
+	if err = b.Create(transaction); err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("inserting block of snapshot")
+		return err
+	}
+	ib := &model.InfoBlock{
+		Hash:           m.BlockHash,
+		BlockID:        header.BlockID,
+		Time:           header.Time,
+		EcosystemID:    header.EcosystemID,
+		KeyID:          header.KeyID,
+		NodePosition:   converter.Int64ToStr(header.NodePosition),
+		CurrentVersion: consts.VERSION,
+	}
+	if err = ib.Update(transaction); err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("updating info block")
+		return err
+	}
+	return nil
+}
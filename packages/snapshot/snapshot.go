@@ -0,0 +1,280 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package snapshot contains the snapshots of the state which are used for the fast synchronization.
+//
+// The snapshot of the block N is the dump of all tables of ecosystems and the log of transactions
+// after the block N has been played. The dump is the sequence of records split into chunks. The first
+// record is the block N, then every table is the record of its schema followed by the records of rows
+// in the order of the primary key, so all nodes produce the same chunks for the same state. The
+// manifest contains the hashes of chunks and is signed by the node key. The hash of the manifest
+// is committed to the blockchain by the contract NewSnapshot. The importing node accepts the manifest
+// of the full node whose hash is committed by the block signed by the full node. The blocks before
+// the snapshot and their rollback data aren't imported, so the state can't be rolled back past the snapshot.
+package snapshot
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/AplaProject/go-apla/packages/conf"
+	"github.com/AplaProject/go-apla/packages/conf/syspar"
+	"github.com/AplaProject/go-apla/packages/consts"
+	"github.com/AplaProject/go-apla/packages/converter"
+	"github.com/AplaProject/go-apla/packages/crypto"
+	"github.com/AplaProject/go-apla/packages/model"
+
+	log "github.com/sirupsen/logrus"
+	msgpack "gopkg.in/vmihailenco/msgpack.v2"
+)
+
+const (
+	// ChunkSize is the size of chunk after which the next chunk is started
+	ChunkSize = 1 << 20
+	// KeepSnapshots is the number of the latest snapshots which are stored by the node
+	KeepSnapshots = 2
+
+	snapshotsDir = "snapshots"
+	manifestFile = "manifest"
+)
+
+var (
+	// ErrSignature is returned if the manifest has the wrong signature
+	ErrSignature = errors.New("incorrect signature of snapshot")
+	// ErrSigner is returned if the manifest isn't signed by the full node
+	ErrSigner = errors.New("snapshot isn't signed by the full node")
+	// ErrChunk is returned if the chunk doesn't match the manifest
+	ErrChunk = errors.New("chunk doesn't match snapshot")
+	// ErrFormat is returned if the chunk has the wrong records
+	ErrFormat = errors.New("incorrect format of snapshot")
+)
+
+// isNodePublicKey checks the signer of the manifest, it is replaced by tests
+var isNodePublicKey = syspar.IsNodePublicKey
+
+// Manifest describes the snapshot of the block
+type Manifest struct {
+	BlockID   int64
+	BlockHash []byte
+	Chunks    [][]byte // the hashes of chunks
+	PublicKey []byte   // the node key which has signed the snapshot
+	Signature []byte
+}
+
+// Hash returns the hash of the snapshot which is committed to the blockchain
+func (m *Manifest) Hash() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.Write(converter.DecToBin(m.BlockID, 8))
+	buf.Write(m.BlockHash)
+	for _, chunk := range m.Chunks {
+		buf.Write(chunk)
+	}
+	return crypto.Hash(buf.Bytes())
+}
+
+// Sign signs the hash of the snapshot by the node key
+func (m *Manifest) Sign(privateKey string, publicKey []byte) error {
+	hash, err := m.Hash()
+	if err != nil {
+		return err
+	}
+	m.PublicKey = publicKey
+	m.Signature, err = crypto.Sign(privateKey, hex.EncodeToString(hash))
+	return err
+}
+
+// Verify checks that the manifest is signed by the full node
+func (m *Manifest) Verify() error {
+	if !isNodePublicKey(m.PublicKey) {
+		return ErrSigner
+	}
+	hash, err := m.Hash()
+	if err != nil {
+		return err
+	}
+	ok, err := crypto.CheckSign(m.PublicKey, hex.EncodeToString(hash), m.Signature)
+	if err != nil || !ok {
+		return ErrSignature
+	}
+	return nil
+}
+
+// CheckChunk compares the chunk with its hash from the manifest
+func (m *Manifest) CheckChunk(index int, data []byte) error {
+	if index < 0 || index >= len(m.Chunks) {
+		return ErrChunk
+	}
+	hash, err := crypto.Hash(data)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(hash, m.Chunks[index]) {
+		return ErrChunk
+	}
+	return nil
+}
+
+// Marshal returns the binary manifest
+func (m *Manifest) Marshal() ([]byte, error) {
+	return msgpack.Marshal(m)
+}
+
+// UnmarshalManifest parses the binary manifest
+func UnmarshalManifest(data []byte) (*Manifest, error) {
+	m := &Manifest{}
+	if err := msgpack.Unmarshal(data, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// IsSnapshotBlock returns true if the snapshot is created after the block
+func IsSnapshotBlock(blockID int64) bool {
+	interval := syspar.GetSnapshotInterval()
+	return interval > 0 && blockID > 1 && blockID%interval == 0
+}
+
+// record is the block, the schema of the next table or the row of the table
+type record struct {
+	Block  []byte             `msgpack:",omitempty"`
+	Table  *model.TableSchema `msgpack:",omitempty"`
+	Values []interface{}      `msgpack:",omitempty"`
+}
+
+// chunkWriter splits the records into chunks
+type chunkWriter struct {
+	size   int
+	buf    bytes.Buffer
+	hashes [][]byte
+	save   func(index int, data []byte) error
+}
+
+func (w *chunkWriter) write(r *record) error {
+	data, err := msgpack.Marshal(r)
+	if err != nil {
+		return err
+	}
+	w.buf.Write(data)
+	if w.buf.Len() >= w.size {
+		return w.flush()
+	}
+	return nil
+}
+
+func (w *chunkWriter) flush() error {
+	if w.buf.Len() == 0 {
+		return nil
+	}
+	hash, err := crypto.Hash(w.buf.Bytes())
+	if err != nil {
+		return err
+	}
+	if err = w.save(len(w.hashes), w.buf.Bytes()); err != nil {
+		return err
+	}
+	w.hashes = append(w.hashes, hash)
+	w.buf.Reset()
+	return nil
+}
+
+// readRecords calls f for every record of the chunk
+func readRecords(data []byte, f func(*record) error) error {
+	dec := msgpack.NewDecoder(bytes.NewReader(data))
+	for {
+		r := &record{}
+		if err := dec.Decode(r); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return ErrFormat
+		}
+		if err := f(r); err != nil {
+			return err
+		}
+	}
+}
+
+// Dir returns the directory of the snapshot
+func Dir(blockID int64) string {
+	return filepath.Join(conf.Config.DataDir, snapshotsDir, strconv.FormatInt(blockID, 10))
+}
+
+func chunkFile(index int) string {
+	return strconv.Itoa(index)
+}
+
+// ReadManifest returns the binary manifest of the snapshot
+func ReadManifest(blockID int64) ([]byte, error) {
+	return ioutil.ReadFile(filepath.Join(Dir(blockID), manifestFile))
+}
+
+// ReadChunk returns the chunk of the snapshot
+func ReadChunk(blockID int64, index int) ([]byte, error) {
+	return ioutil.ReadFile(filepath.Join(Dir(blockID), chunkFile(index)))
+}
+
+// List returns the blocks of the created snapshots in the ascending order
+func List() ([]int64, error) {
+	files, err := ioutil.ReadDir(filepath.Join(conf.Config.DataDir, snapshotsDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	list := make([]int64, 0, len(files))
+	for _, file := range files {
+		blockID, err := strconv.ParseInt(file.Name(), 10, 64)
+		if err != nil || !file.IsDir() {
+			continue
+		}
+		if _, err = os.Stat(filepath.Join(Dir(blockID), manifestFile)); err == nil {
+			list = append(list, blockID)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i] < list[j] })
+	return list, nil
+}
+
+// Latest returns the block of the latest snapshot or zero
+func Latest() (int64, error) {
+	list, err := List()
+	if err != nil || len(list) == 0 {
+		return 0, err
+	}
+	return list[len(list)-1], nil
+}
+
+// removeOld removes the snapshots except KeepSnapshots latest ones
+func removeOld() {
+	list, err := List()
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.IOError, "error": err}).Error("getting list of snapshots")
+		return
+	}
+	for i := 0; i < len(list)-KeepSnapshots; i++ {
+		if err = os.RemoveAll(Dir(list[i])); err != nil {
+			log.WithFields(log.Fields{"type": consts.IOError, "error": err, "block_id": list[i]}).Error("removing snapshot")
+		}
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"encoding/hex"
+	"reflect"
+	"testing"
+
+	"github.com/AplaProject/go-apla/packages/conf/syspar"
+	"github.com/AplaProject/go-apla/packages/crypto"
+	"github.com/AplaProject/go-apla/packages/model"
+)
+
+func writeRecords(t *testing.T, size int, records []*record) (*chunkWriter, [][]byte) {
+	chunks := make([][]byte, 0)
+	w := &chunkWriter{size: size, save: func(index int, data []byte) error {
+		if index != len(chunks) {
+			t.Errorf("wrong index of chunk %d", index)
+		}
+		chunks = append(chunks, append([]byte{}, data...))
+		return nil
+	}}
+	for _, r := range records {
+		if err := w.write(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.flush(); err != nil {
+		t.Fatal(err)
+	}
+	return w, chunks
+}
+
+func TestChunks(t *testing.T) {
+	records := []*record{{Block: []byte{1, 2, 3}}, {Table: &model.TableSchema{
+		Name:       "1_keys",
+		Columns:    []model.TableColumn{{Name: "id", Type: "bigint", NotNull: true, Default: "'0'::bigint"}, {Name: "pub", Type: "bytea"}},
+		PrimaryKey: []string{"id"},
+	}}}
+	for i := 0; i < 100; i++ {
+		records = append(records, &record{Values: []interface{}{"1", nil}})
+	}
+	w, chunks := writeRecords(t, 256, records)
+	if len(chunks) < 2 || len(w.hashes) != len(chunks) {
+		t.Fatalf("wrong number of chunks %d", len(chunks))
+	}
+	_, again := writeRecords(t, 256, records)
+	if !reflect.DeepEqual(chunks, again) {
+		t.Error("chunks must not depend on the writing")
+	}
+
+	m := &Manifest{BlockID: 10, BlockHash: []byte{4}, Chunks: w.hashes}
+	read := make([]*record, 0, len(records))
+	for i, chunk := range chunks {
+		if err := m.CheckChunk(i, chunk); err != nil {
+			t.Fatal(err)
+		}
+		if err := readRecords(chunk, func(r *record) error {
+			read = append(read, r)
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !reflect.DeepEqual(read, records) {
+		t.Error("wrong records of chunks")
+	}
+	if err := m.CheckChunk(0, chunks[1]); err != ErrChunk {
+		t.Error("chunk must not match manifest")
+	}
+	if err := readRecords([]byte{0xc1}, func(*record) error { return nil }); err != ErrFormat {
+		t.Error("format must be wrong")
+	}
+}
+
+func TestManifest(t *testing.T) {
+	private, public, err := crypto.GenHexKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, _ := hex.DecodeString(public)
+	m := &Manifest{BlockID: 100, BlockHash: []byte{1, 2}, Chunks: [][]byte{{3}, {4}}}
+	if err = m.Sign(private, key); err != nil {
+		t.Fatal(err)
+	}
+	data, err := m.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := UnmarshalManifest(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = parsed.Verify(); err != ErrSigner {
+		t.Errorf("manifest of unknown node must be rejected %v", err)
+	}
+	isNodePublicKey = func(publicKey []byte) bool {
+		return bytes.Equal(publicKey, key)
+	}
+	defer func() {
+		isNodePublicKey = syspar.IsNodePublicKey
+	}()
+	if err = parsed.Verify(); err != nil {
+		t.Fatal(err)
+	}
+	hash, _ := m.Hash()
+	parsed.Chunks[1] = []byte{5}
+	changed, _ := parsed.Hash()
+	if bytes.Equal(hash, changed) || parsed.Verify() != ErrSignature {
+		t.Error("hash must depend on chunks")
+	}
+}
@@ -8,8 +8,9 @@ import (
 )
 
 const (
-	Count = ".count"
-	Time  = ".time"
+	Count       = ".count"
+	Time        = ".time"
+	RateLimited = ".ratelimited"
 )
 
 var Client statsd.Statter
@@ -0,0 +1,313 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package tcpserver
+
+import (
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AplaProject/go-apla/packages/conf"
+	"github.com/AplaProject/go-apla/packages/consts"
+	"github.com/AplaProject/go-apla/packages/converter"
+	"github.com/AplaProject/go-apla/packages/utils"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// The versions of the node protocol. The fields of requests which have appeared in the new
+// versions are marked by the tag `version`.
+const (
+	// ProtocolVersionLegacy is the version of nodes which don't send hello
+	ProtocolVersionLegacy = 1
+	// ProtocolVersion is the latest version, GetBodiesRequest has Count since it
+	ProtocolVersion = 2
+	// MinProtocolVersion is the oldest supported version
+	MinProtocolVersion = ProtocolVersionLegacy
+)
+
+// The features of nodes
+const (
+	// FeatureSecure means that the node accepts the secure channel
+	FeatureSecure = "secure"
+)
+
+// legacyTimeout is the time during which hello isn't sent to the node which hasn't supported it
+const legacyTimeout = 10 * time.Minute
+
+// ErrIncompatibleVersion is returned if the nodes haven't a common protocol version
+var ErrIncompatibleVersion = errors.New("incompatible protocol version")
+
+var (
+	legacyMutex sync.Mutex
+	legacyHosts = make(map[string]time.Time)
+
+	legacyRequests = []uint16{
+		RequestTypeFullNode,
+		RequestTypeNotFullNode,
+		RequestTypeStopNetwork,
+		RequestTypeConfirmation,
+		RequestTypeBlockCollection,
+		RequestTypeMaxBlock,
+	}
+	supportedRequests = append(append([]uint16{}, legacyRequests...), RequestTypeBlockHashes,
+		RequestTypeSnapshot, RequestTypeSecure, RequestTypeHello)
+)
+
+// HelloRequest is sent by the client after RequestTypeHello
+type HelloRequest struct {
+	Version      uint16 // the latest version of the client
+	MinVersion   uint16 // the oldest version of the client
+	RequestTypes []byte // 2 bytes per the request type
+	Features     []byte // the comma separated names
+}
+
+// HelloResponse contains the negotiated version, it is zero if the versions are incompatible
+type HelloResponse struct {
+	Version      uint16
+	RequestTypes []byte
+	Features     []byte
+}
+
+// Capabilities is the negotiated version, the request types and the features of the node
+type Capabilities struct {
+	Version      uint16
+	RequestTypes []uint16
+	Features     []string
+}
+
+// HasRequest returns true if the node handles the request type
+func (c *Capabilities) HasRequest(reqType uint16) bool {
+	for _, item := range c.RequestTypes {
+		if item == reqType {
+			return true
+		}
+	}
+	return false
+}
+
+// HasFeature returns true if the node supports the feature
+func (c *Capabilities) HasFeature(name string) bool {
+	for _, item := range c.Features {
+		if item == name {
+			return true
+		}
+	}
+	return false
+}
+
+// LocalCapabilities returns the capabilities of this node
+func LocalCapabilities() *Capabilities {
+	features := make([]string, 0)
+	if !conf.Config.TCPSecurityMode().IsOff() {
+		features = append(features, FeatureSecure)
+	}
+	return &Capabilities{Version: ProtocolVersion, RequestTypes: supportedRequests, Features: features}
+}
+
+// LegacyCapabilities returns the capabilities of nodes which don't support hello
+func LegacyCapabilities() *Capabilities {
+	return &Capabilities{Version: ProtocolVersionLegacy, RequestTypes: legacyRequests, Features: []string{}}
+}
+
+func encodeRequestTypes(types []uint16) []byte {
+	data := make([]byte, 0, len(types)*2)
+	for _, item := range types {
+		data = append(data, converter.DecToBin(int64(item), 2)...)
+	}
+	return data
+}
+
+func decodeRequestTypes(data []byte) []uint16 {
+	types := make([]uint16, 0, len(data)/2)
+	for i := 0; i+1 < len(data); i += 2 {
+		types = append(types, uint16(converter.BinToDec(data[i:i+2])))
+	}
+	return types
+}
+
+func decodeFeatures(data []byte) []string {
+	features := make([]string, 0)
+	for _, item := range strings.Split(string(data), `,`) {
+		if len(item) > 0 {
+			features = append(features, item)
+		}
+	}
+	return features
+}
+
+// negotiateVersion returns the latest common version or zero
+func negotiateVersion(version, minVersion uint16) uint16 {
+	if version < MinProtocolVersion || minVersion > ProtocolVersion {
+		return 0
+	}
+	if version > ProtocolVersion {
+		return ProtocolVersion
+	}
+	return version
+}
+
+// Hello answers to the hello of the client and returns the negotiated version
+func Hello(rw io.ReadWriter) (uint16, error) {
+	req := &HelloRequest{}
+	if err := ReadRequest(req, rw); err != nil {
+		return 0, err
+	}
+	local := LocalCapabilities()
+	version := negotiateVersion(req.Version, req.MinVersion)
+	resp := &HelloResponse{
+		Version:      version,
+		RequestTypes: encodeRequestTypes(local.RequestTypes),
+		Features:     []byte(strings.Join(local.Features, `,`)),
+	}
+	if err := SendRequest(resp, rw); err != nil {
+		return 0, err
+	}
+	if version == 0 {
+		log.WithFields(log.Fields{"type": consts.ProtocolError, "version": req.Version, "min_version": req.MinVersion}).Warning("incompatible protocol version")
+		return 0, ErrIncompatibleVersion
+	}
+	return version, nil
+}
+
+// sendHello sends hello with the latest version of the client
+func sendHello(rw io.ReadWriter, version uint16) (*Capabilities, error) {
+	local := LocalCapabilities()
+	if err := SendRequestType(RequestTypeHello, rw); err != nil {
+		return nil, err
+	}
+	err := SendRequest(&HelloRequest{
+		Version:      version,
+		MinVersion:   MinProtocolVersion,
+		RequestTypes: encodeRequestTypes(local.RequestTypes),
+		Features:     []byte(strings.Join(local.Features, `,`)),
+	}, rw)
+	if err != nil {
+		return nil, err
+	}
+	resp := &HelloResponse{}
+	if err = ReadRequest(resp, rw); err != nil {
+		return nil, err
+	}
+	if resp.Version < MinProtocolVersion || resp.Version > ProtocolVersion {
+		return nil, ErrIncompatibleVersion
+	}
+	return &Capabilities{
+		Version:      resp.Version,
+		RequestTypes: decodeRequestTypes(resp.RequestTypes),
+		Features:     decodeFeatures(resp.Features),
+	}, nil
+}
+
+func isLegacyHost(host string) bool {
+	legacyMutex.Lock()
+	defer legacyMutex.Unlock()
+	if until, ok := legacyHosts[host]; ok {
+		if time.Now().Before(until) {
+			return true
+		}
+		delete(legacyHosts, host)
+	}
+	return false
+}
+
+func setLegacyHost(host string) {
+	legacyMutex.Lock()
+	defer legacyMutex.Unlock()
+	legacyHosts[host] = time.Now().Add(legacyTimeout)
+}
+
+// Peer is the connection with the node and its negotiated capabilities
+type Peer struct {
+	net.Conn
+	*Capabilities
+}
+
+// SendRequest sends the request of the negotiated version
+func (p *Peer) SendRequest(request interface{}) error {
+	return SendRequestVersion(request, p.Conn, p.Version)
+}
+
+// ReadRequest reads the request of the negotiated version
+func (p *Peer) ReadRequest(request interface{}) error {
+	return ReadRequestVersion(request, p.Conn, p.Version)
+}
+
+func init() {
+	utils.PeerHello = peerHello
+}
+
+// peerHello negotiates the legacy version for the requests which are sent over utils.TCPConn
+// and returns true if the node supports the secure channel
+func peerHello(host string, conn net.Conn) (bool, error) {
+	if isLegacyHost(host) {
+		return false, nil
+	}
+	capabilities, err := sendHello(conn, ProtocolVersionLegacy)
+	if err == ErrIncompatibleVersion {
+		return false, err
+	}
+	if err != nil {
+		setLegacyHost(host)
+		return false, utils.ErrHelloUnsupported
+	}
+	return capabilities.HasFeature(FeatureSecure), nil
+}
+
+// Connect connects to the node and negotiates the protocol version. If the node doesn't support
+// hello then the legacy version is used. The secure channel is made after hello if the node supports it.
+func Connect(host string) (*Peer, error) {
+	identity := utils.SecureIdentity()
+	conn, err := utils.DialTCP(host)
+	if err != nil {
+		return nil, err
+	}
+	if isLegacyHost(host) {
+		if conn, err = utils.UpgradeConn(conn, host, identity, false); err != nil {
+			return nil, err
+		}
+		return &Peer{Conn: conn, Capabilities: LegacyCapabilities()}, nil
+	}
+	capabilities, err := sendHello(conn, ProtocolVersion)
+	if err == nil {
+		secure := capabilities.HasFeature(FeatureSecure)
+		utils.SetPeerSecure(host, secure)
+		if conn, err = utils.UpgradeConn(conn, host, identity, secure); err != nil {
+			return nil, err
+		}
+		return &Peer{Conn: conn, Capabilities: capabilities}, nil
+	}
+	conn.Close()
+	if err == ErrIncompatibleVersion {
+		log.WithFields(log.Fields{"type": consts.ProtocolError, "host": host}).Error("incompatible protocol version")
+		return nil, err
+	}
+	// the legacy node closes the connection after the unknown request type
+	log.WithFields(log.Fields{"type": consts.ProtocolError, "host": host, "error": err}).Debug("hello isn't supported, using legacy protocol")
+	setLegacyHost(host)
+	utils.SetPeerSecure(host, false)
+	if conn, err = utils.DialTCP(host); err != nil {
+		return nil, err
+	}
+	if conn, err = utils.UpgradeConn(conn, host, identity, false); err != nil {
+		return nil, err
+	}
+	return &Peer{Conn: conn, Capabilities: LegacyCapabilities()}, nil
+}
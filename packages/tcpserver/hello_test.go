@@ -0,0 +1,134 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package tcpserver
+
+import (
+	"bytes"
+	"net"
+	"testing"
+
+	"github.com/AplaProject/go-apla/packages/utils"
+)
+
+func TestNegotiateVersion(t *testing.T) {
+	for _, item := range []struct {
+		version, minVersion, result uint16
+	}{
+		{ProtocolVersion, MinProtocolVersion, ProtocolVersion},
+		{ProtocolVersion + 5, ProtocolVersion, ProtocolVersion},
+		{ProtocolVersionLegacy, ProtocolVersionLegacy, ProtocolVersionLegacy},
+		{ProtocolVersion + 5, ProtocolVersion + 1, 0},
+		{0, 0, 0},
+	} {
+		if version := negotiateVersion(item.version, item.minVersion); version != item.result {
+			t.Errorf("wrong version %d of %v", version, item)
+		}
+	}
+}
+
+func TestHello(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	ch := make(chan uint16, 1)
+	go func() {
+		reqType := &RequestType{}
+		ReadRequest(reqType, server)
+		version, _ := Hello(server)
+		ch <- version
+		server.Close()
+	}()
+	capabilities, err := sendHello(client, ProtocolVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if <-ch != ProtocolVersion || capabilities.Version != ProtocolVersion {
+		t.Errorf("wrong version %d", capabilities.Version)
+	}
+	if !capabilities.HasRequest(RequestTypeBlockCollection) || !capabilities.HasRequest(RequestTypeHello) ||
+		capabilities.HasRequest(55) {
+		t.Errorf("wrong request types %v", capabilities.RequestTypes)
+	}
+	if !capabilities.HasFeature(FeatureSecure) {
+		t.Errorf("wrong features %v", capabilities.Features)
+	}
+}
+
+func TestPeerHello(t *testing.T) {
+	client, server := net.Pipe()
+	ch := make(chan uint16, 1)
+	go func() {
+		reqType := &RequestType{}
+		ReadRequest(reqType, server)
+		version, _ := Hello(server)
+		ch <- version
+		server.Close()
+	}()
+	secure, err := peerHello(`secure:7078`, client)
+	client.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !secure {
+		t.Error("secure channel must be supported")
+	}
+	if version := <-ch; version != ProtocolVersionLegacy {
+		t.Errorf("wrong version %d", version)
+	}
+
+	// the legacy node closes the connection after the unknown request type
+	client, server = net.Pipe()
+	go func() {
+		reqType := &RequestType{}
+		ReadRequest(reqType, server)
+		server.Close()
+	}()
+	secure, err = peerHello(`legacy:7078`, client)
+	client.Close()
+	if err != utils.ErrHelloUnsupported || secure {
+		t.Errorf("wrong result %v %v", secure, err)
+	}
+	if !isLegacyHost(`legacy:7078`) {
+		t.Error("legacy host must be stored")
+	}
+	if secure, err = peerHello(`legacy:7078`, nil); err != nil || secure {
+		t.Errorf("wrong result of legacy host %v %v", secure, err)
+	}
+}
+
+func TestVersionedRequest(t *testing.T) {
+	req := &GetBodiesRequest{BlockID: 10, Count: 5}
+	for _, item := range []struct {
+		version uint16
+		size    int
+		count   uint32
+	}{{ProtocolVersionLegacy, 5, 0}, {ProtocolVersion, 9, 5}} {
+		var buf bytes.Buffer
+		if err := SendRequestVersion(req, &buf, item.version); err != nil {
+			t.Fatal(err)
+		}
+		if buf.Len() != item.size {
+			t.Errorf("wrong size %d of version %d", buf.Len(), item.version)
+		}
+		result := &GetBodiesRequest{}
+		if err := ReadRequestVersion(result, &buf, item.version); err != nil {
+			t.Fatal(err)
+		}
+		if result.BlockID != 10 || result.Count != item.count {
+			t.Errorf("wrong request %+v of version %d", result, item.version)
+		}
+	}
+}
@@ -22,6 +22,10 @@ const (
 	RequestTypeConfirmation    = 4
 	RequestTypeBlockCollection = 7
 	RequestTypeMaxBlock        = 10
+	RequestTypeBlockHashes     = 11
+	RequestTypeSnapshot        = 12
+	RequestTypeSecure          = consts.DATA_TYPE_SECURE
+	RequestTypeHello           = consts.DATA_TYPE_HELLO
 )
 
 // RequestType is type of request
@@ -41,6 +45,37 @@ type MaxBlockResponse struct {
 type GetBodiesRequest struct {
 	BlockID      uint32
 	ReverseOrder bool
+	Count        uint32 `version:"2"` // the number of blocks, BlocksPerRequest if it is zero
+}
+
+// GetHashesRequest contains the first block and the number of hashes
+type GetHashesRequest struct {
+	BlockID uint32
+	Count   uint32
+}
+
+// GetHashesResponse contains the hashes of blocks, consts.HashSize bytes per block
+type GetHashesResponse struct {
+	Hashes []byte
+}
+
+// SnapshotRequest contains the block of the snapshot, zero means the latest snapshot, and the number
+// of the chunk, zero means the manifest
+type SnapshotRequest struct {
+	BlockID uint32
+	Chunk   uint32
+}
+
+// SnapshotResponse contains the manifest or the chunk of the snapshot. Hash is the hash of the snapshot
+// which has been committed to the blockchain, Data is empty if the node hasn't the snapshot. The manifest
+// is sent with the block which has committed the hash and the hash of the previous block, so the signature
+// of the block can be checked.
+type SnapshotResponse struct {
+	BlockID        uint32
+	Hash           []byte
+	Data           []byte
+	CommitBlock    []byte
+	CommitPrevHash []byte
 }
 
 // GetBodyResponse is Data []bytes
@@ -80,13 +115,35 @@ type StopNetworkResponse struct {
 	Hash []byte
 }
 
-// ReadRequest is reading request
+// fieldVersion returns the protocol version since which the field is sent. The version is set by the tag `version`.
+func fieldVersion(field reflect.StructField) uint16 {
+	tag := field.Tag.Get("version")
+	if len(tag) == 0 {
+		return ProtocolVersionLegacy
+	}
+	version, err := strconv.ParseUint(tag, 10, 16)
+	if err != nil {
+		log.WithFields(log.Fields{"value": tag, "type": consts.ConversionError, "error": err}).Error("parsing version tag")
+		panic("bad version tag")
+	}
+	return uint16(version)
+}
+
+// ReadRequest is reading request of the legacy protocol version
 func ReadRequest(request interface{}, r io.Reader) error {
+	return ReadRequestVersion(request, r, ProtocolVersionLegacy)
+}
+
+// ReadRequestVersion is reading request of the negotiated protocol version
+func ReadRequestVersion(request interface{}, r io.Reader, version uint16) error {
 	if reflect.ValueOf(request).Elem().Kind() != reflect.Struct {
 		log.WithFields(log.Fields{"type": consts.ProtocolError}).Error("bad request type")
 		panic("bad request type")
 	}
 	for i := 0; i < reflect.ValueOf(request).Elem().NumField(); i++ {
+		if fieldVersion(reflect.TypeOf(request).Elem().Field(i)) > version {
+			continue
+		}
 		t := reflect.ValueOf(request).Elem().Field(i)
 		switch t.Kind() {
 		case reflect.Slice:
@@ -128,13 +185,21 @@ func ReadRequest(request interface{}, r io.Reader) error {
 	return nil
 }
 
-// SendRequest in sending request
+// SendRequest in sending request of the legacy protocol version
 func SendRequest(request interface{}, w io.Writer) error {
+	return SendRequestVersion(request, w, ProtocolVersionLegacy)
+}
+
+// SendRequestVersion in sending request of the negotiated protocol version
+func SendRequestVersion(request interface{}, w io.Writer, version uint16) error {
 	if reflect.ValueOf(request).Elem().Kind() != reflect.Struct {
 		log.WithFields(log.Fields{"type": consts.ProtocolError}).Error("bad request type")
 		panic("bad request type")
 	}
 	for i := 0; i < reflect.ValueOf(request).Elem().NumField(); i++ {
+		if fieldVersion(reflect.TypeOf(request).Elem().Field(i)) > version {
+			continue
+		}
 		t := reflect.ValueOf(request).Elem().Field(i)
 		switch t.Kind() {
 		case reflect.Slice:
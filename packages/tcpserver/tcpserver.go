@@ -23,8 +23,11 @@ import (
 	"time"
 
 	"github.com/AplaProject/go-apla/packages/conf"
+	"github.com/AplaProject/go-apla/packages/conf/syspar"
 	"github.com/AplaProject/go-apla/packages/consts"
+	"github.com/AplaProject/go-apla/packages/network"
 	"github.com/AplaProject/go-apla/packages/service"
+	"github.com/AplaProject/go-apla/packages/utils"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -51,7 +54,45 @@ func HandleTCPRequest(rw net.Conn) {
 		return
 	}
 
-	log.WithFields(log.Fields{"request_type": dType.Type}).Debug("tcpserver got request type")
+	var secure bool
+	if dType.Type == RequestTypeSecure {
+		if rw, err = acceptSecure(rw); err != nil {
+			return
+		}
+		secure = true
+		if err = ReadRequest(dType, rw); err != nil {
+			log.Errorf("read request type failed: %s", err)
+			return
+		}
+	}
+
+	version := uint16(ProtocolVersionLegacy)
+	if dType.Type == RequestTypeHello {
+		if version, err = Hello(rw); err != nil {
+			return
+		}
+		if err = ReadRequest(dType, rw); err != nil {
+			log.Errorf("read request type failed: %s", err)
+			return
+		}
+		// the client makes the secure channel after hello if this node supports it
+		if dType.Type == RequestTypeSecure && !secure {
+			if rw, err = acceptSecure(rw); err != nil {
+				return
+			}
+			secure = true
+			if err = ReadRequest(dType, rw); err != nil {
+				log.Errorf("read request type failed: %s", err)
+				return
+			}
+		}
+	}
+
+	log.WithFields(log.Fields{"request_type": dType.Type, "secure": secure, "version": version}).Debug("tcpserver got request type")
+	if !secure && conf.Config.TCPSecurityMode().IsRequired() && isNodeRequest(dType.Type) {
+		log.WithFields(log.Fields{"type": consts.ProtocolError, "request_type": dType.Type, "remote": rw.RemoteAddr().String()}).Warning("node request without secure channel")
+		return
+	}
 	var response interface{}
 
 	switch dType.Type {
@@ -69,7 +110,7 @@ func HandleTCPRequest(rw net.Conn) {
 
 	case RequestTypeStopNetwork:
 		req := &StopNetworkRequest{}
-		if err = ReadRequest(req, rw); err == nil {
+		if err = ReadRequestVersion(req, rw, version); err == nil {
 			err = Type3(req, rw)
 		}
 
@@ -78,20 +119,32 @@ func HandleTCPRequest(rw net.Conn) {
 			return
 		}
 		req := &ConfirmRequest{}
-		err = ReadRequest(req, rw)
+		err = ReadRequestVersion(req, rw, version)
 		if err == nil {
 			response, err = Type4(req)
 		}
 
 	case RequestTypeBlockCollection:
 		req := &GetBodiesRequest{}
-		err = ReadRequest(req, rw)
+		err = ReadRequestVersion(req, rw, version)
 		if err == nil {
 			err = Type7(req, rw)
 		}
 
 	case RequestTypeMaxBlock:
 		response, err = Type10()
+
+	case RequestTypeBlockHashes:
+		req := &GetHashesRequest{}
+		if err = ReadRequestVersion(req, rw, version); err == nil {
+			response, err = Type11(req)
+		}
+
+	case RequestTypeSnapshot:
+		req := &SnapshotRequest{}
+		if err = ReadRequestVersion(req, rw, version); err == nil {
+			response, err = Type12(req)
+		}
 	}
 
 	if err != nil || response == nil {
@@ -99,12 +152,31 @@ func HandleTCPRequest(rw net.Conn) {
 	}
 
 	log.WithFields(log.Fields{"response": response, "request_type": dType.Type}).Debug("tcpserver responded")
-	err = SendRequest(response, rw)
+	err = SendRequestVersion(response, rw, version)
 	if err != nil {
 		log.Errorf("tcpserver handle error: %s", err)
 	}
 }
 
+// isNodeRequest returns true if the request can be sent only by full nodes
+func isNodeRequest(reqType uint16) bool {
+	return reqType == RequestTypeFullNode || reqType == RequestTypeStopNetwork
+}
+
+// acceptSecure makes the secure channel with the full node
+func acceptSecure(rw net.Conn) (net.Conn, error) {
+	identity, err := utils.NodeIdentity()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := network.Accept(rw, identity, syspar.IsNodePublicKey)
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.ProtocolError, "error": err, "remote": rw.RemoteAddr().String()}).Warning("accepting secure channel")
+		return nil, err
+	}
+	return conn, nil
+}
+
 // TcpListener is listening tcp address
 func TcpListener(laddr string) error {
 
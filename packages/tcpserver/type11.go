@@ -0,0 +1,47 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package tcpserver
+
+import (
+	"github.com/AplaProject/go-apla/packages/consts"
+	"github.com/AplaProject/go-apla/packages/model"
+	"github.com/AplaProject/go-apla/packages/utils"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// HashesPerRequest is the maximum number of hashes per request
+const HashesPerRequest = 10000
+
+// Type11 sends the hashes of blocks which are used as the header chain for downloading blocks
+// from several nodes
+func Type11(request *GetHashesRequest) (*GetHashesResponse, error) {
+	count := int32(HashesPerRequest)
+	if request.Count > 0 && int32(request.Count) < count {
+		count = int32(request.Count)
+	}
+	hashes, err := model.GetBlockHashes(int64(request.BlockID), count)
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err, "block_id": request.BlockID}).Error("getting hashes of blocks")
+		return nil, utils.ErrInfo(err)
+	}
+	response := &GetHashesResponse{Hashes: make([]byte, 0, len(hashes)*consts.HashSize)}
+	for _, hash := range hashes {
+		response.Hashes = append(response.Hashes, hash...)
+	}
+	return response, nil
+}
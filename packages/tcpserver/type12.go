@@ -0,0 +1,99 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package tcpserver
+
+import (
+	"encoding/hex"
+	"os"
+
+	"github.com/AplaProject/go-apla/packages/consts"
+	"github.com/AplaProject/go-apla/packages/model"
+	"github.com/AplaProject/go-apla/packages/snapshot"
+	"github.com/AplaProject/go-apla/packages/utils"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Type12 sends the manifest or the chunk of the state snapshot and the hash of the snapshot from the blockchain
+func Type12(request *SnapshotRequest) (*SnapshotResponse, error) {
+	blockID := int64(request.BlockID)
+	if blockID == 0 {
+		latest, err := snapshot.Latest()
+		if err != nil {
+			log.WithFields(log.Fields{"type": consts.IOError, "error": err}).Error("getting latest snapshot")
+			return nil, utils.ErrInfo(err)
+		}
+		blockID = latest
+	}
+	response := &SnapshotResponse{BlockID: uint32(blockID)}
+	if blockID == 0 {
+		return response, nil
+	}
+
+	committed := &model.Snapshot{}
+	found, err := committed.GetByBlockID(blockID)
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err, "block_id": blockID}).Error("getting snapshot")
+		return nil, utils.ErrInfo(err)
+	}
+	if found {
+		if response.Hash, err = hex.DecodeString(committed.Hash); err != nil {
+			log.WithFields(log.Fields{"type": consts.ConversionError, "error": err, "block_id": blockID}).Error("decoding hash of snapshot")
+			return nil, utils.ErrInfo(err)
+		}
+	}
+
+	if request.Chunk == 0 {
+		if found {
+			if err = setCommitBlock(response, committed); err != nil {
+				return nil, utils.ErrInfo(err)
+			}
+		}
+		response.Data, err = snapshot.ReadManifest(blockID)
+	} else {
+		response.Data, err = snapshot.ReadChunk(blockID, int(request.Chunk-1))
+	}
+	if err != nil && !os.IsNotExist(err) {
+		log.WithFields(log.Fields{"type": consts.IOError, "error": err, "block_id": blockID, "chunk": request.Chunk}).Error("reading snapshot")
+		return nil, utils.ErrInfo(err)
+	}
+	return response, nil
+}
+
+// setCommitBlock adds the block which contains the transaction of the snapshot to the response
+func setCommitBlock(response *SnapshotResponse, committed *model.Snapshot) error {
+	blockID, found, err := committed.GetCommitBlockID()
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err, "snapshot": committed.ID}).Error("getting commit block of snapshot")
+		return err
+	}
+	if !found {
+		return nil
+	}
+	commit, prev := &model.Block{}, &model.Block{}
+	if found, err = commit.Get(blockID); err == nil && found {
+		found, err = prev.Get(blockID - 1)
+	}
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err, "block_id": blockID}).Error("getting commit block of snapshot")
+		return err
+	}
+	if found {
+		response.CommitBlock, response.CommitPrevHash = commit.Data, prev.Hash
+	}
+	return nil
+}
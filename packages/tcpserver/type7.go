@@ -35,10 +35,14 @@ func Type7(request *GetBodiesRequest, w net.Conn) error {
 
 	var blocks []model.Block
 	var err error
+	count := BlocksPerRequest
+	if request.Count > 0 && int32(request.Count) < count {
+		count = int32(request.Count)
+	}
 	if request.ReverseOrder {
-		blocks, err = block.GetReverseBlockchain(int64(request.BlockID), BlocksPerRequest)
+		blocks, err = block.GetReverseBlockchain(int64(request.BlockID), count)
 	} else {
-		blocks, err = block.GetBlocksFrom(int64(request.BlockID-1), "ASC", BlocksPerRequest)
+		blocks, err = block.GetBlocksFrom(int64(request.BlockID-1), "ASC", count)
 	}
 	if err != nil {
 		log.WithFields(log.Fields{"type": consts.DBError, "error": err, "block_id": request.BlockID}).Error("Error getting 1000 blocks from block_id")
@@ -159,6 +159,7 @@ func init() {
 		`Custom`:    {tplFunc{customTag, customTagFull, `custom`, `Column,Body`}, false},
 		`Vars`:      {tplFunc{tailTag, defaultTailFull, `vars`, `Prefix`}, false},
 		`Cutoff`:    {tplFunc{tailTag, defaultTailFull, `cutoff`, `Cutoff`}, false},
+		`AsOfBlock`: {tplFunc{tailTag, defaultTailFull, `asofblock`, `AsOfBlock`}, false},
 	}}
 	tails[`p`] = forTails{map[string]tailInfo{
 		`Style`: {tplFunc{tailTag, defaultTailFull, `style`, `Style`}, false},
@@ -549,9 +550,17 @@ func dbfindTag(par parFunc) string {
 		fields = `*`
 	}
 	fields = strings.ToLower(fields)
+	var whereColumns []string
 	if par.Node.Attr[`where`] != nil {
-		where = smart.PrepareWhere(` where ` +
-			converter.Escape(macro(par.Node.Attr[`where`].(string), par.Workspace.Vars)))
+		where = macro(par.Node.Attr[`where`].(string), par.Workspace.Vars)
+		if smart.IsWhereJSON(where) {
+			if where, whereColumns, err = smart.GetWhere(where); err != nil {
+				return err.Error()
+			}
+			where = ` where ` + where
+		} else {
+			where = smart.PrepareWhere(` where ` + converter.Escape(where))
+		}
 	}
 	if par.Node.Attr[`whereid`] != nil {
 		where = fmt.Sprintf(` where id='%d'`, converter.StrToInt64(macro(par.Node.Attr[`whereid`].(string), par.Workspace.Vars)))
@@ -602,6 +611,9 @@ func dbfindTag(par parFunc) string {
 	if err != nil || sc.AccessColumns(tblname, &fieldsList, false) != nil {
 		return `Access denied`
 	}
+	if err = sc.AccessWhereColumns(tblname, whereColumns); err != nil {
+		return `Access denied`
+	}
 	fields = strings.Join(fieldsList, `,`)
 
 	if fields != "*" {
@@ -644,9 +656,21 @@ func dbfindTag(par parFunc) string {
 		}
 		columnNames[i] = strings.TrimSpace(columnNames[i])
 	}
+	from := `"` + tblname + `"`
+	countFrom := tblname
+	if par.Node.Attr[`asofblock`] != nil {
+		asOfBlock := converter.StrToInt64(macro(par.Node.Attr[`asofblock`].(string), par.Workspace.Vars))
+		if asOfBlock > 0 {
+			if from, err = model.GetTableAsOfBlock(nil, tblname, asOfBlock); err != nil {
+				log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting table as of block in DBFind")
+				return err.Error()
+			}
+			countFrom = from
+		}
+	}
 	if par.Node.Attr[`countvar`] != nil {
 		var count int64
-		err = model.GetDB(nil).Table(tblname).Where(strings.Replace(where, `where`, ``, 1)).Count(&count).Error
+		err = model.GetDB(nil).Table(countFrom).Where(strings.Replace(where, `where`, ``, 1)).Count(&count).Error
 		if err != nil {
 			log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("selecting count from table in DBFind")
 		}
@@ -655,7 +679,7 @@ func dbfindTag(par parFunc) string {
 		(*par.Workspace.Vars)[par.Node.Attr[`countvar`].(string)] = countStr
 		delete(par.Node.Attr, `countvar`)
 	}
-	list, err := model.GetAll(`select `+fields+` from "`+tblname+`"`+where+order+offset, limit)
+	list, err := model.GetAll(`select `+fields+` from `+from+where+order+offset, limit)
 	if err != nil {
 		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting all from db")
 		return err.Error()
@@ -39,6 +39,7 @@ type FirstBlockTransaction struct {
 	Logger        *log.Entry
 	DbTransaction *model.DbTransaction
 	Data          interface{}
+	BlockVersion  int
 }
 
 // ErrFirstBlockHostIsEmpty host for first block is not specified
@@ -59,7 +60,7 @@ func (t *FirstBlockTransaction) Action() error {
 	logger := t.Logger
 	data := t.Data.(*consts.FirstBlock)
 	keyID := crypto.Address(data.PublicKey)
-	err := model.ExecSchemaEcosystem(nil, firstEcosystemID, keyID, ``, keyID)
+	err := model.ExecSchemaEcosystem(nil, firstEcosystemID, keyID, ``, keyID, t.BlockVersion)
 	if err != nil {
 		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("executing ecosystem schema")
 		return utils.ErrInfo(err)
@@ -6,6 +6,8 @@ import (
 	"github.com/AplaProject/go-apla/packages/consts"
 	"github.com/AplaProject/go-apla/packages/crypto"
 	"github.com/AplaProject/go-apla/packages/model"
+	"github.com/AplaProject/go-apla/packages/script"
+	"github.com/AplaProject/go-apla/packages/txstream"
 	"github.com/AplaProject/go-apla/packages/utils"
 
 	log "github.com/sirupsen/logrus"
@@ -14,12 +16,12 @@ import (
 var ErrDuplicatedTx = errors.New("Duplicated transaction")
 
 // InsertInLogTx is inserting tx in log
-func InsertInLogTx(transaction *model.DbTransaction, binaryTx []byte, time int64) error {
+func InsertInLogTx(transaction *model.DbTransaction, binaryTx []byte, time, blockID, keyID, fuel int64) error {
 	txHash, err := crypto.Hash(binaryTx)
 	if err != nil {
 		log.WithFields(log.Fields{"error": err, "type": consts.CryptoError}).Fatal("hashing binary tx")
 	}
-	ltx := &model.LogTransaction{Hash: txHash, Time: time}
+	ltx := &model.LogTransaction{Hash: txHash, Time: time, BlockID: blockID, KeyID: keyID, Fuel: fuel}
 	err = ltx.Create(transaction)
 	if err != nil {
 		log.WithFields(log.Fields{"error": err, "type": consts.DBError}).Error("insert logged transaction")
@@ -99,9 +101,7 @@ func MarkTransactionBad(dbTransaction *model.DbTransaction, hash []byte, errText
 		return nil
 	}
 	model.MarkTransactionUsed(dbTransaction, hash)
-	if len(errText) > 255 {
-		errText = errText[:255]
-	}
+	errText = script.TrimVMError(errText, consts.TxErrorSize)
 
 	// set loglevel as error because default level setups to "error"
 	log.WithFields(log.Fields{"type": consts.BadTxError, "tx_hash": string(hash), "error": errText}).Error("tx marked as bad")
@@ -126,6 +126,10 @@ func MarkTransactionBad(dbTransaction *model.DbTransaction, hash []byte, errText
 		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("deleting transaction from queue")
 		return utils.ErrInfo(err)
 	}
+	// the clients mustn't get the rejection if the transaction of the caller is rolled back
+	dbTransaction.OnCommit(func() {
+		txstream.Rejected(hash, errText)
+	})
 
 	return nil
 }
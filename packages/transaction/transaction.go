@@ -36,6 +36,7 @@ type Transaction struct {
 	TxType        int64
 	TxCost        int64 // Maximum cost of executing contract
 	TxFuel        int64
+	TxSpentFuel   int64           // The fuel spent by the contract, it is logged for the explorer
 	TxUsedCost    decimal.Decimal // Used cost of CPU resources
 	TxPtr         interface{}     // Pointer to the corresponding struct in consts/struct.go
 	TxData        map[string]interface{}
@@ -45,6 +46,7 @@ type Transaction struct {
 	tx            custom.TransactionInterface
 	DbTransaction *model.DbTransaction
 	SysUpdate     bool
+	Events        []model.Event // The events emitted by the contract
 
 	SmartContract smart.SmartContract
 }
@@ -232,6 +234,16 @@ func (t *Transaction) fillTxData(fieldInfos []*script.FieldInfo, input []byte, f
 				forv = strings.Join(slist, `,`)
 			}
 			v = list
+		default:
+			if len(script.RecordName(fitem.Type)) > 0 {
+				var s string
+				if err := converter.BinUnmarshal(&input, &s); err != nil {
+					log.WithFields(log.Fields{"error": err, "type": consts.UnmarshallingError}).Error("bin unmarshalling record")
+					return err
+				}
+				v, err = script.DecodeRecord(fitem.Type, s)
+				forv, isforv = s, true
+			}
 		}
 		if t.TxData[fitem.Name] == nil {
 			t.TxData[fitem.Name] = v
@@ -397,6 +409,11 @@ func (t *Transaction) CallContract(flags int) (resultContract string, err error)
 	}
 	resultContract, err = sc.CallContract(flags)
 	t.SysUpdate = sc.SysUpdate
+	t.TxSpentFuel = sc.TxFuel
+	t.Events = nil
+	if err == nil {
+		t.Events = sc.Events
+	}
 	return
 }
 
@@ -420,7 +437,11 @@ func GetTxTypeAndUserID(binaryBlock []byte) (txType int64, keyID int64) {
 func GetTransaction(t *Transaction, txType string) (custom.TransactionInterface, error) {
 	switch txType {
 	case consts.TxTypeParserFirstBlock:
-		return &custom.FirstBlockTransaction{t.GetLogger(), t.DbTransaction, t.TxPtr}, nil
+		var blockVersion int
+		if t.BlockData != nil {
+			blockVersion = t.BlockData.Version
+		}
+		return &custom.FirstBlockTransaction{t.GetLogger(), t.DbTransaction, t.TxPtr, blockVersion}, nil
 	case consts.TxTypeParserStopNetwork:
 		return &custom.StopNetworkTransaction{t.GetLogger(), t.TxPtr, nil}, nil
 	}
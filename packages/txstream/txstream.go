@@ -0,0 +1,113 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package txstream delivers the changes of the statuses of transactions to the subscribers
+// inside the node. It doesn't depend on Centrifugo.
+package txstream
+
+import (
+	"encoding/hex"
+	"sync"
+
+	"github.com/AplaProject/go-apla/packages/consts"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// The statuses of the transaction
+const (
+	StatusQueued     = `queued`
+	StatusAccepted   = `accepted`
+	StatusRejected   = `rejected`
+	StatusRolledBack = `rolledback`
+)
+
+// subscriptionBuffer is the number of events which are kept for the slow subscriber
+const subscriptionBuffer = 64
+
+// Event is the change of the status of the transaction
+type Event struct {
+	Hash    string
+	Status  string
+	BlockID int64
+	Result  string
+	Error   string
+}
+
+// Subscription receives the events of the specified transactions
+type Subscription struct {
+	C      <-chan *Event
+	ch     chan *Event
+	hashes map[string]bool
+}
+
+var subscribers = struct {
+	sync.RWMutex
+	list map[*Subscription]bool
+}{list: make(map[*Subscription]bool)}
+
+// Subscribe returns the subscription to the events of the transactions with the specified hex hashes
+func Subscribe(hashes []string) *Subscription {
+	ch := make(chan *Event, subscriptionBuffer)
+	s := &Subscription{C: ch, ch: ch, hashes: make(map[string]bool)}
+	for _, hash := range hashes {
+		s.hashes[hash] = true
+	}
+	subscribers.Lock()
+	subscribers.list[s] = true
+	subscribers.Unlock()
+	return s
+}
+
+// Close removes the subscription
+func (s *Subscription) Close() {
+	subscribers.Lock()
+	delete(subscribers.list, s)
+	subscribers.Unlock()
+}
+
+// Publish sends the event to the subscribers of the transaction. The event is skipped for
+// the subscriber which doesn't read its events.
+func Publish(event *Event) {
+	subscribers.RLock()
+	defer subscribers.RUnlock()
+	for s := range subscribers.list {
+		if !s.hashes[event.Hash] {
+			continue
+		}
+		select {
+		case s.ch <- event:
+		default:
+			log.WithFields(log.Fields{"type": consts.ParameterExceeded, "tx_hash": event.Hash,
+				"status": event.Status}).Warning("subscription buffer is full, event is skipped")
+		}
+	}
+}
+
+// Queued publishes that the transaction has been added to the queue
+func Queued(hash []byte) {
+	Publish(&Event{Hash: hex.EncodeToString(hash), Status: StatusQueued})
+}
+
+// Rejected publishes that the transaction has been marked as bad
+func Rejected(hash []byte, errText string) {
+	Publish(&Event{Hash: hex.EncodeToString(hash), Status: StatusRejected, Error: errText})
+}
+
+// RolledBack publishes that the block of the transaction has been rolled back
+func RolledBack(hash []byte, blockID int64) {
+	Publish(&Event{Hash: hex.EncodeToString(hash), Status: StatusRolledBack, BlockID: blockID})
+}
@@ -0,0 +1,60 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package txstream
+
+import (
+	"testing"
+)
+
+func TestSubscribe(t *testing.T) {
+	first := Subscribe([]string{`0102`, `0304`})
+	second := Subscribe([]string{`0304`})
+	defer second.Close()
+
+	Queued([]byte{1, 2})
+	Rejected([]byte{3, 4}, `{"type":"error","error":"wrong"}`)
+	Queued([]byte{5, 6})
+
+	event := <-first.C
+	if event.Hash != `0102` || event.Status != StatusQueued {
+		t.Errorf("wrong event %v", event)
+	}
+	event = <-first.C
+	if event.Hash != `0304` || event.Status != StatusRejected || len(event.Error) == 0 {
+		t.Errorf("wrong event %v", event)
+	}
+	event = <-second.C
+	if event.Hash != `0304` || event.Status != StatusRejected {
+		t.Errorf("wrong event %v", event)
+	}
+	if len(first.C) != 0 || len(second.C) != 0 {
+		t.Errorf("unexpected events")
+	}
+
+	first.Close()
+	RolledBack([]byte{1, 2}, 10)
+	if len(first.C) != 0 {
+		t.Errorf("event after close")
+	}
+
+	for i := 0; i < subscriptionBuffer+5; i++ {
+		RolledBack([]byte{3, 4}, int64(i))
+	}
+	if len(second.C) != subscriptionBuffer {
+		t.Errorf("wrong buffer %d", len(second.C))
+	}
+}
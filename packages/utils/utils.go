@@ -41,6 +41,7 @@ import (
 	"github.com/AplaProject/go-apla/packages/converter"
 	"github.com/AplaProject/go-apla/packages/crypto"
 	"github.com/AplaProject/go-apla/packages/model"
+	"github.com/AplaProject/go-apla/packages/network"
 	uuid "github.com/satori/go.uuid"
 
 	"github.com/pkg/errors"
@@ -122,6 +123,15 @@ var (
 	DaemonsCount int
 
 	ErrNodesUnavailable = errors.New("All nodes unvailabale")
+	// ErrHelloUnsupported is returned by PeerHello if the node doesn't answer to hello
+	ErrHelloUnsupported = errors.New("hello isn't supported")
+	// ErrSecureUnsupported is returned if the secure channel is required but the node doesn't support it
+	ErrSecureUnsupported = errors.New("secure channel isn't supported")
+
+	// PeerHello sends hello to the node over the plain connection and returns true if the node
+	// supports the secure channel. It negotiates the legacy version of the protocol, so the connection
+	// can be used for the legacy requests. It is set by tcpserver.
+	PeerHello func(host string, conn net.Conn) (bool, error)
 )
 
 // GetHTTPTextAnswer returns HTTP answer as a string
@@ -343,8 +353,43 @@ func TypeInt(txType string) int64 {
 	return 0
 }
 
-// TCPConn connects to the address
-func TCPConn(Addr string) (net.Conn, error) {
+// secureTimeout is the time during which the result of hello about the secure channel is kept
+const secureTimeout = 10 * time.Minute
+
+type securePeer struct {
+	secure bool
+	until  time.Time
+}
+
+var (
+	secureMutex sync.Mutex
+	secureHosts = make(map[string]securePeer)
+)
+
+// SetPeerSecure stores whether the node supports the secure channel according to its hello
+func SetPeerSecure(host string, secure bool) {
+	secureMutex.Lock()
+	defer secureMutex.Unlock()
+	secureHosts[host] = securePeer{secure: secure, until: time.Now().Add(secureTimeout)}
+}
+
+// peerSecure returns the stored result of hello, known is false if it isn't stored or expired
+func peerSecure(host string) (secure, known bool) {
+	secureMutex.Lock()
+	defer secureMutex.Unlock()
+	peer, ok := secureHosts[host]
+	if !ok {
+		return false, false
+	}
+	if time.Now().After(peer.until) {
+		delete(secureHosts, host)
+		return false, false
+	}
+	return peer.secure, true
+}
+
+// DialTCP makes the plain connection to the address
+func DialTCP(Addr string) (net.Conn, error) {
 	conn, err := net.DialTimeout("tcp", Addr, consts.TCPConnTimeout)
 	if err != nil {
 		log.WithFields(log.Fields{"type": consts.ConnectionError, "error": err, "address": Addr}).Debug("dialing tcp")
@@ -355,6 +400,102 @@ func TCPConn(Addr string) (net.Conn, error) {
 	return conn, nil
 }
 
+// SecureIdentity returns the keys of the node if its connections to other nodes are secured,
+// otherwise it returns nil
+func SecureIdentity() *network.Identity {
+	if conf.Config.TCPSecurityMode().IsOff() {
+		return nil
+	}
+	identity, err := NodeIdentity()
+	if err != nil || !syspar.IsNodePublicKey(identity.PublicKey) {
+		return nil
+	}
+	return identity
+}
+
+// UpgradeConn makes the secure channel over the connection if the identity is defined. The connection
+// stays plain if hello of the node has shown that it doesn't support the secure channel and
+// the secure channel isn't required. The failed handshake is the connection error.
+func UpgradeConn(conn net.Conn, Addr string, identity *network.Identity, peerSecure bool) (net.Conn, error) {
+	if identity == nil {
+		return conn, nil
+	}
+	if !peerSecure {
+		if conf.Config.TCPSecurityMode().IsRequired() {
+			conn.Close()
+			log.WithFields(log.Fields{"type": consts.ConnectionError, "address": Addr}).Error("node doesn't support required secure channel")
+			return nil, ErrSecureUnsupported
+		}
+		log.WithFields(log.Fields{"type": consts.ConnectionError, "address": Addr}).Debug("node doesn't support secure channel, using plain connection")
+		return conn, nil
+	}
+	secure, err := secureConn(conn, Addr, identity)
+	if err != nil {
+		conn.Close()
+		log.WithFields(log.Fields{"type": consts.ConnectionError, "error": err, "address": Addr}).Error("making secure channel")
+		return nil, ErrInfo(err)
+	}
+	return secure, nil
+}
+
+// TCPConn connects to the address. If the node is the full node then the secure channel is used,
+// the plain connection is made only if hello of the remote node shows that it doesn't support
+// the secure channel and the secure channel isn't required.
+func TCPConn(Addr string) (net.Conn, error) {
+	conn, err := DialTCP(Addr)
+	if err != nil {
+		return nil, err
+	}
+	identity := SecureIdentity()
+	if identity == nil {
+		return conn, nil
+	}
+	secure, known := peerSecure(Addr)
+	if !known && PeerHello != nil {
+		secure, err = PeerHello(Addr, conn)
+		if err == ErrHelloUnsupported {
+			// the legacy node closes the connection after the unknown request type
+			conn.Close()
+			if conn, err = DialTCP(Addr); err != nil {
+				return nil, err
+			}
+		} else if err != nil {
+			conn.Close()
+			return nil, ErrInfo(err)
+		}
+		SetPeerSecure(Addr, secure)
+	} else if !known {
+		secure = true
+	}
+	return UpgradeConn(conn, Addr, identity, secure)
+}
+
+func secureConn(conn net.Conn, Addr string, identity *network.Identity) (net.Conn, error) {
+	if _, err := conn.Write(converter.DecToBin(consts.DATA_TYPE_SECURE, 2)); err != nil {
+		return nil, err
+	}
+	return network.Dial(conn, identity, func(publicKey []byte) bool {
+		if node, err := syspar.GetNodeByHost(Addr); err == nil {
+			return bytes.Equal(node.PublicKey, publicKey)
+		}
+		return syspar.IsNodePublicKey(publicKey)
+	})
+}
+
+// NodeIdentity returns the node keys for the secure channel
+func NodeIdentity() (*network.Identity, error) {
+	private, public, err := GetNodeKeys()
+	if err != nil {
+		return nil, err
+	}
+	publicKey, err := hex.DecodeString(public)
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.ConversionError, "error": err}).Error("decoding node public key from hex")
+		return nil, err
+	}
+	return &network.Identity{PrivateKey: private, PublicKey: publicKey}, nil
+}
+
 // GetCurrentDir returns the current directory
 func GetCurrentDir() string {
 	dir, err := filepath.Abs(filepath.Dir(os.Args[0]))
@@ -0,0 +1,194 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package webhook matches the transactions of blocks with the filters of webhooks and sends
+// the signed notifications
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/AplaProject/go-apla/packages/crypto"
+)
+
+// The headers of the webhook request
+const (
+	HeaderWebhook   = `X-Apla-Webhook-Id`
+	HeaderDelivery  = `X-Apla-Delivery-Id`
+	HeaderTimestamp = `X-Apla-Timestamp`
+	HeaderSignature = `X-Apla-Signature`
+)
+
+const (
+	// MaxAttempts is the number of attempts after which the delivery is moved to the dead letters
+	MaxAttempts = 8
+
+	backoffBase = 10 * time.Second
+	backoffMax  = time.Hour
+)
+
+// ErrForbiddenAddress is returned if the webhook points to the address of the local network
+var ErrForbiddenAddress = errors.New(`address of webhook is not allowed`)
+
+// Change is the changed row of the table
+type Change struct {
+	Table string `json:"table"`
+	ID    string `json:"id"`
+}
+
+// Event is the transaction of the block
+type Event struct {
+	BlockID   int64    `json:"block_id"`
+	BlockTime int64    `json:"block_time"`
+	TxHash    string   `json:"tx_hash"`
+	Ecosystem int64    `json:"ecosystem"`
+	KeyID     int64    `json:"key_id"`
+	Contract  string   `json:"contract"`
+	Changes   []Change `json:"changes"`
+}
+
+// Payload is the body of the webhook request
+type Payload struct {
+	WebhookID int64  `json:"webhook_id"`
+	Webhook   string `json:"webhook"`
+	Event
+}
+
+// trimEcosystem removes @ecosystem prefix of the contract name
+func trimEcosystem(name string) string {
+	if strings.HasPrefix(name, `@`) {
+		name = strings.TrimLeft(name[1:], `0123456789`)
+	}
+	return name
+}
+
+// Match checks the event with the filters of the webhook of the ecosystem. It returns the event
+// which contains only the changes of the watched tables.
+func Match(ecosystemID int64, contracts, tables []string, event *Event) (*Event, bool) {
+	result := *event
+	result.Changes = make([]Change, 0)
+	for _, table := range tables {
+		name := fmt.Sprintf(`%d_%s`, ecosystemID, table)
+		for _, change := range event.Changes {
+			if change.Table == name {
+				result.Changes = append(result.Changes, change)
+			}
+		}
+	}
+	if len(result.Changes) > 0 {
+		return &result, true
+	}
+	if event.Ecosystem != ecosystemID || len(event.Contract) == 0 {
+		return nil, false
+	}
+	for _, contract := range contracts {
+		if trimEcosystem(contract) == trimEcosystem(event.Contract) {
+			return &result, true
+		}
+	}
+	return nil, false
+}
+
+// Sign returns hex HMAC of the body with the timestamp
+func Sign(secret string, body []byte, timestamp string) (string, error) {
+	sign, err := crypto.GetHMACWithTimestamp(secret, string(body), timestamp)
+	if err != nil {
+		return ``, err
+	}
+	return hex.EncodeToString(sign), nil
+}
+
+// Backoff returns the delay before the next attempt
+func Backoff(attempts int64) time.Duration {
+	delay := backoffBase
+	for i := int64(1); i < attempts && delay < backoffMax; i++ {
+		delay *= 2
+	}
+	if delay > backoffMax {
+		delay = backoffMax
+	}
+	return delay
+}
+
+// CheckIP returns ErrForbiddenAddress if the address is private, loopback, link-local or unspecified
+func CheckIP(ip net.IP) error {
+	if ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsUnspecified() {
+		return ErrForbiddenAddress
+	}
+	return nil
+}
+
+// NewClient returns HTTP client which connects only to the public addresses. The address is checked
+// after the resolving of the host, so the host can't be resolved to the local address later.
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil {
+				return ErrForbiddenAddress
+			}
+			return CheckIP(ip)
+		},
+	}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: &http.Transport{DialContext: dialer.DialContext},
+	}
+}
+
+// Send posts the signed body to the url. It returns the status code of the response and
+// the error if the request hasn't been accepted.
+func Send(ctx context.Context, client *http.Client, url, secret string, webhookID, deliveryID int64, body []byte) (int, error) {
+	timestamp := fmt.Sprint(time.Now().Unix())
+	sign, err := Sign(secret, body, timestamp)
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequest(`POST`, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set(`Content-Type`, `application/json`)
+	req.Header.Set(HeaderWebhook, fmt.Sprint(webhookID))
+	req.Header.Set(HeaderDelivery, fmt.Sprint(deliveryID))
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, sign)
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf(`webhook has responded %s`, resp.Status)
+	}
+	return resp.StatusCode, nil
+}
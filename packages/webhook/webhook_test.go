@@ -0,0 +1,96 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package webhook
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMatch(t *testing.T) {
+	event := &Event{Ecosystem: 1, Contract: `@1TokensSend`, Changes: []Change{
+		{Table: `1_keys`, ID: `10`}, {Table: `2_members`, ID: `3`}, {Table: `1_history`, ID: `7`},
+	}}
+	if result, ok := Match(1, []string{`TokensSend`}, nil, event); !ok || len(result.Changes) != 0 {
+		t.Errorf("contract must be matched %v", result)
+	}
+	if _, ok := Match(2, []string{`TokensSend`}, nil, event); ok {
+		t.Error("contract of other ecosystem mustn't be matched")
+	}
+	result, ok := Match(2, nil, []string{`members`}, event)
+	if !ok || len(result.Changes) != 1 || result.Changes[0].ID != `3` {
+		t.Errorf("table must be matched %v", result)
+	}
+	if _, ok = Match(1, []string{`NewMenu`}, []string{`pages`}, event); ok {
+		t.Error("event mustn't be matched")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for attempts, delay := range map[int64]time.Duration{
+		1: 10 * time.Second, 2: 20 * time.Second, 4: 80 * time.Second, 20: time.Hour,
+	} {
+		if Backoff(attempts) != delay {
+			t.Errorf("wrong delay %v of %d attempts", Backoff(attempts), attempts)
+		}
+	}
+}
+
+func TestSend(t *testing.T) {
+	var signed bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		sign, _ := Sign(`secret`, body, r.Header.Get(HeaderTimestamp))
+		signed = sign == r.Header.Get(HeaderSignature) && r.Header.Get(HeaderDelivery) == `5`
+		if r.URL.Path == `/fail` {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	code, err := Send(context.Background(), http.DefaultClient, server.URL, `secret`, 1, 5, []byte(`{"a":1}`))
+	if err != nil || code != http.StatusOK || !signed {
+		t.Errorf("wrong delivery %d %v %v", code, err, signed)
+	}
+	if code, err = Send(context.Background(), http.DefaultClient, server.URL+`/fail`, `secret`, 1, 5,
+		[]byte(`{}`)); err == nil || code != http.StatusInternalServerError {
+		t.Errorf("delivery must fail %d %v", code, err)
+	}
+}
+
+func TestCheckIP(t *testing.T) {
+	for addr, allowed := range map[string]bool{
+		`8.8.8.8`: true, `2001:4860:4860::8888`: true, `127.0.0.1`: false, `10.1.2.3`: false,
+		`192.168.0.1`: false, `169.254.169.254`: false, `::1`: false, `fe80::1`: false, `0.0.0.0`: false,
+	} {
+		if err := CheckIP(net.ParseIP(addr)); (err == nil) != allowed {
+			t.Errorf("wrong check of %s: %v", addr, err)
+		}
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	if _, err := Send(context.Background(), NewClient(time.Second), server.URL, `secret`, 1, 5,
+		[]byte(`{}`)); err == nil {
+		t.Error("local address must be rejected")
+	}
+}